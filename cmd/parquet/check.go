@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/patdeg/parquet/pqtool"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const checkUsage = `parquet check -rules rules_file [-on-failure fail|quarantine|report] [-quarantine file [-o file]] [-result file] [-samples n] [-from parquet|csv] [-d delimiter | -tab] input_file
+
+Checks the rows of a flat parquet file, or of a CSV file (empty values being
+nulls), against the data quality rules of a YAML or JSON file:
+
+  rows: {min: 1, max: 1000000}         # bounds of the row count
+  columns:
+    - name: id
+      not_null: true
+      unique: true
+    - name: country
+      in: [US, FR, DE]
+    - name: email
+      regex: '^[^@]+@[^@]+$'
+    - name: age
+      min: 0
+      max: 150                         # numbers, dates, timestamps or strings
+    - name: customer_id
+      lookup: {file: customers.parquet, column: id}
+
+Null values pass all the rules but not_null, and lookup files are relative to
+the rules file. With -on-failure fail (default), the exit code is 2 if rules
+fail. With quarantine, the failing rows are written to the -quarantine file
+with their failed rules in an _errors column, and the others to the -o file.
+With report, failures are only reported. -result writes the result as JSON.
+Example: parquet check -rules rules.yaml -on-failure quarantine -quarantine bad.csv -o good.parquet events.parquet`
+
+// Check the rows of a parquet or CSV file against data quality rules
+func runCheck(args []string) {
+	var (
+		opts            pqtool.CheckOptions
+		from, delimiter string
+		isTabDelimited  bool
+	)
+
+	fs := newFlagSet("check")
+	checkFlags := addCheckFlags(fs, &opts)
+	fs.StringVar(&opts.Output, "o", "", "parquet or CSV file of the rows passing rules, with -on-failure quarantine")
+	fs.StringVar(&from, "from", "", "input format: parquet or csv (default: from the file extension)")
+	fs.StringVar(&delimiter, "d", ",", "CSV delimiter")
+	fs.BoolVar(&isTabDelimited, "tab", false, "CSV tab delimited")
+	args = parseArgs(fs, args, checkUsage, 1, 1)
+	if !checkFlags.parse() {
+		ErrorExit("Error: -rules is required\nUsage:\n%v", checkUsage)
+	}
+
+	if from == "" {
+		from = detectFormat(args[0])
+		if from == "" {
+			from = "parquet"
+		}
+	}
+	switch from {
+	case "csv":
+		opts.CSV = true
+	case "parquet":
+	default:
+		ErrorExit("Error: unknown input format '%v', use -from parquet|csv", from)
+	}
+	if isTabDelimited || (opts.CSV && strings.ToLower(filepath.Ext(args[0])) == ".tsv") {
+		if delimiter == "," {
+			delimiter = "\t"
+		} else if isTabDelimited {
+			ErrorExit("Error: you can't use -tab and -d at the same time")
+		}
+	}
+	opts.Delimiter = delimiter
+
+	if _, err := pqtool.Check(os.Stdout, args[0], opts); err == pqtool.ErrRulesFailed {
+		os.Exit(2)
+	} else if err != nil {
+		ErrorExit("Error: %v", err)
+	}
+}
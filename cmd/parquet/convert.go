@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"github.com/patdeg/parquet/pqtool"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const convertUsage = `parquet convert [-from csv|arrow|avro|sqlite] [-d delimiter | -tab] [-sort columns [-sort-memory size] [-temp-dir dir]] [-rules rules_file [-on-failure fail|quarantine|report] [-quarantine file] [-result file]] [-t table | -q query] input_file parquet_file
+
+CSV rows can be sorted by one or more columns with -sort, each column followed
+by :asc (default) or :desc, and :nulls_last (default) or :nulls_first. Rows are
+sorted in memory up to -sort-memory bytes (256M by default), then spilled to
+temporary files merged at the end, and the sort columns are recorded in the row
+group metadata.
+
+CSV rows can be checked against the data quality rules of -rules (see
+'parquet check -h'). Rows failing rules either fail the conversion (the parquet
+file being removed, exit code 2), are written to the -quarantine file instead
+of the parquet file, or are only reported. -result writes the result as JSON.
+Example: parquet convert -sort 'customer_id,event_time:desc:nulls_first' events.csv events.parquet`
+
+// Convert a CSV, Arrow, Avro or SQLite file to parquet
+func runConvert(args []string) {
+	var format, delimiter, table, query string
+	var isTabDelimited bool
+	var sortOpts pqtool.SortOptions
+	var checkOpts pqtool.CheckOptions
+
+	// Parse command lines flag and arguments
+	fs := newFlagSet("convert")
+	fs.StringVar(&format, "from", "", "input format: csv, arrow, avro or sqlite (default: from the file extension)")
+	fs.StringVar(&delimiter, "d", ",", "CSV delimiter")
+	fs.BoolVar(&isTabDelimited, "tab", false, "CSV tab delimited")
+	fs.StringVar(&table, "t", "", "SQLite table to convert")
+	fs.StringVar(&query, "q", "", "SQLite query to convert")
+	sortFlags := addSortFlags(fs, &sortOpts)
+	checkFlags := addCheckFlags(fs, &checkOpts)
+	args = parseArgs(fs, args, convertUsage, 2, 2)
+	sortFlags.parse()
+	isChecked := checkFlags.parse()
+
+	input_filename := args[0]
+	parquet_filename := args[1]
+
+	if format == "" {
+		format = detectFormat(input_filename)
+	}
+
+	// Check that either tab or customer delimiter is set
+	if isTabDelimited || (format == "csv" && strings.ToLower(filepath.Ext(input_filename)) == ".tsv") {
+		if delimiter == "," {
+			delimiter = "\t"
+		} else if isTabDelimited {
+			ErrorExit("Error: you can't use -tab and -d at the same time")
+		}
+	}
+
+	if len(sortOpts.Keys) > 0 && format != "csv" {
+		ErrorExit("Error: -sort is only supported for CSV files")
+	}
+	if isChecked && format != "csv" {
+		ErrorExit("Error: -rules is only supported for CSV files")
+	}
+
+	fmt.Printf(`%v2PARQUET
+Input file:    %v
+Parquet file:  %v
+`, strings.ToUpper(format), input_filename, parquet_filename)
+
+	var err error
+	switch format {
+	case "csv":
+		opts := pqtool.CSVOptions{
+			Delimiter: delimiter,
+			Sort:      sortOpts,
+		}
+		if isChecked {
+			opts.Check = &checkOpts
+		}
+		err = pqtool.CSVToParquet(input_filename, parquet_filename, opts)
+	case "arrow":
+		err = pqtool.ArrowToParquet(input_filename, parquet_filename)
+	case "avro":
+		err = pqtool.AvroToParquet(input_filename, parquet_filename)
+	case "sqlite":
+		err = pqtool.SQLiteToParquet(input_filename, parquet_filename, pqtool.SQLiteOptions{
+			Table: table,
+			Query: query,
+		})
+	default:
+		ErrorExit("Error: unknown input format '%v', use -from csv|arrow|avro|sqlite", format)
+	}
+	if err == pqtool.ErrRulesFailed {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if err != nil {
+		ErrorExit("Error: %v", err)
+	}
+}
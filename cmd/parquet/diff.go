@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/patdeg/parquet/pqtool"
+	"os"
+)
+
+const diffUsage = `parquet diff [-keys columns] [-tolerance x] [-samples n] [-o output_file] [-sort-memory size] [-temp-dir dir] parquet_file parquet_file
+
+Compares the rows of two flat parquet files, matched by the values of -keys
+columns (sorted in temporary files, rows with the same keys being matched in
+order), or by position without -keys. Prints the numbers of same, changed,
+removed (first file only) and added (second file only) rows, and sample changes
+of each column. Floats and decimals are equal within -tolerance, and columns of
+different types are compared as text. With -o, the differences are written to
+a parquet or CSV file (.csv extension) with a _diff column: removed, added, and
+old and new for the values of changed rows. Exits with 0 if the files have the
+same rows, else 2.
+Example: parquet diff -keys id -tolerance 1e-9 -o diff.csv before.parquet after.parquet`
+
+// Compare the rows of two parquet files
+func runDiff(args []string) {
+	var (
+		opts         pqtool.DiffOptions
+		keys, memory string
+	)
+
+	fs := newFlagSet("diff")
+	fs.StringVar(&keys, "keys", "", "key columns matching the rows (default: rows matched by position)")
+	fs.Float64Var(&opts.Tolerance, "tolerance", 0, "largest difference of equal floats and decimals")
+	fs.IntVar(&opts.Samples, "samples", 5, "number of sample changes shown per column")
+	fs.StringVar(&opts.Output, "o", "", "parquet or CSV file of the differences")
+	fs.StringVar(&memory, "sort-memory", "256M", "size of the rows sorted by key in memory before spilling to temporary files")
+	fs.StringVar(&opts.TempDir, "temp-dir", "", "directory of the temporary files of the sort (default: system temporary directory)")
+	args = parseArgs(fs, args, diffUsage, 2, 2)
+
+	var err error
+	if opts.Memory, err = pqtool.ParseSize(memory); err != nil || opts.Memory == 0 {
+		ErrorExit("Error: Invalid sort memory %v", memory)
+	}
+	if opts.Tolerance < 0 || opts.Samples < 0 {
+		ErrorExit("Error: Invalid tolerance or number of samples")
+	}
+	opts.Keys = splitList(keys)
+
+	result, err := pqtool.Diff(os.Stdout, args[0], args[1], opts)
+	if err != nil {
+		ErrorExit("Error: %v", err)
+	}
+	if !result.Equal() {
+		os.Exit(2)
+	}
+}
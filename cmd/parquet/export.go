@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"github.com/patdeg/parquet/pqtool"
+	"strings"
+)
+
+const exportUsage = `parquet export [-to csv|arrow|avro|sqlite] [flags] parquet_file output_file
+
+CSV:     [-H] [-pg text|csv] [-t table] [-s sql_file]
+Arrow:   [-stream] [-b batch_size]
+Avro:    [-c codec] [-b batch_size]
+SQLite:  [-t table] [-r] [-b batch_size]`
+
+// Export a parquet file to CSV (or PostgreSQL COPY), Arrow, Avro or SQLite
+func runExport(args []string) {
+	var format, pgFormat, table, sqlFilename, codec string
+	var isHeader, isStream, isReplace bool
+	var batchSize int
+
+	// Parse command lines flag and arguments
+	fs := newFlagSet("export")
+	fs.StringVar(&format, "to", "", "output format: csv, arrow, avro or sqlite (default: from the file extension)")
+	fs.BoolVar(&isHeader, "H", false, "CSV header line with the column names")
+	fs.StringVar(&pgFormat, "pg", "", "PostgreSQL COPY format: text or csv")
+	fs.StringVar(&table, "t", "", "PostgreSQL or SQLite table name (default: parquet file name)")
+	fs.StringVar(&sqlFilename, "s", "", "PostgreSQL load script (default: output file name with .sql extension)")
+	fs.BoolVar(&isStream, "stream", false, "Arrow IPC stream format instead of file format")
+	fs.StringVar(&codec, "c", "snappy", "Avro block compression: null, deflate or snappy")
+	fs.BoolVar(&isReplace, "r", false, "drop the SQLite table first if it exists")
+	fs.IntVar(&batchSize, "b", 0, "rows per Arrow record batch (10000), Avro block (1000) or SQLite transaction (1000)")
+	args = parseArgs(fs, args, exportUsage, 2, 2)
+
+	if batchSize < 0 {
+		ErrorExit("Error: batch size must be positive")
+	}
+
+	parquet_filename := args[0]
+	output_filename := args[1]
+
+	if format == "" {
+		format = detectFormat(output_filename)
+	}
+
+	fmt.Printf(`PARQUET2%v
+Parquet file:  %v
+Output file:   %v
+`, strings.ToUpper(format), parquet_filename, output_filename)
+
+	var err error
+	switch format {
+	case "csv":
+		err = pqtool.ParquetToCSV(parquet_filename, output_filename, pqtool.ExportCSVOptions{
+			Header:      isHeader,
+			PgFormat:    pgFormat,
+			Table:       table,
+			SQLFilename: sqlFilename,
+		})
+	case "arrow":
+		err = pqtool.ParquetToArrow(parquet_filename, output_filename, pqtool.ArrowOptions{
+			Stream:    isStream,
+			BatchSize: batchSize,
+		})
+	case "avro":
+		err = pqtool.ParquetToAvro(parquet_filename, output_filename, pqtool.AvroOptions{
+			Compression: codec,
+			BatchSize:   batchSize,
+		})
+	case "sqlite":
+		err = pqtool.ParquetToSQLite(parquet_filename, output_filename, pqtool.SQLiteOptions{
+			Table:     table,
+			Replace:   isReplace,
+			BatchSize: batchSize,
+		})
+	default:
+		ErrorExit("Error: unknown output format '%v', use -to csv|arrow|avro|sqlite", format)
+	}
+	if err != nil {
+		ErrorExit("Error: %v", err)
+	}
+}
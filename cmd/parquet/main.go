@@ -0,0 +1,299 @@
+// Command parquet converts, inspects and simulates parquet files.
+//
+// Usage:
+//
+//	parquet command [flags] arguments
+//
+// Run "parquet command -h" for the flags and arguments of a command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/patdeg/parquet/pqtool"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Subcommand of the parquet command
+type command struct {
+	name        string
+	description string
+	run         func(args []string)
+}
+
+var (
+	isHelp   bool
+	commands = []command{
+		{"check", "check the rows of a parquet or CSV file against data quality rules", runCheck},
+		{"convert", "convert a CSV, Arrow, Avro or SQLite file to parquet", runConvert},
+		{"diff", "compare the rows of two parquet files", runDiff},
+		{"export", "export a parquet file to CSV, PostgreSQL COPY, Arrow, Avro or SQLite", runExport},
+		{"merge", "merge parquet files into one file or files of a target size", runMerge},
+		{"profile", "profile the columns of a parquet or CSV file", runProfile},
+		{"rewrite", "rewrite a parquet file with another codec, row group size, encodings or order", runRewrite},
+		{"schema-diff", "compare the schemas of parquet files and their compatibility", runSchemaDiff},
+		{"show", "show the schema, size and content of a parquet file", runShow},
+		{"simulate", "write a parquet file of random data", runSimulate},
+		{"split", "split a parquet file by rows, size or row group", runSplit},
+		{"transform", "drop, rename, cast, add or reorder the columns of a parquet file", runTransform},
+		{"validate", "check parquet files for corruption", runValidate},
+	}
+)
+
+// Print an error and exit program
+func ErrorExit(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
+
+// Return the usage of the parquet command, listing its subcommands
+func usage() string {
+	text := "Usage:\nparquet command [flags] arguments\n\nCommands:\n"
+	for _, c := range commands {
+		text += fmt.Sprintf("  %-12v %v\n", c.name, c.description)
+	}
+	return text + "\nRun 'parquet command -h' for help on a command"
+}
+
+// Create the flag set of a command with the common verbose and help flags.
+// Parse errors exit with code 1 in parseArgs, as exit code 2 is a result of
+// some commands (e.g. different files for diff)
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.BoolVar(&pqtool.Verbose, "v", false, "verbose mode")
+	fs.BoolVar(&isHelp, "h", false, "help")
+	return fs
+}
+
+// Parse the flags of a command and return its arguments, printing the usage
+// on -h or if there are less than min or more than max arguments (-1 for no maximum)
+func parseArgs(fs *flag.FlagSet, args []string, usage string, min int, max int) []string {
+	if err := fs.Parse(args); err != nil {
+		// The flag package printed the error and the flags
+		os.Exit(1)
+	}
+
+	if isHelp {
+		fmt.Println("Usage:\n" + usage)
+		fs.PrintDefaults()
+		os.Exit(0)
+	}
+
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		ErrorExit("Usage:\n%v", usage)
+	}
+
+	return fs.Args()
+}
+
+// Return whether a flag is set on the command line, to tell its zero value from its default
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// Layout flags of the commands writing parquet files
+type writerFlags struct {
+	opts     *pqtool.WriterOptions
+	rowGroup string
+	page     string
+}
+
+// Add the flags of the layout of written parquet files (codec, sizes of row
+// groups and pages, version of data pages, parallelism) to a flag set
+func addWriterFlags(fs *flag.FlagSet, opts *pqtool.WriterOptions) *writerFlags {
+	f := &writerFlags{opts: opts}
+	fs.StringVar(&opts.Compression, "codec", "SNAPPY", "compression codec: UNCOMPRESSED, SNAPPY, GZIP or ZSTD")
+	fs.StringVar(&f.rowGroup, "row-group-size", "128M", "size of row groups")
+	fs.StringVar(&f.page, "page-size", "8K", "size of data pages")
+	fs.IntVar(&opts.DataPageVersion, "page-version", 1, "version of data pages: 1 or 2")
+	fs.IntVar(&opts.Parallelism, "parallel", 4, "number of goroutines encoding the pages of a file")
+	return f
+}
+
+// Set the sizes of the layout options from the parsed flags, exiting on invalid values
+func (f *writerFlags) parse() {
+	var err error
+	if f.opts.RowGroupSize, err = pqtool.ParseSize(f.rowGroup); err != nil || f.opts.RowGroupSize == 0 {
+		ErrorExit("Error: Invalid row group size %v", f.rowGroup)
+	}
+	if f.opts.PageSize, err = pqtool.ParseSize(f.page); err != nil || f.opts.PageSize == 0 {
+		ErrorExit("Error: Invalid page size %v", f.page)
+	}
+	if f.opts.Parallelism < 1 {
+		ErrorExit("Error: Invalid parallelism %v", f.opts.Parallelism)
+	}
+}
+
+// Flags of the order of written rows
+type sortFlags struct {
+	opts   *pqtool.SortOptions
+	keys   string
+	memory string
+}
+
+// Add the flags of the order of written rows (key columns, memory of the sort,
+// directory of temporary files) to a flag set
+func addSortFlags(fs *flag.FlagSet, opts *pqtool.SortOptions) *sortFlags {
+	f := &sortFlags{opts: opts}
+	fs.StringVar(&f.keys, "sort", "", "columns to sort the rows by as NAME[:asc|:desc][:nulls_first|:nulls_last],...")
+	fs.StringVar(&f.memory, "sort-memory", "256M", "size of the rows sorted in memory before spilling to temporary files")
+	fs.StringVar(&opts.TempDir, "temp-dir", "", "directory of the temporary files of the sort (default: system temporary directory)")
+	return f
+}
+
+// Set the sort options from the parsed flags, exiting on invalid values
+func (f *sortFlags) parse() {
+	var err error
+	if f.keys != "" {
+		if f.opts.Keys, err = pqtool.ParseSortKeys(strings.Split(f.keys, ",")); err != nil {
+			ErrorExit("Error: %v", err)
+		}
+	}
+	if f.opts.Memory, err = pqtool.ParseSize(f.memory); err != nil || f.opts.Memory == 0 {
+		ErrorExit("Error: Invalid sort memory %v", f.memory)
+	}
+}
+
+// Flags of the data quality rules of rows
+type checkFlags struct {
+	opts  *pqtool.CheckOptions
+	rules string
+}
+
+// Add the flags of the data quality rules of rows (rules file, action on
+// failures, quarantine and result files) to a flag set
+func addCheckFlags(fs *flag.FlagSet, opts *pqtool.CheckOptions) *checkFlags {
+	f := &checkFlags{opts: opts}
+	fs.StringVar(&f.rules, "rules", "", "YAML or JSON file of data quality rules")
+	fs.StringVar(&opts.OnFailure, "on-failure", "fail", "action on rows failing rules: fail, quarantine or report")
+	fs.StringVar(&opts.Quarantine, "quarantine", "", "parquet or CSV file of the rows failing rules, with -on-failure quarantine")
+	fs.StringVar(&opts.Result, "result", "", "JSON file of the result of the rules")
+	fs.IntVar(&opts.Samples, "samples", 5, "failing rows reported per rule")
+	return f
+}
+
+// Load the rules file of the parsed flags, exiting on errors, and return
+// whether rules are checked
+func (f *checkFlags) parse() bool {
+	if f.rules == "" {
+		return false
+	}
+	if f.opts.Samples <= 0 {
+		ErrorExit("Error: -samples must be positive")
+	}
+	var err error
+	if f.opts.Rules, err = pqtool.LoadRules(f.rules); err != nil {
+		ErrorExit("Error: %v", err)
+	}
+	return true
+}
+
+// Split a comma-separated list, ignoring the commas between parentheses (e.g.
+// in DECIMAL(10,2)), and return nil for an empty list
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	var items []string
+	depth, start := 0, 0
+	for i, c := range list {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(items, list[start:])
+}
+
+// Split the comma-separated columns of -add as NAME:TYPE=VALUE, ignoring the
+// commas between parentheses in types. Commas and backslashes in values are
+// escaped as \, and \\
+func splitValues(list string) []string {
+	if list == "" {
+		return nil
+	}
+	var (
+		items            []string
+		item             strings.Builder
+		depth            int
+		inValue, escaped bool
+	)
+	for _, c := range list {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+			continue
+		case c == '=':
+			inValue = true
+		case c == '(' && !inValue:
+			depth++
+		case c == ')' && !inValue:
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, item.String())
+			item.Reset()
+			inValue = false
+			continue
+		}
+		item.WriteRune(c)
+	}
+	if escaped {
+		item.WriteRune('\\')
+	}
+	return append(items, item.String())
+}
+
+// Return the format of a file from its extension: csv, arrow, avro or sqlite
+func detectFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", ".tsv", ".txt":
+		return "csv"
+	case ".arrow", ".arrows", ".feather", ".ipc":
+		return "arrow"
+	case ".avro":
+		return "avro"
+	case ".db", ".sqlite", ".sqlite3":
+		return "sqlite"
+	}
+	return ""
+}
+
+func main() {
+
+	if len(os.Args) < 2 {
+		ErrorExit(usage())
+	}
+
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.name == name {
+			pqtool.Output = os.Stdout
+			c.run(os.Args[2:])
+			return
+		}
+	}
+
+	if name == "-h" || name == "help" {
+		fmt.Println(usage())
+		os.Exit(0)
+	}
+
+	ErrorExit("Error: unknown command %v\n%v", name, usage())
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/patdeg/parquet/pqtool"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const mergeUsage = `parquet merge [-size size] [-copy] [layout options] input_file|input_directory [...] output_file
+
+Merges parquet files, or the .parquet files of directories, into a single file or
+into files of about -size bytes (e.g. 512M), named like output-00000.parquet.
+The files must have the same schema, or flat schemas with the same types for the
+columns of the same name: columns are merged by name, and columns missing from
+some files are optional.
+With -copy, if the files have the same schema and are compressed with -codec,
+their row groups are copied without being decoded, keeping their sizes. Else
+the rows are decoded and written in row groups of -row-group-size.
+Layout options: -codec, -row-group-size, -page-size, -page-version, -parallel
+Example: parquet merge -size 512M -copy events/ events.parquet`
+
+// Merge parquet files into one file or into files of a target size
+func runMerge(args []string) {
+	var (
+		opts pqtool.MergeOptions
+		size string
+	)
+
+	fs := newFlagSet("merge")
+	fs.StringVar(&size, "size", "", "size of the output files (e.g. 512M, 1G), a single file if empty")
+	fs.BoolVar(&opts.CopyRowGroups, "copy", false, "copy the row groups without decoding them if the schemas and codecs match")
+	layout := addWriterFlags(fs, &opts.Writer)
+	args = parseArgs(fs, args, mergeUsage, 2, -1)
+
+	var err error
+	if size != "" {
+		if opts.TargetSize, err = pqtool.ParseSize(size); err != nil || opts.TargetSize == 0 {
+			ErrorExit("Error: Invalid size %v", size)
+		}
+	}
+	layout.parse()
+
+	output := args[len(args)-1]
+	var inputs []string
+	for _, name := range args[:len(args)-1] {
+		inputs = append(inputs, parquetFiles(name)...)
+	}
+
+	if err = pqtool.Merge(inputs, output, opts); err != nil {
+		ErrorExit("Error: %v", err)
+	}
+}
+
+// Return the .parquet files of a directory in alphabetical order, or the file itself
+func parquetFiles(name string) []string {
+	info, err := os.Stat(name)
+	if err != nil {
+		ErrorExit("Error: %v", err)
+	}
+	if !info.IsDir() {
+		return []string{name}
+	}
+	files, err := filepath.Glob(filepath.Join(name, "*.parquet"))
+	if err != nil {
+		ErrorExit("Error: %v", err)
+	}
+	sort.Strings(files)
+	return files
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"github.com/patdeg/parquet/pqtool"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const profileUsage = `parquet profile [-format text|json|html] [-o output_file] [-from parquet|csv] [-d delimiter | -tab] [-top k] [-bins n] [-exact] [-max-values n] [-sample n] input_file
+
+Profiles the columns of a flat parquet file, or of a CSV file before its
+conversion (empty values being nulls, and values not of the type detected from
+the 2nd line being counted as invalid): null count and ratio, distinct count,
+min/max, mean, standard deviation and quantiles of numbers, string lengths, most
+frequent values, and histograms. Beyond -max-values distinct values per column,
+distinct counts are estimated with HyperLogLog and top value counts are lower
+bounds, unless -exact counts all the values. Quantiles are computed on a sample
+of -sample values per column. The report is written as text, JSON or a
+self-contained HTML page (default: from the extension of -o, else text).
+Example: parquet profile -o events.html events.parquet`
+
+// Profile the columns of a parquet or CSV file
+func runProfile(args []string) {
+	var (
+		opts                    pqtool.ProfileOptions
+		output, from, delimiter string
+		isTabDelimited          bool
+	)
+
+	fs := newFlagSet("profile")
+	fs.StringVar(&opts.Format, "format", "", "report format: text, json or html")
+	fs.StringVar(&output, "o", "", "report file (default: standard output)")
+	fs.StringVar(&from, "from", "", "input format: parquet or csv (default: from the file extension)")
+	fs.StringVar(&delimiter, "d", ",", "CSV delimiter")
+	fs.BoolVar(&isTabDelimited, "tab", false, "CSV tab delimited")
+	fs.IntVar(&opts.TopK, "top", 10, "number of most frequent values per column")
+	fs.IntVar(&opts.Bins, "bins", 10, "number of bins of the histograms")
+	fs.BoolVar(&opts.Exact, "exact", false, "count all the distinct values exactly")
+	fs.IntVar(&opts.MaxValues, "max-values", 100000, "distinct values counted per column before estimating")
+	fs.IntVar(&opts.SampleSize, "sample", 100000, "values sampled per column for the quantiles")
+	args = parseArgs(fs, args, profileUsage, 1, 1)
+
+	if opts.TopK <= 0 || opts.Bins <= 0 || opts.MaxValues <= 0 || opts.SampleSize <= 0 {
+		ErrorExit("Error: -top, -bins, -max-values and -sample must be positive")
+	}
+
+	if from == "" {
+		from = detectFormat(args[0])
+		if from == "" {
+			from = "parquet"
+		}
+	}
+	switch from {
+	case "csv":
+		opts.CSV = true
+	case "parquet":
+	default:
+		ErrorExit("Error: unknown input format '%v', use -from parquet|csv", from)
+	}
+	if isTabDelimited || (opts.CSV && strings.ToLower(filepath.Ext(args[0])) == ".tsv") {
+		if delimiter == "," {
+			delimiter = "\t"
+		} else if isTabDelimited {
+			ErrorExit("Error: you can't use -tab and -d at the same time")
+		}
+	}
+	opts.CSVOptions.Delimiter = delimiter
+
+	if opts.Format == "" {
+		switch strings.ToLower(filepath.Ext(output)) {
+		case ".json":
+			opts.Format = "json"
+		case ".html", ".htm":
+			opts.Format = "html"
+		}
+	}
+
+	w := os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			ErrorExit("Error: can't create report file '%v': %v", output, err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if _, err := pqtool.Profile(w, args[0], opts); err != nil {
+		ErrorExit("Error: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/patdeg/parquet/pqtool"
+	"strings"
+)
+
+const rewriteUsage = `parquet rewrite [-codec codec] [-row-group-size size] [-page-size size] [-page-version 1|2] [-encoding NAME=ENCODING,...] [-sort columns [-sort-memory size] [-temp-dir dir]] parquet_file output_file
+
+Rewrites a flat parquet file with another layout, decoding and encoding its rows
+without converting their values: codec (UNCOMPRESSED, SNAPPY, GZIP or ZSTD),
+sizes of row groups and pages, version of data pages, and encodings of columns
+(* for all columns) among PLAIN, PLAIN_DICTIONARY, RLE_DICTIONARY,
+DELTA_BINARY_PACKED, DELTA_BYTE_ARRAY and DELTA_LENGTH_BYTE_ARRAY. Columns keep
+their dictionary encoding unless -encoding changes it, and the file keeps its
+key-value metadata. DELTA_BINARY_PACKED integers need a range (max - min) below
+2^30 for 32 bits and 2^62 for 64 bits.
+Rows can be sorted by one or more columns with -sort, each column followed by
+:asc (default) or :desc, and :nulls_last (default) or :nulls_first. Rows are
+sorted in memory up to -sort-memory bytes (256M by default), then spilled to
+temporary files merged at the end, and the sort columns are recorded in the row
+group metadata.
+Example: parquet rewrite -codec ZSTD -row-group-size 256M -encoding 'country=PLAIN_DICTIONARY' vendor.parquet events.parquet`
+
+// Rewrite a parquet file with another codec, row group size, page size, encodings or order
+func runRewrite(args []string) {
+	var (
+		opts      pqtool.RewriteOptions
+		encodings string
+	)
+
+	fs := newFlagSet("rewrite")
+	layout := addWriterFlags(fs, &opts.Writer)
+	fs.StringVar(&encodings, "encoding", "", "encodings of columns as NAME=ENCODING,... (* for all columns)")
+	sortFlags := addSortFlags(fs, &opts.Sort)
+	args = parseArgs(fs, args, rewriteUsage, 2, 2)
+	layout.parse()
+	sortFlags.parse()
+
+	if encodings != "" {
+		opts.Encodings = strings.Split(encodings, ",")
+	}
+	if err := pqtool.Rewrite(args[0], args[1], opts); err != nil {
+		ErrorExit("Error: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/patdeg/parquet/pqtool"
+	"os"
+)
+
+const schemaDiffUsage = `parquet schema-diff parquet_file parquet_file [...]
+
+Compares the schemas of parquet files to the schema of the first file, listing
+the columns added and removed, and the changes of type, logical type (e.g. UTF8,
+DATE, DECIMAL(10,2)), nullability and order. A schema is backward compatible if
+it can read the files of the first schema (e.g. added optional columns, widened
+types like INT32 to INT64, required columns becoming optional), and forward
+compatible if the first schema can read its files (e.g. removed optional
+columns, narrowed types). The exit code classifies the compatibility of all the
+schemas:
+  0  identical schemas
+  2  backward and forward compatible
+  3  backward compatible only
+  4  forward compatible only
+  5  incompatible
+Errors (e.g. invalid flags, unreadable files) exit with 1.
+Example: parquet schema-diff dataset/part-00000.parquet new.parquet`
+
+// Exit codes of the compatibilities of schemas
+var compatibilityExitCodes = map[pqtool.Compatibility]int{
+	pqtool.Identical:          0,
+	pqtool.FullyCompatible:    2,
+	pqtool.BackwardCompatible: 3,
+	pqtool.ForwardCompatible:  4,
+	pqtool.Incompatible:       5,
+}
+
+// Compare the schemas of parquet files, exiting with their compatibility
+func runSchemaDiff(args []string) {
+	fs := newFlagSet("schema-diff")
+	args = parseArgs(fs, args, schemaDiffUsage, 2, -1)
+
+	c, err := pqtool.SchemaDiff(os.Stdout, args)
+	if err != nil {
+		ErrorExit("Error: %v", err)
+	}
+	os.Exit(compatibilityExitCodes[c])
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"github.com/patdeg/parquet/pqtool"
+	"os"
+)
+
+// Show the schema, row count, size and content of a parquet file
+func runShow(args []string) {
+
+	fs := newFlagSet("show")
+	args = parseArgs(fs, args, "parquet show parquet_file", 1, 1)
+
+	if err := pqtool.Show(os.Stdout, args[0]); err != nil {
+		ErrorExit("Error: %v", err)
+	}
+}
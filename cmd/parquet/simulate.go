@@ -0,0 +1,266 @@
+package main
+
+import (
+	"github.com/patdeg/parquet/pqtool"
+	"strconv"
+	"strings"
+)
+
+const simulateUsage = `parquet simulate [-seed seed] [-null ratio] parquet_file rows NAME:TYPE[:SEED] [NAME:TYPE[:SEED] ...]
+parquet simulate -spec spec_file [-seed seed] [-null ratio] parquet_file [rows]
+parquet simulate -spec tables_spec_file [-seed seed] [-null ratio] output_directory
+parquet simulate -clone source_file [-save-spec spec_file] [-seed seed] parquet_file [rows]
+parquet simulate -size size [options] parquet_file NAME:TYPE[:SEED] [NAME:TYPE[:SEED] ...]
+
+Options -files, -workers and -size apply to single-table simulations.
+Layout options: -codec, -row-group-size, -page-size, -encoding, -page-version, -parallel
+-csv also writes the rows of each parquet file to a CSV file of the same name.
+
+Types: INT8, INT16, INT32, INT64, UINT8, UINT16, UINT32, UINT64, FLOAT32, FLOAT64,
+DECIMAL(PRECISION,SCALE), UTF8, DATE, TIMESTAMP
+The same seed writes the same file. Each column's values depend only on the
+seed and the column name, or on the column's own SEED if given.
+Example: parquet simulate -seed 42 test.parquet 100 X:INT32 Y:FLOAT32
+
+The spec file (YAML, or JSON with a .json extension) declares the seed, the
+number of rows and the generator of each column:
+
+  seed: 42
+  rows: 1000
+  columns:
+    - {name: id, type: INT64, generator: sequence, min: 1}
+    - {name: event, type: INT64, generator: increasing, min: 1000, step: 10}
+    - {name: age, type: UINT8, min: 18, max: 99}
+    - {name: price, type: DOUBLE, generator: normal, mean: 100, stddev: 15, min: 0}
+    - {name: clicks, type: INT64, generator: zipf, exponent: 1.5, max: 10000}
+    - {name: wait, type: FLOAT64, generator: exponential, rate: 0.5}
+    - {name: score, type: FLOAT32, generator: uniform, min: 0, max: 1}
+    - {name: sku, type: UTF8, generator: pattern, pattern: "SKU-####-??"}
+    - {name: status, type: UTF8, generator: enum, values: [new, paid, sent], weights: [1, 5, 10]}
+    - {name: city, type: UTF8, min_length: 4, max_length: 10, cardinality: 50, null_ratio: 0.1}
+    - {name: user, type: UTF8, generator: uuid}
+    - {name: day, type: DATE, from: 2020-01-01, to: 2020-12-31}
+    - {name: at, type: TIMESTAMP, generator: sequence, from: 2020-01-01, step: 60}
+
+Generators: uniform, normal, exponential, zipf and sequence for numbers,
+increasing (random gaps up to step) for integers, random, pattern and uuid for
+text, uniform and sequence for dates, enum for all. Integers are uniform on the
+range of their type (or min to max) by default, and sequences are checked not
+to overflow it. Decimals (type DECIMAL with a precision up to 18 and a scale in
+spec files) are generated as integers of their unscaled values: min: 1050 is
+10.50 in DECIMAL(10,2).
+Columns with a null_ratio (or -null) are OPTIONAL and get nulls with this probability.
+
+Spec files can also declare nested columns: LIST with items, MAP with a key and
+a value, and STRUCT with fields, each declared like a column. min_length,
+max_length (0 to 5 by default) and length_weights give the number of items of
+lists and maps. Items and values with a null_ratio are OPTIONAL. Map keys are
+unique in a map, whose entries are in no particular order. Lists and maps can't
+be items of lists or values of maps directly, but can be struct fields:
+
+    - name: tags
+      type: LIST
+      max_length: 3
+      items: {type: UTF8, generator: enum, values: [red, green, blue], null_ratio: 0.1}
+    - name: attrs
+      type: MAP
+      key: {type: UTF8, generator: pattern, pattern: "key#"}
+      value: {type: DOUBLE, generator: uniform, min: 0, max: 1}
+    - name: address
+      type: STRUCT
+      fields:
+        - {name: city, type: UTF8, cardinality: 50}
+        - {name: zip, type: INT32, min: 10000, max: 99999, null_ratio: 0.2}
+
+A spec file with tables instead of columns writes one parquet file per table
+in the output directory. Foreign keys reference the column TABLE.COLUMN of
+another table, whose values must be unique (e.g. from a sequence generator, as
+random values can repeat and stop the simulation), and take its values with a
+uniform or zipf fanout (few keys get most rows), or with orphan_ratio values
+that aren't referenced:
+
+  seed: 42
+  tables:
+    - name: customers
+      rows: 1000
+      columns:
+        - {name: id, type: INT64, generator: sequence}
+        - {name: country, type: UTF8, generator: enum, values: [US, FR, JP]}
+    - name: orders
+      rows: 100000
+      columns:
+        - {name: id, type: INT64, generator: sequence}
+        - {name: customer_id, type: INT64, references: customers.id, fanout: zipf, orphan_ratio: 0.01, min: 1000000}
+
+With -clone, the columns have the schema of the source parquet file and values
+with a similar profile (null ratios, ranges, distributions, number of distinct
+values, string lengths) without copying its values. The number of rows is the
+one of the source file by default. -save-spec writes the derived spec to edit it
+
+With -files N, the rows are split into N files (e.g. test-00000.parquet,
+test-00001.parquet) written in parallel by -workers workers, the number of
+CPUs by default. Each file has its own random sources derived from the seed,
+so files have the same rows whatever the number of workers, and sequences
+continue from one file to the next. With -size (e.g. 500M, 10G), the number of rows is
+estimated from a sample to write files of about this total size, and the rows
+argument is omitted. A throughput report ends multi-file and sized simulations.
+Example: parquet simulate -spec spec.yaml -files 16 -size 10G data.parquet
+
+The layout options write files to benchmark readers: -codec UNCOMPRESSED,
+SNAPPY (default), GZIP or ZSTD, -row-group-size (128M by default) and
+-page-size (8K by default), -page-version 2 for data pages v2 (except for
+dictionary-encoded columns), and -parallel, the number of goroutines encoding
+the pages of a file (1 with dictionary-encoded columns, for reproducible
+files). -encoding sets the encoding of columns as NAME=ENCODING
+(TABLE.COLUMN for tables, * for all columns) among PLAIN, PLAIN_DICTIONARY,
+RLE_DICTIONARY, DELTA_BINARY_PACKED (integers, dates and timestamps),
+DELTA_BYTE_ARRAY and DELTA_LENGTH_BYTE_ARRAY (strings), overriding the
+encoding of spec columns. Strings are dictionary-encoded by default, PLAIN
+turns the dictionary off and RLE_DICTIONARY on for other columns. Random
+DELTA_BINARY_PACKED integers need a range (max - min) below 2^30 for 32 bits
+and 2^62 for 64 bits, the default range without min and max.
+Example: parquet simulate -codec ZSTD -encoding '*=PLAIN,id=DELTA_BINARY_PACKED' test.parquet 1000 ID:INT64 S:UTF8
+
+With -csv, the CSV files (test.csv for test.parquet) have the same rows in the
+format of parquet export -H: a header line, numbers, dates as 2006-01-02,
+timestamps as 2006-01-02 15:04:05.999 and empty nulls. They are the expected
+output of parquet export -H on the parquet files, except for nested columns
+which are rejected. parquet convert doesn't read them back as the same parquet
+files: it infers REQUIRED INT64, DOUBLE, DATE, TIMESTAMP_MILLIS or BYTE_ARRAY
+columns from the first row (e.g. INT32 and UINT8 columns come back as INT64)
+and reads empty values as zeros, empty text or dates of year 1. As CSV values
+aren't quoted, names, enum values and patterns with commas or line breaks are
+rejected.
+Example: parquet simulate -csv -seed 42 test.parquet 100 X:INT64 Y:FLOAT64 D:DATE`
+
+// Write a parquet file of random data
+func runSimulate(args []string) {
+
+	var (
+		opts      pqtool.SimulateOptions
+		specFile  string
+		cloneFile string
+		saveSpec  string
+		size      string
+		encodings string
+		seed      int64
+	)
+
+	fs := newFlagSet("simulate")
+	fs.Int64Var(&seed, "seed", 0, "seed of the random values (default: random seed)")
+	fs.StringVar(&specFile, "spec", "", "YAML or JSON file declaring the columns")
+	fs.Float64Var(&opts.NullRatio, "null", 0, "probability of a null in columns without their own null_ratio")
+	fs.StringVar(&cloneFile, "clone", "", "parquet file whose schema and profile are simulated")
+	fs.StringVar(&saveSpec, "save-spec", "", "YAML or JSON file where the spec is saved")
+	fs.IntVar(&opts.Files, "files", 1, "number of files the rows are split into")
+	fs.IntVar(&opts.Workers, "workers", 0, "number of files written in parallel (0 for the number of CPUs)")
+	fs.StringVar(&size, "size", "", "total size of the files (e.g. 500M, 10G) instead of a number of rows")
+	layout := addWriterFlags(fs, &opts.Writer)
+	fs.StringVar(&encodings, "encoding", "", "encodings of columns as NAME=ENCODING,... (* for all columns)")
+	fs.BoolVar(&opts.CSV, "csv", false, "also write the rows to CSV files next to the parquet files")
+	args = parseArgs(fs, args, simulateUsage, 1, -1)
+	if isFlagSet(fs, "seed") {
+		opts.Seed = &seed
+	}
+
+	// 1st parameter: Parquet filename to create
+	filename := args[0]
+
+	var (
+		nRows   int
+		columns []pqtool.Column
+		err     error
+	)
+
+	if size != "" {
+		if opts.TargetSize, err = pqtool.ParseSize(size); err != nil || opts.TargetSize == 0 {
+			ErrorExit("Error: Invalid size %v", size)
+		}
+	}
+	if opts.Files < 1 {
+		ErrorExit("Error: Invalid number of files %v", opts.Files)
+	}
+	layout.parse()
+
+	if specFile != "" || cloneFile != "" {
+		if len(args) > 2 || (specFile != "" && cloneFile != "") {
+			ErrorExit("Usage:\n%v", simulateUsage)
+		}
+		var spec *pqtool.Spec
+		if specFile != "" {
+			spec, err = pqtool.LoadSpec(specFile)
+		} else {
+			spec, err = pqtool.CloneSpec(cloneFile)
+		}
+		if err != nil {
+			ErrorExit("Error: %v", err)
+		}
+		columns, nRows = spec.Columns, spec.Rows
+		if opts.Seed == nil {
+			opts.Seed = spec.Seed
+		}
+
+		// Tables: write a file per table in the output directory
+		if len(spec.Tables) > 0 {
+			if len(args) > 1 {
+				ErrorExit("Usage:\n%v", simulateUsage)
+			}
+			setEncodings(spec, encodings)
+			if saveSpec != "" {
+				if err = pqtool.SaveSpec(saveSpec, spec); err != nil {
+					ErrorExit("Error: %v", err)
+				}
+			}
+			if err = pqtool.SimulateTables(filename, spec.Tables, opts); err != nil {
+				ErrorExit("Error: %v", err)
+			}
+			return
+		}
+	} else if opts.TargetSize > 0 {
+		// No number of rows: the columns follow the file name
+		if len(args) < 2 {
+			ErrorExit("Usage:\n%v", simulateUsage)
+		}
+		args = append([]string{args[0], "0"}, args[1:]...)
+	} else if len(args) < 3 {
+		ErrorExit("Usage:\n%v", simulateUsage)
+	}
+
+	// 2nd parameter: Number of rows to simulate
+	if len(args) > 1 {
+		nRows, err = strconv.Atoi(args[1])
+		if err != nil {
+			ErrorExit("Error: Invalid number of rows %v: %v", args[1], err)
+		}
+	}
+
+	// 3+th parameter: Parquet fields/columns name, data type and optional seed
+	if specFile == "" && cloneFile == "" {
+		columns, err = pqtool.ParseColumns(args[2:])
+		if err != nil {
+			ErrorExit("Error: %v", err)
+		}
+	}
+
+	spec := &pqtool.Spec{Seed: opts.Seed, Rows: nRows, Columns: columns}
+	setEncodings(spec, encodings)
+	if saveSpec != "" {
+		if err = pqtool.SaveSpec(saveSpec, spec); err != nil {
+			ErrorExit("Error: %v", err)
+		}
+	}
+
+	if err = pqtool.Simulate(filename, nRows, columns, opts); err != nil {
+		ErrorExit("Error: %v", err)
+	}
+}
+
+// Set the encodings of the columns of a spec given as NAME=ENCODING,...
+func setEncodings(spec *pqtool.Spec, encodings string) {
+	if encodings == "" {
+		return
+	}
+	if err := pqtool.SetEncodings(spec, strings.Split(encodings, ",")); err != nil {
+		ErrorExit("Error: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/patdeg/parquet/pqtool"
+)
+
+const splitUsage = `parquet split -rows n | -parts n | -size size | -row-groups [layout options] parquet_file output_file
+
+Splits a parquet file into parts named like output_file with their index
+(output-00000.parquet, output-00001.parquet, ...), with its schema and
+key-value metadata:
+  -rows n       parts of n rows, the last part with the remaining rows
+  -parts n      n parts with the same number of rows
+  -size size    parts of about size bytes (e.g. 1G) made of whole row groups
+  -row-groups   one part per row group
+Row groups are copied without being decoded, by size, by row group, and by
+rows when the parts start at row groups. Else the rows are decoded (flat files
+only) and written with their column types and the layout options: by rows,
+and by size when a row group is larger than the size, the number of rows of
+the parts being estimated from the size of a row in the file: -codec
+(default: codec of the file), -row-group-size, -page-size, -page-version,
+-parallel
+Example: parquet split -size 1G big.parquet parts/big.parquet`
+
+// Split a parquet file by rows, size or row group
+func runSplit(args []string) {
+	var (
+		opts pqtool.SplitOptions
+		size string
+	)
+
+	fs := newFlagSet("split")
+	fs.Int64Var(&opts.Rows, "rows", 0, "number of rows of each part")
+	fs.IntVar(&opts.Parts, "parts", 0, "number of parts")
+	fs.StringVar(&size, "size", "", "size of the parts (e.g. 512M, 1G)")
+	fs.BoolVar(&opts.RowGroups, "row-groups", false, "one part per row group")
+	layout := addWriterFlags(fs, &opts.Writer)
+	opts.Writer.Compression, fs.Lookup("codec").DefValue = "", "" // codec of the file by default
+	args = parseArgs(fs, args, splitUsage, 2, 2)
+
+	var err error
+	if size != "" {
+		if opts.TargetSize, err = pqtool.ParseSize(size); err != nil || opts.TargetSize == 0 {
+			ErrorExit("Error: Invalid size %v", size)
+		}
+	}
+	if opts.Rows < 0 || opts.Parts < 0 {
+		ErrorExit("Error: Invalid number of rows or parts")
+	}
+	layout.parse()
+
+	if err = pqtool.Split(args[0], args[1], opts); err != nil {
+		ErrorExit("Error: %v", err)
+	}
+}
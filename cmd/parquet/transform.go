@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/patdeg/parquet/pqtool"
+)
+
+const transformUsage = `parquet transform [-drop columns] [-rename OLD=NEW,...] [-cast NAME=TYPE,...] [-add NAME:TYPE=VALUE,...] [-order columns] [-lossy] [layout options] parquet_file output_file
+
+Writes a flat parquet file with columns dropped, renamed, cast, added and
+reordered, in this order (casts and order use the new names):
+  -drop a,b               drop columns
+  -rename old=new,...     rename columns
+  -cast NAME=TYPE,...     cast columns to INT32, INT64, INT8, UINT16, FLOAT,
+                          DOUBLE, DECIMAL(precision,scale), UTF8, BOOLEAN, DATE,
+                          TIMESTAMP, TIMESTAMP_MICROS... Text is parsed, with the
+                          layout after the type for dates and timestamps (e.g.
+                          DATE:01/02/2006, default 2006-01-02 and 2006-01-02 15:04:05)
+  -add NAME:TYPE=VALUE,...  add columns with a constant value, null if empty,
+                          commas and backslashes in values escaped as \, and \\
+  -order a,b              put these columns first, the others following
+Casts must be lossless: a value that can't be converted exactly (e.g. 1.5 to
+INT64, 300 to INT8, text that isn't a number) stops the transform with its row.
+With -lossy, cast columns are optional, these values are null, and the number of
+lossy values of each column is reported with their first rows.
+Layout options: -codec, -row-group-size, -page-size, -page-version, -parallel
+Example: parquet transform -drop tmp -rename ts=event_time -cast 'id=INT64,price=DECIMAL(10,2),day=DATE:01/02/2006' -add 'source:UTF8=web' events.parquet events2.parquet`
+
+// Drop, rename, cast, add or reorder the columns of a parquet file
+func runTransform(args []string) {
+	var (
+		opts                           pqtool.TransformOptions
+		drop, rename, cast, add, order string
+	)
+
+	fs := newFlagSet("transform")
+	fs.StringVar(&drop, "drop", "", "columns to drop")
+	fs.StringVar(&rename, "rename", "", "columns to rename as OLD=NEW,...")
+	fs.StringVar(&cast, "cast", "", "columns to cast as NAME=TYPE,...")
+	fs.StringVar(&add, "add", "", "columns to add as NAME:TYPE=VALUE,...")
+	fs.StringVar(&order, "order", "", "first columns in order")
+	fs.BoolVar(&opts.Lossy, "lossy", false, "write the values that can't be cast exactly as null instead of failing")
+	layout := addWriterFlags(fs, &opts.Writer)
+	args = parseArgs(fs, args, transformUsage, 2, 2)
+	layout.parse()
+
+	opts.Drop = splitList(drop)
+	opts.Rename = splitList(rename)
+	opts.Cast = splitList(cast)
+	opts.Add = splitValues(add)
+	opts.Order = splitList(order)
+	if err := pqtool.Transform(args[0], args[1], opts); err != nil {
+		ErrorExit("Error: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/patdeg/parquet/pqtool"
+	"os"
+)
+
+const validateUsage = `parquet validate parquet_file...
+
+Checks parquet files: magic bytes, footer length and metadata, page headers,
+CRC checksums of the pages that have one, decompression and decoding of every
+page, numbers of values and rows of the column chunks and row groups, and
+min/max statistics and null counts against the values. Each problem is printed
+with its row group, column and page (indexes from 0). Exits with 0 if all the
+files are valid, else 2.
+Example: parquet validate events.parquet`
+
+// Validate parquet files
+func runValidate(args []string) {
+
+	fs := newFlagSet("validate")
+	args = parseArgs(fs, args, validateUsage, 1, -1)
+
+	valid := true
+	for _, filename := range args {
+		problems, err := pqtool.Validate(os.Stdout, filename)
+		if err != nil {
+			ErrorExit("Error: %v", err)
+		}
+		valid = valid && len(problems) == 0
+	}
+	if !valid {
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,20 @@
+module github.com/patdeg/parquet
+
+go 1.21
+
+require (
+	github.com/apache/arrow/go/arrow v0.0.0-20200730104253-651201b0f516
+	github.com/apache/thrift v0.13.1-0.20201008052519-daf620915714
+	github.com/linkedin/goavro/v2 v2.10.0
+	github.com/mattn/go-sqlite3 v1.14.6
+	github.com/xitongsys/parquet-go v1.5.4
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/golang/snappy v0.0.1 // indirect
+	github.com/google/flatbuffers v1.11.0 // indirect
+	github.com/klauspost/compress v1.10.5 // indirect
+	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
+)
@@ -0,0 +1,447 @@
+package pqtool
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/decimal128"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+	"io"
+	"math/big"
+	"os"
+	"reflect"
+)
+
+// ArrowOptions are the options to convert a parquet file to Arrow
+type ArrowOptions struct {
+	Stream    bool // Write the Arrow IPC stream format instead of the file format
+	BatchSize int  // Rows per record batch, 10000 if zero
+}
+
+// Map the parquet type of a field to an Arrow data type
+func arrowType(f Field) arrow.DataType {
+	switch f.Type {
+	case "BOOLEAN":
+		return arrow.FixedWidthTypes.Boolean
+	case "INT32":
+		return arrow.PrimitiveTypes.Int32
+	case "INT64":
+		return arrow.PrimitiveTypes.Int64
+	case "INT_8":
+		return arrow.PrimitiveTypes.Int8
+	case "INT_16":
+		return arrow.PrimitiveTypes.Int16
+	case "INT_32":
+		return arrow.PrimitiveTypes.Int32
+	case "INT_64":
+		return arrow.PrimitiveTypes.Int64
+	case "UINT_8":
+		return arrow.PrimitiveTypes.Uint8
+	case "UINT_16":
+		return arrow.PrimitiveTypes.Uint16
+	case "UINT_32":
+		return arrow.PrimitiveTypes.Uint32
+	case "UINT_64":
+		return arrow.PrimitiveTypes.Uint64
+	case "FLOAT":
+		return arrow.PrimitiveTypes.Float32
+	case "DOUBLE":
+		return arrow.PrimitiveTypes.Float64
+	case "UTF8":
+		return arrow.BinaryTypes.String
+	case "BYTE_ARRAY":
+		return arrow.BinaryTypes.Binary
+	case "DECIMAL":
+		return &arrow.Decimal128Type{Precision: int32(f.Precision), Scale: int32(f.Scale)}
+	case "DATE":
+		return arrow.PrimitiveTypes.Date32
+	case "TIMESTAMP_MILLIS":
+		return arrow.FixedWidthTypes.Timestamp_ms
+	case "TIMESTAMP_MICROS":
+		return arrow.FixedWidthTypes.Timestamp_us
+	}
+	return nil
+}
+
+// Map an Arrow data type to a parquet type
+func arrowParquetType(dt arrow.DataType) string {
+
+	switch dt.ID() {
+	case arrow.BOOL:
+		return "BOOLEAN"
+	case arrow.INT8:
+		return "INT_8"
+	case arrow.INT16:
+		return "INT_16"
+	case arrow.INT32:
+		return "INT32"
+	case arrow.INT64:
+		return "INT64"
+	case arrow.UINT8:
+		return "UINT_8"
+	case arrow.UINT16:
+		return "UINT_16"
+	case arrow.UINT32:
+		return "UINT_32"
+	case arrow.UINT64:
+		return "UINT_64"
+	case arrow.FLOAT32:
+		return "FLOAT"
+	case arrow.FLOAT64:
+		return "DOUBLE"
+	case arrow.STRING:
+		return "UTF8"
+	case arrow.BINARY:
+		return "BYTE_ARRAY"
+	case arrow.DECIMAL:
+		return "DECIMAL"
+	case arrow.DATE32, arrow.DATE64:
+		return "DATE"
+	case arrow.TIMESTAMP:
+		switch dt.(*arrow.TimestampType).Unit {
+		case arrow.Second, arrow.Millisecond:
+			return "TIMESTAMP_MILLIS"
+		default:
+			return "TIMESTAMP_MICROS"
+		}
+	}
+
+	return ""
+}
+
+// Build the Arrow schema of flat parquet columns
+func ArrowSchema(fields []Field) (*arrow.Schema, error) {
+	arrowFields := make([]arrow.Field, len(fields))
+	for i, f := range fields {
+		dt := arrowType(f)
+		if dt == nil {
+			return nil, fmt.Errorf("invalid type for field %v: %v", f.Name, f.Type)
+		}
+		arrowFields[i] = arrow.Field{
+			Name:     f.Name,
+			Type:     dt,
+			Nullable: f.Optional,
+		}
+	}
+	return arrow.NewSchema(arrowFields, nil), nil
+}
+
+// Return the parquet columns of an Arrow schema, nullable Arrow fields being
+// OPTIONAL columns. Decimals of more than 18 digits are stored in byte arrays
+func ArrowFields(schema *arrow.Schema) ([]Field, error) {
+	fields := make([]Field, len(schema.Fields()))
+	for i, field := range schema.Fields() {
+		fields[i] = Field{
+			Name:     field.Name,
+			Type:     arrowParquetType(field.Type),
+			Optional: field.Nullable,
+		}
+		if dt, ok := field.Type.(*arrow.Decimal128Type); ok {
+			fields[i].Precision, fields[i].Scale = int(dt.Precision), int(dt.Scale)
+			if dt.Precision > 18 {
+				fields[i].BaseType = "BYTE_ARRAY"
+			}
+		}
+		if fields[i].Type == "" {
+			return nil, fmt.Errorf("invalid type for field %v: %v", field.Name, field.Type)
+		}
+	}
+	return fields, nil
+}
+
+// Append a parquet value to an Arrow column builder
+func appendValue(b array.Builder, v reflect.Value, parquetType string) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			b.AppendNull()
+			return
+		}
+		v = v.Elem()
+	}
+	switch parquetType {
+	case "BOOLEAN":
+		b.(*array.BooleanBuilder).Append(v.Bool())
+	case "INT32":
+		b.(*array.Int32Builder).Append(int32(v.Int()))
+	case "INT64", "INT_64":
+		b.(*array.Int64Builder).Append(v.Int())
+	case "INT_8":
+		b.(*array.Int8Builder).Append(int8(v.Int()))
+	case "INT_16":
+		b.(*array.Int16Builder).Append(int16(v.Int()))
+	case "INT_32":
+		b.(*array.Int32Builder).Append(int32(v.Int()))
+	case "UINT_8":
+		b.(*array.Uint8Builder).Append(uint8(v.Uint()))
+	case "UINT_16":
+		b.(*array.Uint16Builder).Append(uint16(v.Uint()))
+	case "UINT_32":
+		b.(*array.Uint32Builder).Append(uint32(v.Uint()))
+	case "UINT_64":
+		b.(*array.Uint64Builder).Append(v.Uint())
+	case "FLOAT":
+		b.(*array.Float32Builder).Append(float32(v.Float()))
+	case "DOUBLE":
+		b.(*array.Float64Builder).Append(v.Float())
+	case "UTF8":
+		b.(*array.StringBuilder).Append(v.String())
+	case "BYTE_ARRAY":
+		b.(*array.BinaryBuilder).Append([]byte(v.String()))
+	case "DECIMAL":
+		b.(*array.Decimal128Builder).Append(decimal128.FromI64(v.Int()))
+	case "DATE":
+		b.(*array.Date32Builder).Append(arrow.Date32(v.Int()))
+	case "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS":
+		b.(*array.TimestampBuilder).Append(arrow.Timestamp(v.Int()))
+	}
+}
+
+// Return the value at row i of an Arrow column, converted to the parquet representation
+func arrowValue(col array.Interface, i int) interface{} {
+	switch a := col.(type) {
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Int8:
+		return a.Value(i)
+	case *array.Int16:
+		return a.Value(i)
+	case *array.Int32:
+		return a.Value(i)
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint8:
+		return a.Value(i)
+	case *array.Uint16:
+		return a.Value(i)
+	case *array.Uint32:
+		return a.Value(i)
+	case *array.Uint64:
+		return a.Value(i)
+	case *array.Float32:
+		return a.Value(i)
+	case *array.Float64:
+		return a.Value(i)
+	case *array.String:
+		return a.Value(i)
+	case *array.Binary:
+		return string(a.Value(i))
+	case *array.Date32:
+		return int32(a.Value(i))
+	case *array.Decimal128:
+		// Unscaled value, as an integer up to 18 digits and as bytes beyond
+		x := a.Value(i)
+		if a.DataType().(*arrow.Decimal128Type).Precision <= 18 {
+			return int64(x.LowBits())
+		}
+		n := new(big.Int).Lsh(big.NewInt(x.HighBits()), 64)
+		n.Add(n, new(big.Int).SetUint64(x.LowBits()))
+		return decimalBytes(new(big.Rat).SetInt(n), 0, 0)
+	case *array.Date64:
+		// Milliseconds to days, rounded down before 1970
+		ms := int64(a.Value(i))
+		days := ms / (24 * 60 * 60 * 1000)
+		if ms%(24*60*60*1000) < 0 {
+			days--
+		}
+		return int32(days)
+	case *array.Timestamp:
+		x := int64(a.Value(i))
+		switch a.DataType().(*arrow.TimestampType).Unit {
+		case arrow.Second:
+			return x * 1000
+		case arrow.Nanosecond:
+			return x / 1000
+		}
+		return x
+	}
+	return nil
+}
+
+// Arrow IPC writer, either in file or in stream format
+type recordWriter interface {
+	Write(rec array.Record) error
+	Close() error
+}
+
+// Read a parquet file and write its rows in Arrow IPC format, one record batch per read batch
+func ParquetToArrow(parquet_filename string, arrow_filename string, opts ArrowOptions) error {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 10000
+	}
+
+	pr, err := OpenReader(parquet_filename)
+	if err != nil {
+		return err
+	}
+	defer pr.Close()
+
+	schema, err := ArrowSchema(pr.Fields)
+	if err != nil {
+		return err
+	}
+	logf("Structure:")
+	for _, f := range schema.Fields() {
+		logf("  %v: %v", f.Name, f.Type)
+	}
+
+	Debug("Creating Arrow file %v", arrow_filename)
+	f, err := os.Create(arrow_filename)
+	if err != nil {
+		return fmt.Errorf("can't create Arrow file '%v': %v", arrow_filename, err)
+	}
+	defer f.Close()
+
+	mem := memory.NewGoAllocator()
+	var w recordWriter
+	if opts.Stream {
+		w = ipc.NewWriter(f, ipc.WithSchema(schema), ipc.WithAllocator(mem))
+	} else {
+		w, err = ipc.NewFileWriter(f, ipc.WithSchema(schema), ipc.WithAllocator(mem))
+		if err != nil {
+			return fmt.Errorf("can't create Arrow writer: %v", err)
+		}
+	}
+
+	rb := array.NewRecordBuilder(mem, schema)
+	defer rb.Release()
+
+	nRows := 0
+	for {
+		slice, err := pr.Read(opts.BatchSize)
+		if err != nil {
+			return err
+		}
+		if slice.Len() == 0 {
+			break
+		}
+
+		for i := 0; i < slice.Len(); i++ {
+			for j, field := range pr.Fields {
+				appendValue(rb.Field(j), slice.Index(i).Field(j), field.Type)
+			}
+		}
+
+		rec := rb.NewRecord()
+		Debug("Writing record batch with %v rows", rec.NumRows())
+		err = w.Write(rec)
+		rec.Release()
+		if err != nil {
+			return fmt.Errorf("writing to Arrow file: %v", err)
+		}
+		nRows += slice.Len()
+	}
+
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("closing Arrow file: %v", err)
+	}
+
+	logf("Arrow file %v written with %v rows and %v fields", arrow_filename, nRows, len(pr.Fields))
+	return nil
+}
+
+// Arrow IPC reader, either in file or in stream format
+type recordReader interface {
+	Schema() *arrow.Schema
+	Read() (array.Record, error)
+}
+
+// Open an Arrow IPC file, detecting the file format (Feather v2) from its magic bytes
+func openArrow(f *os.File, mem memory.Allocator) (recordReader, error) {
+	magic := make([]byte, 6)
+	if _, err := io.ReadFull(f, magic); err != nil && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("reading Arrow file: %v", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("reading Arrow file: %v", err)
+	}
+
+	if bytes.Equal(magic, []byte("ARROW1")) {
+		Debug("Arrow IPC file format")
+		r, err := ipc.NewFileReader(f, ipc.WithAllocator(mem))
+		if err != nil {
+			return nil, fmt.Errorf("can't create Arrow file reader: %v", err)
+		}
+		return r, nil
+	}
+
+	Debug("Arrow IPC stream format")
+	r, err := ipc.NewReader(f, ipc.WithAllocator(mem))
+	if err != nil {
+		return nil, fmt.Errorf("can't create Arrow stream reader: %v", err)
+	}
+	return r, nil
+}
+
+// Read an Arrow IPC file (file or stream format) and convert it to parquet
+func ArrowToParquet(arrow_filename string, parquet_filename string) error {
+
+	Debug("Open Arrow File")
+	f, err := os.Open(arrow_filename)
+	if err != nil {
+		return fmt.Errorf("can't open Arrow file '%v': %v", arrow_filename, err)
+	}
+	defer f.Close()
+
+	ar, err := openArrow(f, memory.NewGoAllocator())
+	if err != nil {
+		return err
+	}
+
+	fields, err := ArrowFields(ar.Schema())
+	if err != nil {
+		return err
+	}
+	logf("Structure:")
+	for i, field := range ar.Schema().Fields() {
+		logf("  %v: %v -> %v", field.Name, field.Type, fields[i].TypeName())
+	}
+
+	pw, err := CreateFieldsWriter(parquet_filename, fields)
+	if err != nil {
+		return err
+	}
+
+	// Loop through each record batch
+	nRows := 0
+	for {
+		rec, err := ar.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			pw.Close()
+			return fmt.Errorf("reading Arrow file '%v': %v", arrow_filename, err)
+		}
+		Debug("Read record batch with %v rows", rec.NumRows())
+
+		for i := 0; i < int(rec.NumRows()); i++ {
+			v := pw.NewRow()
+			for j := range fields {
+				col := rec.Column(j)
+				if col.IsNull(i) {
+					continue
+				}
+				x := arrowValue(col, i)
+				if x == nil {
+					pw.Close()
+					return fmt.Errorf("unkown Arrow type %v", col.DataType())
+				}
+				setField(v.Field(j), x)
+			}
+			if err = pw.Write(v); err != nil {
+				pw.Close()
+				return err
+			}
+		}
+		nRows += int(rec.NumRows())
+	}
+
+	if err = pw.Close(); err != nil {
+		return err
+	}
+
+	logf("Parquet file %v written with %v rows and %v fields", parquet_filename, nRows, len(fields))
+	return nil
+}
@@ -0,0 +1,76 @@
+package pqtool
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/decimal128"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+)
+
+// Files converted to Arrow files or streams and back have the same values
+func TestArrowRoundTrip(t *testing.T) {
+	nFields := len(roundTripFields)
+	checkRoundTrip(t, "arrow", nFields,
+		func(in, out string) error { return ParquetToArrow(in, out, ArrowOptions{BatchSize: 7}) },
+		ArrowToParquet)
+	checkRoundTrip(t, "arrows", nFields,
+		func(in, out string) error { return ParquetToArrow(in, out, ArrowOptions{Stream: true}) },
+		ArrowToParquet)
+}
+
+// Dates in milliseconds are rounded down to days, and decimals of more than 18
+// digits are converted to byte arrays
+func TestArrowDate64Decimal(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "input.arrow"), filepath.Join(dir, "output.parquet")
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "day", Type: arrow.PrimitiveTypes.Date64},
+		{Name: "amount", Type: &arrow.Decimal128Type{Precision: 30, Scale: 2}},
+	}, nil)
+
+	mem := memory.NewGoAllocator()
+	rb := array.NewRecordBuilder(mem, schema)
+	defer rb.Release()
+	rb.Field(0).(*array.Date64Builder).AppendValues([]arrow.Date64{-1, -86400000, -86400001, 0, 86399999}, nil)
+	rb.Field(1).(*array.Decimal128Builder).AppendValues([]decimal128.Num{
+		decimal128.New(1, 5), decimal128.FromI64(-12345), decimal128.New(-2, 0), decimal128.FromI64(0), decimal128.FromI64(1),
+	}, nil)
+	rec := rb.NewRecord()
+	defer rec.Release()
+
+	f, err := os.Create(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := ipc.NewFileWriter(f, ipc.WithSchema(schema), ipc.WithAllocator(mem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err = ArrowToParquet(input, output); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"day=1969-12-31,amount=184467440737095516.21",
+		"day=1969-12-31,amount=-123.45",
+		"day=1969-12-30,amount=-368934881474191032.32",
+		"day=1970-01-01,amount=0.00",
+		"day=1970-01-01,amount=0.01",
+	}
+	if rows := rowsText(t, output); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("rows %v, expected %v", rows, expected)
+	}
+}
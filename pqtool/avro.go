@@ -0,0 +1,855 @@
+package pqtool
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/linkedin/goavro/v2"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/schema"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Key of the parquet key-value metadata holding the original Avro schema
+const AvroSchemaKey = "avro.schema"
+
+// AvroOptions are the options to convert a parquet file to Avro
+type AvroOptions struct {
+	Compression string // Avro block compression: null, deflate or snappy (default)
+	BatchSize   int    // Rows per Avro block, 1000 if zero
+}
+
+// Avro named types (records, enums, fixed) by full and short name
+type avroNames map[string]interface{}
+
+// Convert an Avro native value (as decoded by goavro) into a Parquet reflection
+type fromAvro func(x interface{}) reflect.Value
+
+// Convert a Parquet reflection into an Avro native value (as encoded by goavro)
+type toAvro func(v reflect.Value) interface{}
+
+// Return the full name of an Avro named type
+func fullName(name string, namespace string) string {
+	if namespace == "" || strings.Contains(name, ".") {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// Register a named Avro type (record, enum, fixed) and return the namespace it defines for its children
+func (names avroNames) register(schema map[string]interface{}, namespace string) string {
+	name, _ := schema["name"].(string)
+	if ns, ok := schema["namespace"].(string); ok {
+		namespace = ns
+	}
+	names[fullName(name, namespace)] = schema
+	names[name] = schema
+	if i := strings.LastIndex(fullName(name, namespace), "."); i >= 0 {
+		return fullName(name, namespace)[:i]
+	}
+	return namespace
+}
+
+// Return the definition of a named Avro type, nil if it is not a named type
+func (names avroNames) lookup(name string, namespace string) interface{} {
+	if def, ok := names[fullName(name, namespace)]; ok {
+		return def
+	}
+	return names[name]
+}
+
+// Return if an Avro schema is a union with null, and the non-null type
+func unwrapNull(schema interface{}) (bool, interface{}, error) {
+	union, ok := schema.([]interface{})
+	if !ok {
+		return false, schema, nil
+	}
+	var types []interface{}
+	for _, t := range union {
+		if t != "null" {
+			types = append(types, t)
+		}
+	}
+	if len(types) != 1 {
+		return false, nil, fmt.Errorf("Avro unions other than [\"null\", type] are not supported: %v", schema)
+	}
+	return len(types) < len(union), types[0], nil
+}
+
+// Return the single value wrapped in an Avro union, as decoded by goavro
+func unwrapUnion(x interface{}) interface{} {
+	if m, ok := x.(map[string]interface{}); ok && len(m) == 1 {
+		for _, v := range m {
+			return v
+		}
+	}
+	return x
+}
+
+// Return an integer attribute of an Avro schema (e.g. size, precision, scale)
+func getInt(schema map[string]interface{}, key string) int {
+	if x, ok := schema[key].(float64); ok {
+		return int(x)
+	}
+	return 0
+}
+
+// Return the unscaled value of a decimal as big-endian two's complement bytes, padded to size if not zero
+func decimalBytes(r *big.Rat, scale int, size int) string {
+	n := new(big.Int).Mul(r.Num(), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+	n.Quo(n, r.Denom())
+
+	x := n
+	if n.Sign() < 0 {
+		x = new(big.Int).Not(n)
+	}
+	k := (x.BitLen() + 8) / 8
+	if size > k {
+		k = size
+	}
+	if n.Sign() < 0 {
+		x = new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), uint(8*k)))
+	}
+
+	b := x.Bytes()
+	out := make([]byte, k)
+	if n.Sign() < 0 {
+		for i := range out {
+			out[i] = 0xff
+		}
+	}
+	copy(out[k-len(b):], b)
+	return string(out)
+}
+
+// Return an integer from a reflection of any signed or unsigned integer kind
+func toInt(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
+
+// Return a decimal from its unscaled value, either big-endian two's complement bytes or an integer
+func toDecimal(v reflect.Value, scale int) *big.Rat {
+	n := new(big.Int)
+	if v.Kind() == reflect.String {
+		b := []byte(v.String())
+		n.SetBytes(b)
+		if len(b) > 0 && b[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+		}
+	} else {
+		n.SetInt64(toInt(v))
+	}
+	return new(big.Rat).SetFrac(n, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+}
+
+// Format parquet tag items, prefixed for list and map values (e.g. valuetype)
+func tagItems(prefix string, items ...string) string {
+	tag := ""
+	for i := 0; i+1 < len(items); i += 2 {
+		if tag != "" {
+			tag += ", "
+		}
+		tag += prefix + items[i] + "=" + items[i+1]
+	}
+	return tag
+}
+
+// Map a primitive Avro type (with its optional logical type) to
+// * Type reflection
+// * Parquet tag
+// * Converter from Avro
+func parquetPrimitive(avroType string, schema map[string]interface{}, prefix string) (reflect.Type, string, fromAvro, error) {
+	logicalType, _ := schema["logicalType"].(string)
+	identity := func(x interface{}) reflect.Value { return reflect.ValueOf(x) }
+
+	switch avroType + "." + logicalType {
+	case "int.date":
+		return reflect.TypeOf(int32(0)), tagItems(prefix, "type", "DATE"),
+			func(x interface{}) reflect.Value { return reflect.ValueOf(ToDate(x.(time.Time))) }, nil
+	case "int.time-millis":
+		return reflect.TypeOf(int32(0)), tagItems(prefix, "type", "TIME_MILLIS"),
+			func(x interface{}) reflect.Value { return reflect.ValueOf(int32(x.(time.Duration) / time.Millisecond)) }, nil
+	case "long.time-micros":
+		return reflect.TypeOf(int64(0)), tagItems(prefix, "type", "TIME_MICROS"),
+			func(x interface{}) reflect.Value { return reflect.ValueOf(int64(x.(time.Duration) / time.Microsecond)) }, nil
+	case "long.timestamp-millis":
+		return reflect.TypeOf(int64(0)), tagItems(prefix, "type", "TIMESTAMP_MILLIS"),
+			func(x interface{}) reflect.Value {
+				return reflect.ValueOf(ToTimestamp(x.(time.Time), "TIMESTAMP_MILLIS"))
+			}, nil
+	case "long.timestamp-micros":
+		return reflect.TypeOf(int64(0)), tagItems(prefix, "type", "TIMESTAMP_MICROS"),
+			func(x interface{}) reflect.Value {
+				return reflect.ValueOf(ToTimestamp(x.(time.Time), "TIMESTAMP_MICROS"))
+			}, nil
+	case "bytes.decimal", "fixed.decimal":
+		scale := getInt(schema, "scale")
+		size := getInt(schema, "size")
+		items := []string{"type", "DECIMAL", "basetype", "BYTE_ARRAY"}
+		if avroType == "fixed" {
+			items = []string{"type", "DECIMAL", "basetype", "FIXED_LEN_BYTE_ARRAY", "length", fmt.Sprint(size)}
+		}
+		items = append(items, "scale", fmt.Sprint(scale), "precision", fmt.Sprint(getInt(schema, "precision")))
+		return reflect.TypeOf(string("")), tagItems(prefix, items...),
+			func(x interface{}) reflect.Value { return reflect.ValueOf(decimalBytes(x.(*big.Rat), scale, size)) }, nil
+	}
+
+	switch avroType {
+	case "boolean":
+		return reflect.TypeOf(false), tagItems(prefix, "type", "BOOLEAN"), identity, nil
+	case "int":
+		return reflect.TypeOf(int32(0)), tagItems(prefix, "type", "INT32"), identity, nil
+	case "long":
+		return reflect.TypeOf(int64(0)), tagItems(prefix, "type", "INT64"), identity, nil
+	case "float":
+		return reflect.TypeOf(float32(0)), tagItems(prefix, "type", "FLOAT"), identity, nil
+	case "double":
+		return reflect.TypeOf(float64(0)), tagItems(prefix, "type", "DOUBLE"), identity, nil
+	case "string", "enum":
+		return reflect.TypeOf(string("")), tagItems(prefix, "type", "UTF8"), identity, nil
+	case "bytes":
+		return reflect.TypeOf(string("")), tagItems(prefix, "type", "BYTE_ARRAY"),
+			func(x interface{}) reflect.Value { return reflect.ValueOf(string(x.([]byte))) }, nil
+	case "fixed":
+		return reflect.TypeOf(string("")), tagItems(prefix, "type", "FIXED_LEN_BYTE_ARRAY", "length", fmt.Sprint(getInt(schema, "size"))),
+			func(x interface{}) reflect.Value { return reflect.ValueOf(string(x.([]byte))) }, nil
+	}
+
+	return nil, "", nil, fmt.Errorf("unsupported Avro type %v", avroType)
+}
+
+// Map an Avro record to a reflect structure and its converter
+func (names avroNames) parquetRecord(schema map[string]interface{}, namespace string) (reflect.Type, fromAvro, error) {
+	namespace = names.register(schema, namespace)
+
+	fields, _ := schema["fields"].([]interface{})
+	structFields := make([]reflect.StructField, len(fields))
+	converters := make([]fromAvro, len(fields))
+	fieldNames := make([]string, len(fields))
+	nullables := make([]bool, len(fields))
+	goNames := make(map[string]bool)
+
+	for i, f := range fields {
+		field := f.(map[string]interface{})
+		fieldNames[i], _ = field["name"].(string)
+
+		nullable, inner, err := unwrapNull(field["type"])
+		if err != nil {
+			return nil, nil, err
+		}
+		nullables[i] = nullable
+
+		fieldType, tag, conv, err := names.parquetSchema(inner, namespace, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		converters[i] = conv
+		if tag != "" {
+			tag = ", " + tag
+		}
+
+		// Avro unions with null become OPTIONAL parquet columns stored as pointers
+		repetitionType := "REQUIRED"
+		if nullable {
+			repetitionType = "OPTIONAL"
+			fieldType = reflect.PtrTo(fieldType)
+		}
+
+		goName := FieldName(fieldNames[i], i, goNames)
+		structFields[i] = reflect.StructField{
+			Name: goName,
+			Type: fieldType,
+			Tag:  reflect.StructTag(fmt.Sprintf(`parquet:"name=%v%v, repetitiontype=%v"`, fieldNames[i], tag, repetitionType)),
+		}
+		Debug("  %v: %v", fieldNames[i], structFields[i].Tag)
+	}
+
+	structType := reflect.StructOf(structFields)
+
+	return structType, func(x interface{}) reflect.Value {
+		record := x.(map[string]interface{})
+		v := reflect.New(structType).Elem()
+		for i := range structFields {
+			value := record[fieldNames[i]]
+			if nullables[i] {
+				if value = unwrapUnion(value); value == nil {
+					continue
+				}
+				p := reflect.New(structFields[i].Type.Elem())
+				p.Elem().Set(converters[i](value))
+				v.Field(i).Set(p)
+			} else {
+				v.Field(i).Set(converters[i](value))
+			}
+		}
+		return v
+	}, nil
+}
+
+// Map an Avro schema to
+// * Type reflection
+// * Parquet tag (without name and repetition type)
+// * Converter from Avro
+func (names avroNames) parquetSchema(schema interface{}, namespace string, prefix string) (reflect.Type, string, fromAvro, error) {
+
+	switch s := schema.(type) {
+	case string:
+		if def := names.lookup(s, namespace); def != nil {
+			return names.parquetSchema(def, namespace, prefix)
+		}
+		return parquetPrimitive(s, map[string]interface{}{}, prefix)
+
+	case []interface{}:
+		return nil, "", nil, fmt.Errorf("Avro unions are only supported as record fields: %v", schema)
+
+	case map[string]interface{}:
+		avroType, ok := s["type"].(string)
+		if !ok {
+			return names.parquetSchema(s["type"], namespace, prefix)
+		}
+
+		switch avroType {
+		case "record":
+			structType, conv, err := names.parquetRecord(s, namespace)
+			return structType, "", conv, err
+
+		case "enum", "fixed":
+			names.register(s, namespace)
+			return parquetPrimitive(avroType, s, prefix)
+
+		case "array", "map":
+			if prefix != "" {
+				return nil, "", nil, fmt.Errorf("nested Avro %v in a list or map is not supported", avroType)
+			}
+			var items interface{}
+			if avroType == "array" {
+				items = s["items"]
+			} else {
+				items = s["values"]
+			}
+			if nullable, _, _ := unwrapNull(items); nullable {
+				return nil, "", nil, fmt.Errorf("nullable Avro %v items are not supported", avroType)
+			}
+			itemType, itemTag, itemConv, err := names.parquetSchema(items, namespace, "value")
+			if err != nil {
+				return nil, "", nil, err
+			}
+			if itemTag != "" {
+				itemTag = ", " + itemTag
+			}
+
+			if avroType == "array" {
+				sliceType := reflect.SliceOf(itemType)
+				return sliceType, "type=LIST" + itemTag, func(x interface{}) reflect.Value {
+					items := x.([]interface{})
+					v := reflect.MakeSlice(sliceType, len(items), len(items))
+					for i, item := range items {
+						v.Index(i).Set(itemConv(item))
+					}
+					return v
+				}, nil
+			}
+
+			mapType := reflect.MapOf(reflect.TypeOf(string("")), itemType)
+			return mapType, "type=MAP, keytype=UTF8" + itemTag, func(x interface{}) reflect.Value {
+				v := reflect.MakeMap(mapType)
+				for key, item := range x.(map[string]interface{}) {
+					v.SetMapIndex(reflect.ValueOf(key), itemConv(item))
+				}
+				return v
+			}, nil
+
+		default:
+			return parquetPrimitive(avroType, s, prefix)
+		}
+	}
+
+	return nil, "", nil, fmt.Errorf("invalid Avro schema %v", schema)
+}
+
+// Read an Avro object container file and convert it to parquet, keeping the Avro schema in the metadata
+func AvroToParquet(avro_filename string, parquet_filename string) error {
+
+	// Open Avro File
+	Debug("Open Avro File")
+	f, err := os.Open(avro_filename)
+	if err != nil {
+		return fmt.Errorf("can't open Avro file '%v': %v", avro_filename, err)
+	}
+	defer f.Close()
+
+	ocfr, err := goavro.NewOCFReader(bufio.NewReader(f))
+	if err != nil {
+		return fmt.Errorf("can't read Avro file '%v': %v", avro_filename, err)
+	}
+
+	// Map Avro schema to a reflect structure
+	avroSchema := ocfr.Codec().Schema()
+	Debug("Avro schema: %v", avroSchema)
+	var schema interface{}
+	if err := json.Unmarshal([]byte(avroSchema), &schema); err != nil {
+		return fmt.Errorf("invalid Avro schema: %v", err)
+	}
+	if s, ok := schema.(map[string]interface{}); !ok || s["type"] != "record" {
+		return fmt.Errorf("Avro schema must be a record")
+	}
+	dataType, _, conv, err := avroNames{}.parquetSchema(schema, "", "")
+	if err != nil {
+		return err
+	}
+
+	logf("Structure:")
+	for i := 0; i < dataType.NumField(); i++ {
+		logf("  %v", dataType.Field(i).Tag.Get("parquet"))
+	}
+
+	pw, err := CreateWriter(parquet_filename, dataType)
+	if err != nil {
+		return err
+	}
+
+	// Keep the Avro schema to restore enums, names and namespaces in ParquetToAvro
+	pw.SetMetadata(AvroSchemaKey, avroSchema)
+
+	// Loop through each Avro record
+	nRows := 0
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		if err != nil {
+			pw.Close()
+			return fmt.Errorf("reading Avro file '%v': %v", avro_filename, err)
+		}
+		nRows++
+
+		v := conv(datum)
+		Debug("Writing:%v", v)
+		if err = pw.Write(v); err != nil {
+			pw.Close()
+			return err
+		}
+	}
+	if err := ocfr.Err(); err != nil {
+		pw.Close()
+		return fmt.Errorf("reading Avro file '%v': %v", avro_filename, err)
+	}
+
+	if err = pw.Close(); err != nil {
+		return err
+	}
+
+	logf("Parquet file %v written with %v rows and %v fields", parquet_filename, nRows, dataType.NumField())
+	return nil
+}
+
+// Return the name goavro uses for a member of a union
+func (names avroNames) unionName(schema interface{}, namespace string) string {
+	switch s := schema.(type) {
+	case string:
+		if _, ok := names[fullName(s, namespace)]; ok {
+			return fullName(s, namespace)
+		}
+		return s
+	case map[string]interface{}:
+		avroType, ok := s["type"].(string)
+		if !ok {
+			return names.unionName(s["type"], namespace)
+		}
+		logicalType, _ := s["logicalType"].(string)
+		switch avroType {
+		case "record", "enum", "fixed":
+			name, _ := s["name"].(string)
+			if name == "" && logicalType == "decimal" {
+				return "fixed.decimal"
+			}
+			if ns, ok := s["namespace"].(string); ok {
+				namespace = ns
+			}
+			return fullName(name, namespace)
+		case "array", "map":
+			return avroType
+		}
+		switch logicalType {
+		case "decimal":
+			return "bytes.decimal"
+		case "date", "time-millis", "time-micros", "timestamp-millis", "timestamp-micros":
+			return avroType + "." + logicalType
+		}
+		return avroType
+	}
+	return ""
+}
+
+// Build the Avro schema of a parquet schema
+func DeriveAvroSchema(sh *schema.SchemaHandler) (string, error) {
+	root, _, err := deriveSchema(sh, 0, "")
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(root["type"])
+	if err != nil {
+		return "", fmt.Errorf("can't derive Avro schema: %v", err)
+	}
+	return string(b), nil
+}
+
+// Build the Avro field of the parquet schema element at index pos, returning it with the index of the next sibling
+func deriveSchema(sh *schema.SchemaHandler, pos int, recordName string) (map[string]interface{}, int, error) {
+	se := sh.SchemaElements[pos]
+	name := sh.Infos[pos].ExName
+	next := pos + 1
+
+	// Avro names must be unique, nested types are named after their path
+	typeName := name
+	if recordName != "" {
+		typeName = recordName + "_" + name
+	}
+
+	var avroType interface{}
+	if se.GetNumChildren() == 0 {
+		field_type := se.GetType()
+		convertedType := parquet.ConvertedType(-1)
+		if se.IsSetConvertedType() {
+			convertedType = se.GetConvertedType()
+		}
+		switch {
+		case convertedType == parquet.ConvertedType_DECIMAL:
+			avroType = map[string]interface{}{"type": "bytes", "logicalType": "decimal",
+				"precision": se.GetPrecision(), "scale": se.GetScale()}
+		case convertedType == parquet.ConvertedType_DATE:
+			avroType = map[string]interface{}{"type": "int", "logicalType": "date"}
+		case convertedType == parquet.ConvertedType_TIME_MILLIS:
+			avroType = map[string]interface{}{"type": "int", "logicalType": "time-millis"}
+		case convertedType == parquet.ConvertedType_TIME_MICROS:
+			avroType = map[string]interface{}{"type": "long", "logicalType": "time-micros"}
+		case convertedType == parquet.ConvertedType_TIMESTAMP_MILLIS:
+			avroType = map[string]interface{}{"type": "long", "logicalType": "timestamp-millis"}
+		case convertedType == parquet.ConvertedType_TIMESTAMP_MICROS:
+			avroType = map[string]interface{}{"type": "long", "logicalType": "timestamp-micros"}
+		case convertedType == parquet.ConvertedType_UTF8 || convertedType == parquet.ConvertedType_ENUM ||
+			convertedType == parquet.ConvertedType_JSON:
+			avroType = "string"
+		case convertedType == parquet.ConvertedType_UINT_32 || convertedType == parquet.ConvertedType_INT_64 ||
+			convertedType == parquet.ConvertedType_UINT_64:
+			avroType = "long"
+		case field_type == parquet.Type_BOOLEAN:
+			avroType = "boolean"
+		case field_type == parquet.Type_INT32:
+			avroType = "int"
+		case field_type == parquet.Type_INT64:
+			avroType = "long"
+		case field_type == parquet.Type_FLOAT:
+			avroType = "float"
+		case field_type == parquet.Type_DOUBLE:
+			avroType = "double"
+		case field_type == parquet.Type_BYTE_ARRAY:
+			avroType = "bytes"
+		case field_type == parquet.Type_FIXED_LEN_BYTE_ARRAY:
+			avroType = map[string]interface{}{"type": "fixed", "name": typeName, "size": se.GetTypeLength()}
+		default:
+			return nil, 0, fmt.Errorf("invalid type for field %v: %v", name, field_type)
+		}
+
+	} else if se.IsSetConvertedType() && se.GetConvertedType() == parquet.ConvertedType_LIST {
+		// LIST: optional or required group, repeated group "list", element
+		item, _, err := deriveSchema(sh, pos+2, typeName)
+		if err != nil {
+			return nil, 0, err
+		}
+		if nullable, _, _ := unwrapNull(item["type"]); nullable {
+			return nil, 0, fmt.Errorf("nullable list items in field %v are not supported", name)
+		}
+		avroType = map[string]interface{}{"type": "array", "items": item["type"]}
+		next = skipSchema(sh, pos)
+
+	} else if se.IsSetConvertedType() && (se.GetConvertedType() == parquet.ConvertedType_MAP ||
+		se.GetConvertedType() == parquet.ConvertedType_MAP_KEY_VALUE) {
+		// MAP: optional or required group, repeated group "key_value", key and value
+		key, valuePos, err := deriveSchema(sh, pos+2, typeName)
+		if err != nil {
+			return nil, 0, err
+		}
+		if key["type"] != "string" && key["type"] != "bytes" {
+			return nil, 0, fmt.Errorf("map keys in field %v must be strings", name)
+		}
+		value, _, err := deriveSchema(sh, valuePos, typeName)
+		if err != nil {
+			return nil, 0, err
+		}
+		if nullable, _, _ := unwrapNull(value["type"]); nullable {
+			return nil, 0, fmt.Errorf("nullable map values in field %v are not supported", name)
+		}
+		avroType = map[string]interface{}{"type": "map", "values": value["type"]}
+		next = skipSchema(sh, pos)
+
+	} else {
+		// Group: nested record
+		fields := make([]interface{}, 0)
+		child := pos + 1
+		for i := 0; i < int(se.GetNumChildren()); i++ {
+			var field map[string]interface{}
+			var err error
+			if field, child, err = deriveSchema(sh, child, typeName); err != nil {
+				return nil, 0, err
+			}
+			fields = append(fields, field)
+		}
+		avroType = map[string]interface{}{"type": "record", "name": typeName, "fields": fields}
+		next = child
+	}
+
+	switch se.GetRepetitionType() {
+	case parquet.FieldRepetitionType_OPTIONAL:
+		return map[string]interface{}{"name": name, "type": []interface{}{"null", avroType}, "default": nil}, next, nil
+	case parquet.FieldRepetitionType_REPEATED:
+		if pos > 0 {
+			return map[string]interface{}{"name": name, "type": map[string]interface{}{"type": "array", "items": avroType}}, next, nil
+		}
+	}
+	return map[string]interface{}{"name": name, "type": avroType}, next, nil
+}
+
+// Return the index of the next sibling of the parquet schema element at index pos
+func skipSchema(sh *schema.SchemaHandler, pos int) int {
+	next := pos + 1
+	for i := 0; i < int(sh.SchemaElements[pos].GetNumChildren()); i++ {
+		next = skipSchema(sh, next)
+	}
+	return next
+}
+
+// Map an Avro record to its converter from a parquet struct, matching fields by position
+func (names avroNames) avroRecord(schema map[string]interface{}, namespace string) (toAvro, error) {
+	namespace = names.register(schema, namespace)
+
+	fields, _ := schema["fields"].([]interface{})
+	converters := make([]toAvro, len(fields))
+	fieldNames := make([]string, len(fields))
+	unions := make([]string, len(fields))
+
+	for i, f := range fields {
+		field := f.(map[string]interface{})
+		fieldNames[i], _ = field["name"].(string)
+		nullable, inner, err := unwrapNull(field["type"])
+		if err != nil {
+			return nil, err
+		}
+		if converters[i], err = names.avroSchema(inner, namespace); err != nil {
+			return nil, err
+		}
+		if nullable {
+			unions[i] = names.unionName(inner, namespace)
+		}
+	}
+
+	return func(v reflect.Value) interface{} {
+		v = reflect.Indirect(v)
+		record := make(map[string]interface{}, len(fields))
+		for i := range fields {
+			x := v.Field(i)
+			if unions[i] == "" {
+				record[fieldNames[i]] = converters[i](x)
+				continue
+			}
+			switch x.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Map:
+				if x.IsNil() {
+					record[fieldNames[i]] = nil
+					continue
+				}
+			}
+			record[fieldNames[i]] = goavro.Union(unions[i], converters[i](x))
+		}
+		return record
+	}, nil
+}
+
+// Map an Avro schema to its converter from parquet reflections
+func (names avroNames) avroSchema(schema interface{}, namespace string) (toAvro, error) {
+
+	switch s := schema.(type) {
+	case string:
+		if def := names.lookup(s, namespace); def != nil {
+			return names.avroSchema(def, namespace)
+		}
+		return avroPrimitive(s, map[string]interface{}{})
+
+	case map[string]interface{}:
+		avroType, ok := s["type"].(string)
+		if !ok {
+			return names.avroSchema(s["type"], namespace)
+		}
+
+		switch avroType {
+		case "record":
+			return names.avroRecord(s, namespace)
+
+		case "enum", "fixed":
+			names.register(s, namespace)
+			return avroPrimitive(avroType, s)
+
+		case "array":
+			item, err := names.avroSchema(s["items"], namespace)
+			if err != nil {
+				return nil, err
+			}
+			return func(v reflect.Value) interface{} {
+				v = reflect.Indirect(v)
+				items := make([]interface{}, v.Len())
+				for i := range items {
+					items[i] = item(v.Index(i))
+				}
+				return items
+			}, nil
+
+		case "map":
+			value, err := names.avroSchema(s["values"], namespace)
+			if err != nil {
+				return nil, err
+			}
+			return func(v reflect.Value) interface{} {
+				v = reflect.Indirect(v)
+				values := make(map[string]interface{}, v.Len())
+				iter := v.MapRange()
+				for iter.Next() {
+					values[iter.Key().String()] = value(iter.Value())
+				}
+				return values
+			}, nil
+
+		default:
+			return avroPrimitive(avroType, s)
+		}
+	}
+
+	return nil, fmt.Errorf("Avro unions are only supported as record fields: %v", schema)
+}
+
+// Map a primitive Avro type (with its optional logical type) to its converter
+func avroPrimitive(avroType string, schema map[string]interface{}) (toAvro, error) {
+	logicalType, _ := schema["logicalType"].(string)
+	if logicalType == "decimal" {
+		scale := getInt(schema, "scale")
+		return func(v reflect.Value) interface{} { return toDecimal(reflect.Indirect(v), scale) }, nil
+	}
+
+	switch avroType {
+	case "boolean":
+		return func(v reflect.Value) interface{} { return reflect.Indirect(v).Bool() }, nil
+	case "int":
+		return func(v reflect.Value) interface{} { return int32(toInt(reflect.Indirect(v))) }, nil
+	case "long":
+		return func(v reflect.Value) interface{} { return toInt(reflect.Indirect(v)) }, nil
+	case "float":
+		return func(v reflect.Value) interface{} { return float32(reflect.Indirect(v).Float()) }, nil
+	case "double":
+		return func(v reflect.Value) interface{} { return reflect.Indirect(v).Float() }, nil
+	case "string", "enum":
+		return func(v reflect.Value) interface{} { return reflect.Indirect(v).String() }, nil
+	case "bytes", "fixed":
+		return func(v reflect.Value) interface{} { return []byte(reflect.Indirect(v).String()) }, nil
+	}
+
+	return nil, fmt.Errorf("unsupported Avro type %v", avroType)
+}
+
+// Read a parquet file and convert it to an Avro object container file, using the
+// Avro schema kept by AvroToParquet or one derived from the parquet schema
+func ParquetToAvro(parquet_filename string, avro_filename string, opts AvroOptions) error {
+	if opts.Compression == "" {
+		opts.Compression = goavro.CompressionSnappyLabel
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+
+	Debug("Creating NewLocalFileReader")
+	fr, err := local.NewLocalFileReader(parquet_filename)
+	if err != nil {
+		return fmt.Errorf("can't open parquet file '%v': %v", parquet_filename, err)
+	}
+	defer fr.Close()
+
+	pr, err := reader.NewParquetReader(fr, nil, 4)
+	if err != nil {
+		return fmt.Errorf("can't create parquet reader: %v", err)
+	}
+	defer pr.ReadStop()
+
+	// Use the Avro schema kept by AvroToParquet, or derive one from the parquet schema
+	avroSchema := ""
+	for _, kv := range pr.Footer.KeyValueMetadata {
+		if kv.Key == AvroSchemaKey && kv.Value != nil {
+			Debug("Using Avro schema from parquet metadata")
+			avroSchema = *kv.Value
+		}
+	}
+	if avroSchema == "" {
+		if avroSchema, err = DeriveAvroSchema(pr.SchemaHandler); err != nil {
+			return err
+		}
+	}
+	logf("Avro schema:\n  %v", avroSchema)
+
+	var schema interface{}
+	if err := json.Unmarshal([]byte(avroSchema), &schema); err != nil {
+		return fmt.Errorf("invalid Avro schema: %v", err)
+	}
+	conv, err := avroNames{}.avroSchema(schema, "")
+	if err != nil {
+		return err
+	}
+
+	// Create Avro object container file
+	Debug("Creating Avro file %v", avro_filename)
+	f, err := os.Create(avro_filename)
+	if err != nil {
+		return fmt.Errorf("can't create Avro file '%v': %v", avro_filename, err)
+	}
+	defer f.Close()
+
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               f,
+		Schema:          avroSchema,
+		CompressionName: opts.Compression,
+	})
+	if err != nil {
+		return fmt.Errorf("can't create Avro writer: %v", err)
+	}
+
+	numRows := int(pr.GetNumRows())
+	nRows := numRows
+	for numRows > 0 {
+		rowCount := opts.BatchSize
+		if numRows < rowCount {
+			rowCount = numRows
+		}
+		numRows -= rowCount
+
+		rows, err := pr.ReadByNumber(rowCount)
+		if err != nil {
+			return fmt.Errorf("reading parquet file '%v': %v", parquet_filename, err)
+		}
+
+		data := make([]interface{}, len(rows))
+		for i, row := range rows {
+			data[i] = conv(reflect.ValueOf(row))
+		}
+		Debug("Writing %v rows", len(data))
+		if err = ocfw.Append(data); err != nil {
+			return fmt.Errorf("writing to Avro file: %v", err)
+		}
+	}
+
+	logf("Avro file %v written with %v rows", avro_filename, nRows)
+	return nil
+}
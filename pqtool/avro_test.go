@@ -0,0 +1,12 @@
+package pqtool
+
+import (
+	"testing"
+)
+
+// Files converted to Avro and back have the same values
+func TestAvroRoundTrip(t *testing.T) {
+	checkRoundTrip(t, "avro", len(roundTripFields),
+		func(in, out string) error { return ParquetToAvro(in, out, AvroOptions{BatchSize: 7}) },
+		AvroToParquet)
+}
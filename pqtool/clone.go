@@ -0,0 +1,135 @@
+package pqtool
+
+import (
+	"math"
+	"time"
+)
+
+// Maximum number of distinct values counted in a column, more values are considered unique
+const maxDistinct = 1 << 16
+
+// Maximum range of string lengths with a length histogram, longer ranges have uniform lengths
+const maxLengths = 1000
+
+// Options of the profiles of cloned files: distinct values up to maxDistinct,
+// a histogram bin per string length up to maxLengths, and no quantiles
+var cloneProfileOptions = ProfileOptions{TopK: 1, Bins: maxLengths + 1, MaxValues: maxDistinct, SampleSize: 1}
+
+// Return the number of distinct values to simulate, 0 if values are mostly unique
+func (c *columnProfiler) cardinality(count int64) int {
+	if c.overflow || int64(len(c.counts)) > count/2 {
+		return 0
+	}
+	return len(c.counts)
+}
+
+// Return the step of the sequence of strictly increasing integers, dates or
+// timestamps, 0 if values aren't increasing
+func (c *columnProfiler) step() float64 {
+	if !c.increasing || c.seen < 2 {
+		return 0
+	}
+	if isUnsigned(c.min.Kind()) {
+		return float64(c.max.Uint()-c.min.Uint()) / float64(c.seen-1)
+	}
+	return float64(uint64(c.max.Int()-c.min.Int())) / float64(c.seen-1)
+}
+
+// Return the column simulating the values of a profiled column of a file of rows rows
+func (c *columnProfiler) column(rows int64) Column {
+	f := c.field
+	col := Column{Field: f}
+	if f.Type == "UTF8" {
+		col.Encoding = "PLAIN_DICTIONARY"
+	}
+	nulls := c.profile.Nulls
+	if rows > 0 {
+		col.NullRatio = float64(nulls) / float64(rows)
+	}
+	if !c.min.IsValid() {
+		// No values (only nulls, NaN or an empty file): any value will do
+		return col
+	}
+	col.Cardinality = c.cardinality(rows - nulls)
+
+	switch f.Type {
+	case "BOOLEAN":
+		col.Generator = "enum"
+		col.Values = []string{"true", "false"}
+		col.Weights = []float64{float64(c.counts["true"]), float64(c.counts["false"])}
+		col.Cardinality = 0
+
+	case "INT32", "INT64", "INT_8", "INT_16", "INT_32", "INT_64", "UINT_8", "UINT_16", "UINT_32", "UINT_64", "DECIMAL":
+		// Exact bounds, unscaled values of decimals
+		if isUnsigned(c.min.Kind()) {
+			col.Min, col.Max = UintNumber(c.min.Uint()), UintNumber(c.max.Uint())
+		} else {
+			col.Min, col.Max = IntNumber(c.min.Int()), IntNumber(c.max.Int())
+		}
+		if step := math.Round(c.step()); step >= 1 {
+			col.Generator, col.Step, col.Max = "sequence", step, nil
+		}
+
+	case "FLOAT", "DOUBLE":
+		col.Generator = "normal"
+		col.Min, col.Max = FloatNumber(c.min.Float()), FloatNumber(c.max.Float())
+		col.Mean = c.mean
+		col.StdDev = math.Sqrt(c.m2 / float64(c.n))
+		if col.StdDev == 0 {
+			col.Generator = "uniform"
+		}
+
+	case "UTF8", "BYTE_ARRAY":
+		col.MinLength, col.MaxLength = c.profile.Lengths.Min, c.profile.Lengths.Max
+		if col.MaxLength == 0 {
+			col.Generator = "enum"
+			col.Values = []string{""}
+			col.Cardinality = 0
+		} else if c.lengthWidth == 1 {
+			// Bins of one length each
+			col.LengthWeights = make([]float64, len(c.lengthBins))
+			for i, n := range c.lengthBins {
+				col.LengthWeights[i] = float64(n)
+			}
+		}
+
+	case "DATE", "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS":
+		// Dates are in days, timestamps in milliseconds or microseconds, and
+		// steps of sequences in days for dates and in seconds for timestamps
+		var from, to time.Time
+		step := c.step()
+		if f.Type == "DATE" {
+			from, to = FromDate(int32(c.min.Int())), FromDate(int32(c.max.Int())).AddDate(0, 0, 1)
+		} else {
+			from, to = FromTimestamp(c.min.Int(), f.Type), FromTimestamp(c.max.Int(), f.Type).Add(time.Second)
+			step *= float64(FromTimestamp(1, f.Type).Sub(time.Unix(0, 0))) / float64(time.Second)
+		}
+		col.From, col.To = from.Format("2006-01-02T15:04:05.999999999"), to.Format("2006-01-02T15:04:05.999999999")
+		if step > 0 {
+			col.Generator, col.Step, col.To = "sequence", step, ""
+		}
+	}
+	return col
+}
+
+// Read a flat parquet file and return a spec simulating rows with the same
+// schema and a similar profile, gathered as by Profile: null ratios, ranges,
+// distributions of numbers, number of distinct values and lengths of strings.
+// No value of the file is copied, except the true/false ratio of booleans
+func CloneSpec(filename string) (*Spec, error) {
+	columns, rows, err := profileColumns(filename, cloneProfileOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &Spec{
+		Rows:    int(rows),
+		Columns: make([]Column, len(columns)),
+	}
+	for j, c := range columns {
+		spec.Columns[j] = c.column(rows)
+		Debug("Column %v: %v nulls, min %v, max %v, cardinality %v", c.field.Name,
+			c.profile.Nulls, c.min, c.max, spec.Columns[j].Cardinality)
+	}
+	return spec, nil
+}
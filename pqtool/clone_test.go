@@ -0,0 +1,130 @@
+package pqtool
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Integer bounds of cloned columns are saved as exact integers, and decimals
+// keep their precision and scale
+func TestCloneSpecIntegerBounds(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "input.parquet")
+	fields := []Field{
+		{Name: "i64", Type: "INT64"}, {Name: "u64", Type: "UINT_64"}, {Name: "f", Type: "DOUBLE"},
+		{Name: "price", Type: "DECIMAL", Precision: 18, Scale: 2},
+	}
+	writeRows(t, filename, fields, [][]interface{}{
+		{int64(9007199254740993), uint64(18446744073709551613), 0.5, int64(-9007199254740995)},
+		{int64(9007199254740995), uint64(3), 2.0, int64(123)},
+		{int64(9007199254740995), uint64(3), 2.0, int64(123)},
+	})
+
+	spec, err := CloneSpec(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"spec.yaml", "spec.json"} {
+		specFile := filepath.Join(dir, name)
+		if err = SaveSpec(specFile, spec); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(specFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, bound := range []string{"9007199254740993", "9007199254740995", "18446744073709551613", "0.5", "-9007199254740995"} {
+			if !strings.Contains(string(data), bound) {
+				t.Errorf("%v without bound %v:\n%s", name, bound, data)
+			}
+		}
+
+		loaded, err := LoadSpec(specFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := loaded.Columns[3]; c.Type != "DECIMAL" || c.Precision != 18 || c.Scale != 2 {
+			t.Errorf("%v: decimal cloned as %v", name, c.TypeName())
+		}
+		for j, c := range loaded.Columns {
+			if *c.Min != *spec.Columns[j].Min || *c.Max != *spec.Columns[j].Max {
+				t.Errorf("%v: bounds of %v [%v, %v], expected [%v, %v]", name, c.Name, *c.Min, *c.Max, *spec.Columns[j].Min, *spec.Columns[j].Max)
+			}
+		}
+	}
+}
+
+// Files simulated from a cloned spec have the bounds and decimals of the source file
+func TestCloneSimulate(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "input.parquet"), filepath.Join(dir, "output.parquet")
+	fields := []Field{{Name: "i64", Type: "INT64"}, {Name: "price", Type: "DECIMAL", Precision: 10, Scale: 2}}
+	writeRows(t, input, fields, [][]interface{}{
+		{int64(9007199254740993), int64(-1050)},
+		{int64(9007199254740995), int64(99999)},
+		{int64(9007199254740994), int64(1)},
+	})
+	spec, err := CloneSpec(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := int64(1)
+	if err = Simulate(output, 100, spec.Columns, SimulateOptions{Seed: &seed}); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := schemaElements(t, output), schemaElements(t, input); !reflect.DeepEqual(a, b) {
+		t.Errorf("schema %v, expected %v", a, b)
+	}
+	for i, row := range storedRows(t, output) {
+		values := row.([]interface{})
+		if x := values[0].(int64); x < 9007199254740993 || x > 9007199254740995 {
+			t.Errorf("integer %v out of the bounds at row %v", x, i)
+		}
+		if x := values[1].(int64); x < -1050 || x > 99999 {
+			t.Errorf("unscaled decimal %v out of the bounds at row %v", x, i)
+		}
+	}
+}
+
+// Cloned columns have the null ratios, sequences, string lengths and boolean
+// ratios of the profile of the file
+func TestCloneSpecProfile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "input.parquet")
+	fields := []Field{
+		{Name: "id", Type: "INT64"}, {Name: "s", Type: "UTF8", Optional: true},
+		{Name: "b", Type: "BOOLEAN"}, {Name: "day", Type: "DATE"},
+	}
+	rows := make([][]interface{}, 100)
+	for i := range rows {
+		var s interface{}
+		if i%4 != 0 {
+			s = strings.Repeat("x", 3+i%3)
+		}
+		rows[i] = []interface{}{int64(10 + 5*i), s, i%10 == 0, int32(18000 + i%7)}
+	}
+	writeRows(t, filename, fields, rows)
+
+	spec, err := CloneSpec(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Rows != 100 {
+		t.Errorf("%v rows, expected 100", spec.Rows)
+	}
+	if c := spec.Columns[0]; c.Generator != "sequence" || c.Step != 5 || c.Min.String() != "10" {
+		t.Errorf("id cloned as %v from %v by %v, expected a sequence from 10 by 5", c.Generator, c.Min, c.Step)
+	}
+	if c := spec.Columns[1]; c.NullRatio != 0.25 || c.MinLength != 3 || c.MaxLength != 5 || !reflect.DeepEqual(c.LengthWeights, []float64{25, 25, 25}) || c.Cardinality != 3 {
+		t.Errorf("s cloned with null ratio %v, lengths %v to %v, weights %v and cardinality %v", c.NullRatio, c.MinLength, c.MaxLength, c.LengthWeights, c.Cardinality)
+	}
+	if c := spec.Columns[2]; !reflect.DeepEqual(c.Weights, []float64{10, 90}) {
+		t.Errorf("b cloned with weights %v, expected [10 90]", c.Weights)
+	}
+	if c := spec.Columns[3]; c.Generator != "" || c.Cardinality != 7 || c.From != "2019-04-14T00:00:00" || c.To != "2019-04-21T00:00:00" {
+		t.Errorf("day cloned as %v from %v to %v with cardinality %v", c.Generator, c.From, c.To, c.Cardinality)
+	}
+}
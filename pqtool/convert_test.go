@@ -0,0 +1,100 @@
+package pqtool
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Columns of the round trips through other formats, the decimal last as it
+// doesn't come back from every format
+var roundTripFields = []Field{
+	{Name: "id", Type: "INT64"},
+	{Name: "i32", Type: "INT32"},
+	{Name: "i8", Type: "INT_8"},
+	{Name: "u32", Type: "UINT_32"},
+	{Name: "f", Type: "FLOAT"},
+	{Name: "d", Type: "DOUBLE", Optional: true},
+	{Name: "b", Type: "BOOLEAN"},
+	{Name: "s", Type: "UTF8", Optional: true},
+	{Name: "day", Type: "DATE"},
+	{Name: "ts", Type: "TIMESTAMP_MILLIS"},
+	{Name: "price", Type: "DECIMAL", Precision: 10, Scale: 2},
+}
+
+// Write a file of the first nFields roundTripFields with n rows, and return its rows as text
+func writeRoundTripFile(t *testing.T, filename string, nFields int, n int) []string {
+	t.Helper()
+	day := time.Date(2021, 3, 17, 0, 0, 0, 0, time.UTC)
+	rows := make([][]interface{}, n)
+	for i := range rows {
+		var d, s interface{}
+		if i%4 != 1 {
+			d, s = float64(i)/8-3, string(rune('a'+i%26))+"é"
+		}
+		rows[i] = []interface{}{
+			int64(i) - 1<<40, int32(-i * 1000), int8(i%200 - 100), uint32(4000000000 + i), float32(i) / 4, d, i%3 == 0, s,
+			ToDate(day.AddDate(0, 0, i)), ToTimestamp(day.Add(time.Duration(i)*time.Minute), "TIMESTAMP_MILLIS"), int64(i*101 - 5000),
+		}[:nFields]
+	}
+	writeRows(t, filename, roundTripFields[:nFields], rows)
+	return rowsText(t, filename)
+}
+
+// Return the rows of a parquet file as text, whatever the physical types of
+// its columns (e.g. decimals stored as integers or bytes)
+func rowsText(t *testing.T, filename string) []string {
+	t.Helper()
+	pr, err := openStoredReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	var lines []string
+	for {
+		slice, err := pr.Read(100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slice.Len() == 0 {
+			return lines
+		}
+		for i := 0; i < slice.Len(); i++ {
+			values := make([]string, len(pr.Fields))
+			for j, f := range pr.Fields {
+				if v := reflect.Indirect(slice.Index(i).Field(j)); v.IsValid() {
+					values[j] = f.Name + "=" + formatValue(v, f)
+				}
+			}
+			lines = append(lines, strings.Join(values, ","))
+		}
+	}
+}
+
+// Check that a file of the first nFields roundTripFields converted to another
+// format (with the extension of the converted file) and back has the same values
+func checkRoundTrip(t *testing.T, extension string, nFields int, to func(string, string) error, from func(string, string) error) {
+	t.Helper()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.parquet")
+	expected := writeRoundTripFile(t, input, nFields, 30)
+	converted := filepath.Join(dir, "input."+extension)
+	if err := to(input, converted); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "output.parquet")
+	if err := from(converted, output); err != nil {
+		t.Fatal(err)
+	}
+	rows := rowsText(t, output)
+	if len(rows) != len(expected) {
+		t.Fatalf("%v rows after the round trip, expected %v", len(rows), len(expected))
+	}
+	for i := range rows {
+		if rows[i] != expected[i] {
+			t.Errorf("row %v after the round trip: %v, expected %v", i, rows[i], expected[i])
+		}
+	}
+}
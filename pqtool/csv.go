@@ -0,0 +1,501 @@
+package pqtool
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVOptions are the options to convert a CSV file to parquet
+type CSVOptions struct {
+	Delimiter string        // Field delimiter, comma if empty
+	Sort      SortOptions   // Order of the rows, the order of the CSV file if no keys
+	Check     *CheckOptions // Data quality rules of the rows, empty values being nulls, unchecked if nil
+}
+
+// ExportCSVOptions are the options to convert a parquet file to CSV
+type ExportCSVOptions struct {
+	Header      bool   // Write a header line with the column names
+	PgFormat    string // PostgreSQL COPY format, text or csv, plain CSV if empty
+	Table       string // PostgreSQL table name, parquet file name if empty
+	SQLFilename string // PostgreSQL load script, CSV file name with .sql extension if empty
+}
+
+// Time layouts recognized in CSV files, with the parquet type they map to
+var csvLayouts = []struct {
+	layout      string
+	parquetType string
+}{
+	{time.RFC3339, "TIMESTAMP_MILLIS"},
+	{"2006-01-02 15:04:05", "TIMESTAMP_MILLIS"},
+	{"2006-01-02", "DATE"},
+	{"2006/01/02", "DATE"},
+}
+
+// Assess a unkown data element and return
+// * Parquet datatype
+// * time layout
+func assess(data string) (string, string) {
+
+	if _, err := strconv.Atoi(data); err == nil {
+		return "INT64", ""
+	}
+
+	if _, err := strconv.ParseFloat(data, 64); err == nil {
+		return "DOUBLE", ""
+	}
+
+	for _, l := range csvLayouts {
+		if _, err := time.Parse(l.layout, data); err == nil {
+			return l.parquetType, l.layout
+		}
+	}
+
+	return "BYTE_ARRAY", ""
+}
+
+// Detect the schema of a CSV file with the header on the 1st row and one line of data on the 2nd row
+func InferCSVSchema(filename string, opts CSVOptions) ([]Field, error) {
+	if opts.Delimiter == "" {
+		opts.Delimiter = ","
+	}
+
+	// Open file "filename"
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open CSV file '%v': %v", filename, err)
+	}
+	defer file.Close()
+
+	// Read the first two lines
+	scanner := bufio.NewScanner(file)
+	lines := make([]string, 0, 2)
+	for len(lines) < 2 && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading CSV file '%v': %v", filename, err)
+	}
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("file empty or too small")
+	}
+
+	// Extract field names from 1st line and data examples from the 2nd line
+	fieldNames := strings.Split(lines[0], opts.Delimiter)
+	data := strings.Split(lines[1], opts.Delimiter)
+	if len(data) != len(fieldNames) {
+		return nil, fmt.Errorf("line 2 has %v fields, expected %v", len(data), len(fieldNames))
+	}
+
+	fields := make([]Field, len(fieldNames))
+	for i, name := range fieldNames {
+		fields[i].Name = name
+		fields[i].Type, fields[i].Layout = assess(data[i])
+	}
+
+	return fields, nil
+}
+
+// Convert a text value into the Go value of a field, ignoring number errors (e.g. non-numbers are zero)
+func parseValue(x string, f Field) interface{} {
+	switch f.Type {
+	case "BOOLEAN":
+		b, _ := strconv.ParseBool(x)
+		return b
+	case "INT32", "INT64", "INT_8", "INT_16", "INT_32", "INT_64":
+		i, _ := strconv.ParseInt(x, 10, 64)
+		return i
+	case "UINT_8", "UINT_16", "UINT_32", "UINT_64":
+		u, _ := strconv.ParseUint(x, 10, 64)
+		return u
+	case "FLOAT", "DOUBLE":
+		f, _ := strconv.ParseFloat(x, 64)
+		return f
+	case "DATE", "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS":
+		t, err := time.Parse(f.Layout, x)
+		if err != nil {
+			logf("Error with date '%v', not following format '%v'", x, f.Layout)
+		}
+		if f.Type == "DATE" {
+			return ToDate(t)
+		}
+		return ToTimestamp(t, f.Type)
+	}
+	return x
+}
+
+// Read a CSV file and convert it to parquet, detecting the schema from its first
+// two lines, and sorting the rows if opts.Sort has keys. With opts.Check, the
+// rows are checked against data quality rules: the failing rows are skipped
+// with the quarantine action, and the parquet file is removed and
+// ErrRulesFailed returned if rules fail with the fail action
+func CSVToParquet(csv_filename string, parquet_filename string, opts CSVOptions) error {
+	if opts.Delimiter == "" {
+		opts.Delimiter = ","
+	}
+
+	fields, err := InferCSVSchema(csv_filename, opts)
+	if err != nil {
+		return err
+	}
+	logf("Structure:")
+	for _, f := range fields {
+		logf("  %v: %v", f.Name, f.Type)
+	}
+
+	pw, err := CreateFieldsWriter(parquet_filename, fields)
+	if err != nil {
+		return err
+	}
+	w, err := sortedWriter(pw, fields, opts.Sort)
+	if err != nil {
+		pw.Close()
+		return err
+	}
+
+	var c *checker
+	if opts.Check != nil {
+		if c, err = newChecker(csv_filename, fields, *opts.Check); err != nil {
+			w.Close()
+			os.Remove(parquet_filename)
+			return err
+		}
+	}
+	// Remove the files on errors
+	abort := func() {
+		w.Close()
+		os.Remove(parquet_filename)
+		if c != nil {
+			c.abort()
+		}
+	}
+
+	// Open CSV File
+	Debug("Open CSV File")
+	csv_file, err := os.Open(csv_filename)
+	if err != nil {
+		abort()
+		return fmt.Errorf("can't open CSV file '%v': %v", csv_filename, err)
+	}
+	defer csv_file.Close()
+
+	// Define file scanner and ignore first line (with headers)
+	scanner := bufio.NewScanner(csv_file)
+	Debug("Ignoring first line")
+	scanner.Scan()
+
+	// Loop throw each row of CSV file
+	nRows, nWritten := 0, 0
+	var values []reflect.Value
+	var invalid []int
+	for scanner.Scan() {
+		nRows++
+
+		// Get line (string)
+		line := scanner.Text()
+		Debug("Read:%v", line)
+
+		// Convert data to Reflect values
+		data := strings.Split(line, opts.Delimiter)
+		if len(data) != len(fields) {
+			abort()
+			return fmt.Errorf("line %v has %v fields, expected %v", nRows+1, len(data), len(fields))
+		}
+		v := pw.NewRow()
+		for i, f := range fields {
+			setField(v.Field(i), parseValue(data[i], f))
+		}
+
+		// Check the values, empty and invalid values being nulls
+		if c != nil {
+			values, invalid = values[:0], invalid[:0]
+			for i, f := range fields {
+				switch {
+				case data[i] == "":
+					values = append(values, reflect.Value{})
+				case !validCSVValue(data[i], f):
+					invalid = append(invalid, i)
+					values = append(values, reflect.Value{})
+				default:
+					values = append(values, v.Field(i))
+				}
+			}
+			if failures := c.check(values, invalid, int64(nRows)); len(failures) > 0 && c.quarantine != nil {
+				if err = c.quarantineRow(values, failures); err != nil {
+					abort()
+					return err
+				}
+				continue
+			}
+		}
+
+		// Add data to parquet file
+		Debug("Writing:%v", v)
+		if err = w.Write(v); err != nil {
+			abort()
+			return err
+		}
+		nWritten++
+	}
+	if err := scanner.Err(); err != nil {
+		abort()
+		return fmt.Errorf("reading CSV file '%v': %v", csv_filename, err)
+	}
+
+	// Stop Parquet Writer pw
+	if err = w.Close(); err != nil {
+		os.Remove(parquet_filename)
+		if c != nil {
+			c.abort()
+		}
+		return err
+	}
+
+	if c != nil {
+		if err = c.finish(Output); err != nil {
+			os.Remove(parquet_filename)
+			c.abort()
+			return err
+		}
+		if !c.result.Passed && c.result.Action == "fail" {
+			os.Remove(parquet_filename)
+			return ErrRulesFailed
+		}
+	}
+
+	logf("Parquet file %v written with %v rows and %v fields", parquet_filename, nWritten, len(fields))
+	return nil
+}
+
+// Return the text of a parquet value of a field, dates and timestamps in UTC
+func formatValue(v reflect.Value, f Field) string {
+	switch f.Type {
+	case "DECIMAL":
+		return toDecimal(v, f.Scale).FloatString(f.Scale)
+	case "DATE":
+		return FromDate(int32(v.Int())).Format("2006-01-02")
+	case "TIMESTAMP_MILLIS":
+		return FromTimestamp(v.Int(), f.Type).Format("2006-01-02 15:04:05.999")
+	case "TIMESTAMP_MICROS":
+		return FromTimestamp(v.Int(), f.Type).Format("2006-01-02 15:04:05.999999")
+	case "UINT_8", "UINT_16", "UINT_32", "UINT_64":
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// Map the parquet type of a field to its PostgreSQL type
+func pgType(parquetType string) string {
+	switch parquetType {
+	case "BOOLEAN":
+		return "boolean"
+	case "INT_8", "INT_16", "UINT_8":
+		return "smallint"
+	case "INT32", "INT_32", "UINT_16":
+		return "integer"
+	case "INT64", "INT_64", "UINT_32":
+		return "bigint"
+	case "UINT_64":
+		return "numeric(20,0)"
+	case "FLOAT":
+		return "real"
+	case "DOUBLE":
+		return "double precision"
+	case "DECIMAL":
+		return "numeric"
+	case "DATE":
+		return "date"
+	case "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS":
+		return "timestamp"
+	}
+	return "text"
+}
+
+// Escape a value for the PostgreSQL COPY text format
+func escapeText(x string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(x)
+}
+
+// Quote a value for the CSV format if needed. An empty string is quoted
+// in PostgreSQL mode to tell it apart from a null value
+func quoteCSV(x string, opts ExportCSVOptions) string {
+	if strings.ContainsAny(x, ",\"\n\r") || (opts.PgFormat == "csv" && (x == "" || x == `\.`)) {
+		return `"` + strings.Replace(x, `"`, `""`, -1) + `"`
+	}
+	return x
+}
+
+// Format a value for the output format
+func (opts ExportCSVOptions) format(x string) string {
+	switch opts.PgFormat {
+	case "text":
+		return escapeText(x)
+	case "csv":
+		return quoteCSV(x, opts)
+	}
+	return x
+}
+
+// Return the null representation of the output format
+func (opts ExportCSVOptions) null() string {
+	if opts.PgFormat == "text" {
+		return `\N`
+	}
+	return ""
+}
+
+// Return the field delimiter of the output format
+func (opts ExportCSVOptions) delimiter() string {
+	if opts.PgFormat == "text" {
+		return "\t"
+	}
+	return ","
+}
+
+// Return the header line of the output format with the names of the fields
+func (opts ExportCSVOptions) header(fields []Field) string {
+	header := ""
+	for i, f := range fields {
+		if i > 0 {
+			header += opts.delimiter()
+		}
+		header += opts.format(f.Name)
+	}
+	return header + "\n"
+}
+
+// Return the line of the output format with the values of a row of flat fields
+func (opts ExportCSVOptions) line(row reflect.Value, fields []Field) string {
+	line := ""
+	for j, f := range fields {
+		if j > 0 {
+			line += opts.delimiter()
+		}
+		field := row.Field(j)
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				line += opts.null()
+				continue
+			}
+			field = field.Elem()
+		}
+		line += opts.format(formatValue(field, f))
+	}
+	return line + "\n"
+}
+
+// Write the SQL script creating the PostgreSQL table and loading the CSV file
+func writeLoadScript(fields []Field, csv_filename string, opts ExportCSVOptions) error {
+
+	columns_list := ""
+	names_list := ""
+	for i, f := range fields {
+		columnType := pgType(f.Type)
+		if !f.Optional {
+			columnType += " NOT NULL"
+		}
+		columns_list = addItem(columns_list, quoteIdentifier(f.Name)+" "+columnType)
+		if i > 0 {
+			names_list += ", "
+		}
+		names_list += quoteIdentifier(f.Name)
+	}
+
+	options := "FORMAT text"
+	if opts.PgFormat == "csv" {
+		options = "FORMAT csv"
+		if opts.Header {
+			options += ", HEADER true"
+		}
+	}
+
+	script := fmt.Sprintf(`CREATE TABLE %v (
+	%v
+);
+
+\copy %v (%v) FROM '%v' WITH (%v)
+`, quoteIdentifier(opts.Table), columns_list, quoteIdentifier(opts.Table), names_list, strings.Replace(csv_filename, "'", "''", -1), options)
+
+	Debug("Writing SQL script %v", opts.SQLFilename)
+	if err := ioutil.WriteFile(opts.SQLFilename, []byte(script), 0644); err != nil {
+		return fmt.Errorf("can't write SQL script %v: %v", opts.SQLFilename, err)
+	}
+	return nil
+}
+
+// Read a parquet file and write its rows to a CSV file, or to PostgreSQL COPY files with a load script
+func ParquetToCSV(parquet_filename string, csv_filename string, opts ExportCSVOptions) error {
+
+	if opts.PgFormat != "" && opts.PgFormat != "text" && opts.PgFormat != "csv" {
+		return fmt.Errorf("PostgreSQL format must be text or csv")
+	}
+	if opts.PgFormat == "text" && opts.Header {
+		return fmt.Errorf("the PostgreSQL text format has no header line")
+	}
+	if opts.Table == "" {
+		opts.Table = strings.TrimSuffix(filepath.Base(parquet_filename), filepath.Ext(parquet_filename))
+	}
+	if opts.SQLFilename == "" {
+		opts.SQLFilename = strings.TrimSuffix(csv_filename, filepath.Ext(csv_filename)) + ".sql"
+	}
+
+	pr, err := OpenReader(parquet_filename)
+	if err != nil {
+		return err
+	}
+	defer pr.Close()
+
+	logf("Structure:")
+	for _, f := range pr.Fields {
+		logf("  %v: %v", f.Name, f.Type)
+	}
+
+	// Create CSV file
+	fcsv, err := os.Create(csv_filename)
+	if err != nil {
+		return fmt.Errorf("can't create CSV file: %v", err)
+	}
+	defer fcsv.Close()
+	w := bufio.NewWriter(fcsv)
+
+	if opts.Header {
+		w.WriteString(opts.header(pr.Fields))
+	}
+
+	if opts.PgFormat != "" {
+		if err = writeLoadScript(pr.Fields, csv_filename, opts); err != nil {
+			return err
+		}
+	}
+
+	// Read Parquet File
+	nRows := 0
+	for {
+		slice, err := pr.Read(100)
+		if err != nil {
+			return err
+		}
+		if slice.Len() == 0 {
+			break
+		}
+		for i := 0; i < slice.Len(); i++ {
+			if _, err := w.WriteString(opts.line(slice.Index(i), pr.Fields)); err != nil {
+				return fmt.Errorf("writing line to CSV file: %v", err)
+			}
+		}
+		nRows += slice.Len()
+	}
+
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("writing CSV file: %v", err)
+	}
+
+	logf("CSV file %v written with %v rows and %v fields", csv_filename, nRows, len(pr.Fields))
+	return nil
+}
@@ -0,0 +1,414 @@
+package pqtool
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// DiffOptions are the options of Diff
+type DiffOptions struct {
+	Keys      []string // Key columns matching the rows of the files, rows matched by position if empty
+	Tolerance float64  // Largest difference of equal floats and decimals
+	Samples   int      // Number of sample differences shown per column
+	Output    string   // Parquet or CSV (.csv extension) file of the differences, none if empty
+	Memory    int64    // Size of the rows sorted by key in memory, 256 MB if 0
+	TempDir   string   // Directory of the temporary files of the sort, the default directory for temporary files if empty
+}
+
+// DiffResult counts the differences of the rows of two files
+type DiffResult struct {
+	Same    int64            // Rows in both files with the same values
+	Changed int64            // Rows in both files with different values
+	Removed int64            // Rows of the first file only
+	Added   int64            // Rows of the second file only
+	Columns map[string]int64 // Number of changed rows of each changed column
+}
+
+// Return whether the files have the same rows
+func (r *DiffResult) Equal() bool {
+	return r.Changed == 0 && r.Removed == 0 && r.Added == 0
+}
+
+// Column of both files compared by a diff
+type diffColumn struct {
+	name     string
+	fields   [2]Field // Fields of the column in the files
+	index    [2]int   // Indexes of the column in the rows of the files
+	text     bool     // Different types, values written as text in the differences
+	samples  []string // Sample differences
+	nChanged int64
+}
+
+// Comparison of the rows of two files
+type differ struct {
+	opts    DiffOptions
+	columns []*diffColumn
+	keys    []*diffColumn
+	result  DiffResult
+	out     *outputFile
+}
+
+// Return whether two values of a column are equal, floats and decimals within a tolerance
+func (c *diffColumn) equal(x reflect.Value, y reflect.Value, tolerance float64) bool {
+	xNull, yNull := x.Kind() == reflect.Ptr && x.IsNil(), y.Kind() == reflect.Ptr && y.IsNil()
+	if xNull || yNull {
+		return xNull && yNull
+	}
+	x, y = reflect.Indirect(x), reflect.Indirect(y)
+
+	a, b := c.fields[0], c.fields[1]
+	if (isFloat(a) || a.Type == "DECIMAL") && (isFloat(b) || b.Type == "DECIMAL") {
+		u, v := diffNumber(x, a), diffNumber(y, b)
+		return u == v || math.Abs(u-v) <= tolerance || (math.IsNaN(u) && math.IsNaN(v))
+	}
+	return formatValue(x, a) == formatValue(y, b)
+}
+
+// Return the float of a float or decimal value
+func diffNumber(v reflect.Value, f Field) float64 {
+	if f.Type == "DECIMAL" {
+		x, _ := toDecimal(v, f.Scale).Float64()
+		return x
+	}
+	return v.Float()
+}
+
+// Return the text of a value of a column, null for nulls
+func diffText(v reflect.Value, f Field) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "null"
+		}
+		v = v.Elem()
+	}
+	return formatValue(v, f)
+}
+
+// Return the columns of both files, the key columns among them, and the names of the columns of only one file
+func diffColumns(fields [2][]Field, keys []string) ([]*diffColumn, []*diffColumn, [2][]string, error) {
+	var (
+		columns []*diffColumn
+		only    [2][]string
+	)
+	index := make(map[string]int)
+	for i, f := range fields[1] {
+		index[f.Name] = i
+	}
+	found := make(map[string]bool)
+	for i, f := range fields[0] {
+		j, ok := index[f.Name]
+		if !ok {
+			only[0] = append(only[0], f.Name)
+			continue
+		}
+		found[f.Name] = true
+		g := fields[1][j]
+		columns = append(columns, &diffColumn{
+			name:   f.Name,
+			fields: [2]Field{f, g},
+			index:  [2]int{i, j},
+			text:   f.TypeName() != g.TypeName(),
+		})
+	}
+	for _, f := range fields[1] {
+		if !found[f.Name] {
+			only[1] = append(only[1], f.Name)
+		}
+	}
+
+	var keyColumns []*diffColumn
+	for _, key := range keys {
+		var c *diffColumn
+		for _, column := range columns {
+			if column.name == key {
+				c = column
+			}
+		}
+		if c == nil {
+			return nil, nil, only, fmt.Errorf("no key column %v in both files", key)
+		}
+		if c.fields[0].TypeName() != c.fields[1].TypeName() {
+			return nil, nil, only, fmt.Errorf("key column %v is %v in the first file but %v in the second file", key, c.fields[0].TypeName(), c.fields[1].TypeName())
+		}
+		keyColumns = append(keyColumns, c)
+	}
+	return columns, keyColumns, only, nil
+}
+
+// Compare the keys of rows of the files
+func (d *differ) compareKeys(row1 reflect.Value, row2 reflect.Value) int {
+	for _, c := range d.keys {
+		if result := compareValues(row1.Field(c.index[0]), row2.Field(c.index[1]), false); result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+// Return the description of a row in samples: its key values, or its position
+func (d *differ) rowName(row reflect.Value, file int, position int64) string {
+	if len(d.keys) == 0 {
+		return fmt.Sprintf("row %v", position)
+	}
+	var items []string
+	for _, c := range d.keys {
+		items = append(items, c.name+"="+diffText(row.Field(c.index[file]), c.fields[file]))
+	}
+	return strings.Join(items, ", ")
+}
+
+// Create the file of the differences: the kind of difference (removed, added,
+// old and new values of changed rows) and the columns of both files, as text
+// if their types differ
+func (d *differ) createOutput() error {
+	fields := []Field{{Name: "_diff", Type: "UTF8"}}
+	for _, c := range d.columns {
+		f := c.fields[0]
+		if c.text {
+			f = Field{Name: c.name, Type: "UTF8"}
+		}
+		f.Optional, f.Encoding = true, ""
+		fields = append(fields, f)
+	}
+	var err error
+	d.out, err = createOutputFile(d.opts.Output, fields)
+	return err
+}
+
+// Write a row of a file to the file of differences
+func (d *differ) writeRow(kind string, row reflect.Value, file int) error {
+	if d.out == nil {
+		return nil
+	}
+	v := reflect.New(d.out.DataType).Elem()
+	setField(v.Field(0), kind)
+	for j, c := range d.columns {
+		x := row.Field(c.index[file])
+		if x.Kind() == reflect.Ptr {
+			if x.IsNil() {
+				continue
+			}
+			x = x.Elem()
+		}
+		if c.text {
+			setField(v.Field(j+1), formatValue(x, c.fields[file]))
+		} else {
+			setField(v.Field(j+1), x)
+		}
+	}
+	return d.out.Write(v)
+}
+
+// Close the file of differences
+func (d *differ) closeOutput() error {
+	if d.out != nil {
+		return d.out.Close()
+	}
+	return nil
+}
+
+// Compare rows of the files with the same key or position
+func (d *differ) compareRows(row1 reflect.Value, row2 reflect.Value, position int64) error {
+	changed := false
+	for _, c := range d.columns {
+		x, y := row1.Field(c.index[0]), row2.Field(c.index[1])
+		if c.equal(x, y, d.opts.Tolerance) {
+			continue
+		}
+		changed = true
+		c.nChanged++
+		if len(c.samples) < d.opts.Samples {
+			c.samples = append(c.samples, fmt.Sprintf("%v: %v -> %v", d.rowName(row1, 0, position), diffText(x, c.fields[0]), diffText(y, c.fields[1])))
+		}
+	}
+	if !changed {
+		d.result.Same++
+		return nil
+	}
+	d.result.Changed++
+	if err := d.writeRow("old", row1, 0); err != nil {
+		return err
+	}
+	return d.writeRow("new", row2, 1)
+}
+
+// Write the rows of a file sorted by keys to a temporary file, and return its name
+func sortByKeys(filename string, keys []string, opts DiffOptions) (string, error) {
+	pr, err := OpenReader(filename)
+	if err != nil {
+		return "", err
+	}
+	defer pr.Close()
+
+	file, err := ioutil.TempFile(opts.TempDir, "diff-*.parquet")
+	if err != nil {
+		return "", fmt.Errorf("can't create temporary file: %v", err)
+	}
+	file.Close()
+	pw, err := CreateWriterWithOptions(file.Name(), pr.DataType, WriterOptions{
+		Compression:  "UNCOMPRESSED",
+		RowGroupSize: 16 << 20,
+	})
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	sortKeys := make([]SortKey, len(keys))
+	for i, key := range keys {
+		sortKeys[i].Column = key
+	}
+	w, err := sortedWriter(pw, pr.Fields, SortOptions{Keys: sortKeys, Memory: opts.Memory, TempDir: opts.TempDir})
+	if err != nil {
+		pw.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	for {
+		slice, err := pr.Read(1000)
+		if err == nil && slice.Len() == 0 {
+			break
+		}
+		for i := 0; err == nil && i < slice.Len(); i++ {
+			err = w.Write(slice.Index(i))
+		}
+		if err != nil {
+			w.Close()
+			os.Remove(file.Name())
+			return "", err
+		}
+	}
+	if err = w.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// Compare the rows of two flat parquet files matched by key columns, or by
+// position without keys, and write the counts of the same, changed, removed
+// and added rows, and sample differences of each column. Rows are sorted by
+// key in temporary files, rows with the same key being matched in order
+func Diff(w io.Writer, filename1 string, filename2 string, opts DiffOptions) (*DiffResult, error) {
+	d := &differ{opts: opts, result: DiffResult{Columns: make(map[string]int64)}}
+	filenames := [2]string{filename1, filename2}
+
+	var (
+		fields [2][]Field
+		nRows  [2]int
+		err    error
+	)
+	for i, filename := range filenames {
+		pr, err := OpenReader(filename)
+		if err != nil {
+			return nil, fmt.Errorf("%v (only flat files can be compared)", err)
+		}
+		fields[i], nRows[i] = pr.Fields, pr.NumRows()
+		pr.Close()
+	}
+	var only [2][]string
+	if d.columns, d.keys, only, err = diffColumns(fields, opts.Keys); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(w, "%v: %v rows, %v: %v rows\n", filename1, nRows[0], filename2, nRows[1])
+	for i, names := range only {
+		if len(names) > 0 {
+			fmt.Fprintf(w, "Columns only in %v: %v\n", filenames[i], strings.Join(names, ", "))
+		}
+	}
+
+	// Rows are read from the files sorted by key
+	readFiles := filenames
+	if len(d.keys) > 0 {
+		for i, filename := range filenames {
+			sorted, err := sortByKeys(filename, opts.Keys, opts)
+			if err != nil {
+				return nil, err
+			}
+			defer os.Remove(sorted)
+			readFiles[i] = sorted
+		}
+	}
+	var (
+		readers [2]*batchReader
+		ok      [2]bool
+	)
+	for i, filename := range readFiles {
+		pr, err := OpenReader(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer pr.Close()
+		if readers[i], ok[i], err = newBatchReader(pr, i); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Output != "" {
+		if err = d.createOutput(); err != nil {
+			return nil, err
+		}
+	}
+
+	var position int64
+	for (ok[0] || ok[1]) && err == nil {
+		c := 0
+		switch {
+		case !ok[1]:
+			c = -1
+		case !ok[0]:
+			c = 1
+		case len(d.keys) > 0:
+			c = d.compareKeys(readers[0].row(), readers[1].row())
+		}
+
+		switch {
+		case c < 0:
+			d.result.Removed++
+			if err = d.writeRow("removed", readers[0].row(), 0); err == nil {
+				ok[0], err = readers[0].advance()
+			}
+		case c > 0:
+			d.result.Added++
+			if err = d.writeRow("added", readers[1].row(), 1); err == nil {
+				ok[1], err = readers[1].advance()
+			}
+		default:
+			position++
+			if err = d.compareRows(readers[0].row(), readers[1].row(), position); err == nil {
+				if ok[0], err = readers[0].advance(); err == nil {
+					ok[1], err = readers[1].advance()
+				}
+			}
+		}
+	}
+	if err != nil {
+		d.closeOutput()
+		return nil, err
+	}
+	if err = d.closeOutput(); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(w, "Rows: %v same, %v changed, %v removed, %v added\n", d.result.Same, d.result.Changed, d.result.Removed, d.result.Added)
+	for _, c := range d.columns {
+		if c.nChanged == 0 {
+			continue
+		}
+		d.result.Columns[c.name] = c.nChanged
+		fmt.Fprintf(w, "Column %v: %v rows changed\n", c.name, c.nChanged)
+		for _, sample := range c.samples {
+			fmt.Fprintf(w, "  %v\n", sample)
+		}
+	}
+	if opts.Output != "" {
+		fmt.Fprintf(w, "Differences written to %v\n", opts.Output)
+	}
+	return &d.result, nil
+}
@@ -0,0 +1,169 @@
+package pqtool
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Write two files of rows of fields, and return the result and the output of their diff
+func diffRows(t *testing.T, fields []Field, rows1 [][]interface{}, rows2 [][]interface{}, opts DiffOptions) (*DiffResult, string) {
+	t.Helper()
+	dir := t.TempDir()
+	filename1, filename2 := filepath.Join(dir, "old.parquet"), filepath.Join(dir, "new.parquet")
+	writeRows(t, filename1, fields, rows1)
+	writeRows(t, filename2, fields, rows2)
+	if opts.TempDir == "" {
+		opts.TempDir = dir
+	}
+	var b bytes.Buffer
+	result, err := Diff(&b, filename1, filename2, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result, b.String()
+}
+
+// Check the counts of a diff result
+func checkDiffResult(t *testing.T, result *DiffResult, expected DiffResult) {
+	t.Helper()
+	if expected.Columns == nil {
+		expected.Columns = make(map[string]int64)
+	}
+	if !reflect.DeepEqual(*result, expected) {
+		t.Errorf("result %+v, expected %+v", *result, expected)
+	}
+}
+
+// Rows with duplicate and null keys, matched in order among the rows with the same key
+func TestDiffKeys(t *testing.T) {
+	fields := []Field{
+		{Name: "id", Type: "INT64", Optional: true},
+		{Name: "value", Type: "UTF8"},
+	}
+	rows1 := [][]interface{}{
+		{int64(1), "a"}, {int64(2), "b"}, {int64(2), "c"}, {nil, "x"}, {nil, "y"}, {int64(3), "d"},
+	}
+	rows2 := [][]interface{}{
+		{int64(3), "d"}, {int64(2), "b"}, {int64(2), "z"}, {nil, "w"}, {int64(4), "e"}, {int64(1), "a"},
+	}
+
+	// Rows sorted in memory and in temporary files
+	for _, memory := range []int64{0, 1} {
+		result, output := diffRows(t, fields, rows1, rows2, DiffOptions{Keys: []string{"id"}, Samples: 10, Memory: memory})
+		checkDiffResult(t, result, DiffResult{Same: 3, Changed: 2, Removed: 1, Added: 1, Columns: map[string]int64{"value": 2}})
+		for _, line := range []string{
+			"Rows: 3 same, 2 changed, 1 removed, 1 added\n",
+			"  id=2: c -> z\n",
+			"  id=null: x -> w\n",
+		} {
+			if !strings.Contains(output, line) {
+				t.Errorf("memory %v: output %q, expected %q", memory, output, line)
+			}
+		}
+	}
+
+	// Rows with nulls in one of several keys
+	fields = append(fields, Field{Name: "group", Type: "UTF8", Optional: true})
+	rows1 = [][]interface{}{{int64(1), "a", nil}, {int64(1), "b", "g"}, {nil, "c", nil}}
+	rows2 = [][]interface{}{{nil, "c", nil}, {int64(1), "b", "g"}, {int64(1), "z", nil}}
+	result, output := diffRows(t, fields, rows1, rows2, DiffOptions{Keys: []string{"id", "group"}, Samples: 10})
+	checkDiffResult(t, result, DiffResult{Same: 2, Changed: 1, Columns: map[string]int64{"value": 1}})
+	if !strings.Contains(output, "  id=1, group=null: a -> z\n") {
+		t.Errorf("output %q, expected the keys of the changed row", output)
+	}
+
+	// Without keys, rows are matched by position
+	result, _ = diffRows(t, fields, rows1, rows2, DiffOptions{})
+	checkDiffResult(t, result, DiffResult{Same: 1, Changed: 2, Columns: map[string]int64{"id": 2, "value": 2}})
+}
+
+// Floats and decimals are equal within the tolerance, NaN equal to NaN
+func TestDiffTolerance(t *testing.T) {
+	fields := []Field{
+		{Name: "x", Type: "DOUBLE", Optional: true},
+		{Name: "y", Type: "FLOAT"},
+		{Name: "amount", Type: "DECIMAL", Precision: 10, Scale: 2},
+	}
+	rows1 := [][]interface{}{
+		{1.0, float32(1), int64(100)},
+		{2.0, float32(2), int64(200)},
+		{math.NaN(), float32(math.NaN()), int64(300)},
+		{nil, float32(4), int64(400)},
+	}
+	rows2 := [][]interface{}{
+		{1.005, float32(1.005), int64(101)},
+		{2.5, float32(2), int64(250)},
+		{math.NaN(), float32(math.NaN()), int64(300)},
+		{4.0, float32(4), int64(400)},
+	}
+
+	tests := []struct {
+		tolerance float64
+		expected  DiffResult
+	}{
+		{0, DiffResult{Same: 1, Changed: 3, Columns: map[string]int64{"x": 3, "y": 1, "amount": 2}}},
+		{0.02, DiffResult{Same: 2, Changed: 2, Columns: map[string]int64{"x": 2, "amount": 1}}},
+		{1, DiffResult{Same: 3, Changed: 1, Columns: map[string]int64{"x": 1}}},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.tolerance), func(t *testing.T) {
+			result, _ := diffRows(t, fields, rows1, rows2, DiffOptions{Tolerance: test.tolerance})
+			checkDiffResult(t, result, test.expected)
+		})
+	}
+}
+
+// The differences are written to parquet and CSV files: removed and added rows,
+// and old and new values of changed rows
+func TestDiffOutput(t *testing.T) {
+	fields := []Field{
+		{Name: "id", Type: "INT64"},
+		{Name: "value", Type: "UTF8", Optional: true},
+	}
+	rows1 := [][]interface{}{{int64(1), "a"}, {int64(2), "b"}, {int64(3), nil}}
+	rows2 := [][]interface{}{{int64(4), "d"}, {int64(3), "c"}, {int64(1), "a"}}
+
+	dir := t.TempDir()
+	parquetOutput, csvOutput := filepath.Join(dir, "diff.parquet"), filepath.Join(dir, "diff.csv")
+	result, output := diffRows(t, fields, rows1, rows2, DiffOptions{Keys: []string{"id"}, Output: parquetOutput})
+	checkDiffResult(t, result, DiffResult{Same: 1, Changed: 1, Removed: 1, Added: 1, Columns: map[string]int64{"value": 1}})
+	if !strings.HasSuffix(output, "Differences written to "+parquetOutput+"\n") {
+		t.Errorf("output %q, expected the file of differences", output)
+	}
+	expected := []string{
+		"_diff=removed,id=2,value=b",
+		"_diff=old,id=3,",
+		"_diff=new,id=3,value=c",
+		"_diff=added,id=4,value=d",
+	}
+	if rows := rowsText(t, parquetOutput); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("differences %q, expected %q", rows, expected)
+	}
+
+	diffRows(t, fields, rows1, rows2, DiffOptions{Keys: []string{"id"}, Output: csvOutput})
+	data, err := ioutil.ReadFile(csvOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, expected := string(data), "_diff,id,value\nremoved,2,b\nold,3,\nnew,3,c\nadded,4,d\n"; text != expected {
+		t.Errorf("CSV differences %q, expected %q", text, expected)
+	}
+
+	// Columns of different types are written as text
+	filename1, filename2 := filepath.Join(dir, "int64.parquet"), filepath.Join(dir, "int32.parquet")
+	writeRows(t, filename1, fields, [][]interface{}{{int64(1), "05"}})
+	writeRows(t, filename2, []Field{{Name: "id", Type: "INT32"}, {Name: "value", Type: "UTF8"}}, [][]interface{}{{int32(1), "5"}})
+	if _, err = Diff(ioutil.Discard, filename1, filename2, DiffOptions{Output: parquetOutput}); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"_diff=old,id=1,value=05", "_diff=new,id=1,value=5"}
+	if rows := rowsText(t, parquetOutput); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("differences %q, expected %q", rows, expected)
+	}
+}
@@ -0,0 +1,538 @@
+package pqtool
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Default range of simulated dates and timestamps: Jan 1st 2021 to Jan 1st 2023
+var (
+	defaultFrom = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	defaultTo   = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// Bounds of the values of integer types
+var intBounds = map[string]struct{ min, max float64 }{
+	"INT32":   {math.MinInt32, math.MaxInt32},
+	"INT64":   {math.MinInt64, math.MaxInt64},
+	"INT_8":   {math.MinInt8, math.MaxInt8},
+	"INT_16":  {math.MinInt16, math.MaxInt16},
+	"INT_32":  {math.MinInt32, math.MaxInt32},
+	"INT_64":  {math.MinInt64, math.MaxInt64},
+	"UINT_8":  {0, math.MaxUint8},
+	"UINT_16": {0, math.MaxUint16},
+	"UINT_32": {0, math.MaxUint32},
+	"UINT_64": {0, math.MaxUint64},
+}
+
+// Return the integer of a type nearest to x, an uint64 for UINT_64 and an int64 for the other types
+func toInteger(x float64, parquetType string) interface{} {
+	b := intBounds[parquetType]
+	x = math.Max(b.min, math.Min(b.max, math.Round(x)))
+	if parquetType == "UINT_64" {
+		if x >= math.MaxUint64 {
+			return uint64(math.MaxUint64)
+		}
+		return uint64(x)
+	}
+	if x >= math.MaxInt64 {
+		return int64(math.MaxInt64)
+	}
+	return int64(x)
+}
+
+// Return the bits of an integer returned by toInteger, so that differences are
+// computed the same way for signed and unsigned integers
+func intBits(x interface{}) uint64 {
+	if u, ok := x.(uint64); ok {
+		return u
+	}
+	return uint64(x.(int64))
+}
+
+// Return the integer of a type from its bits
+func fromBits(bits uint64, parquetType string) interface{} {
+	if parquetType == "UINT_64" {
+		return bits
+	}
+	return int64(bits)
+}
+
+// Return a random integer between 0 and n included, without modulo bias
+func randomUint64n(r *rand.Rand, n uint64) uint64 {
+	if n == math.MaxUint64 {
+		return r.Uint64()
+	}
+	n++
+	rest := (math.MaxUint64%n + 1) % n
+	for {
+		if x := r.Uint64(); x <= math.MaxUint64-rest {
+			return x % n
+		}
+	}
+}
+
+// Return a random string of minLength to maxLength letters
+func randomLetters(r *rand.Rand, minLength int, maxLength int) string {
+	text := make([]byte, minLength+r.Intn(maxLength-minLength+1))
+	for i := range text {
+		text[i] = characters[r.Intn(len(characters))]
+	}
+	return string(text)
+}
+
+// Return a random string following a pattern: # is a digit, ? a letter,
+// * a letter or digit, \ escapes the next character, others are kept as is
+func randomPattern(r *rand.Rand, pattern string) string {
+	const digits = "0123456789"
+	text := make([]byte, 0, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '#':
+			text = append(text, digits[r.Intn(len(digits))])
+		case '?':
+			text = append(text, characters[r.Intn(len(characters))])
+		case '*':
+			k := r.Intn(len(characters) + len(digits))
+			if k < len(characters) {
+				text = append(text, characters[k])
+			} else {
+				text = append(text, digits[k-len(characters)])
+			}
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			text = append(text, pattern[i])
+		default:
+			text = append(text, pattern[i])
+		}
+	}
+	return string(text)
+}
+
+// Return a random version 4 UUID
+func randomUUID(r *rand.Rand) string {
+	b := make([]byte, 16)
+	r.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Return a function picking one of n choices with the given weights, uniformly if there are no weights
+func weightedChoice(r *rand.Rand, n int, weights []float64) (func() int, error) {
+	if len(weights) == 0 {
+		return func() int { return r.Intn(n) }, nil
+	}
+	if len(weights) != n {
+		return nil, fmt.Errorf("%v weights for %v values", len(weights), n)
+	}
+
+	// Cumulated weights, searched for a random number up to the total
+	cumulated := make([]float64, n)
+	total := 0.0
+	for i, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("negative weight %v", w)
+		}
+		total += w
+		cumulated[i] = total
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("weights sum to zero")
+	}
+	return func() int {
+		x := r.Float64() * total
+		return sort.Search(n, func(i int) bool { return cumulated[i] > x })
+	}, nil
+}
+
+// Size in bits of integer types
+var intBitSizes = map[string]int{
+	"INT32": 32, "INT64": 64, "INT_8": 8, "INT_16": 16, "INT_32": 32, "INT_64": 64,
+	"UINT_8": 8, "UINT_16": 16, "UINT_32": 32, "UINT_64": 64,
+}
+
+// Return the value of a column's type for an enum value given as text
+func enumValue(x string, c Column) (interface{}, error) {
+	switch c.Type {
+	case "BOOLEAN":
+		return strconv.ParseBool(x)
+	case "UINT_8", "UINT_16", "UINT_32", "UINT_64":
+		u, err := strconv.ParseUint(x, 10, intBitSizes[c.Type])
+		if c.Type == "UINT_64" {
+			return u, err
+		}
+		return int64(u), err
+	case "INT32", "INT64", "INT_8", "INT_16", "INT_32", "INT_64":
+		return strconv.ParseInt(x, 10, intBitSizes[c.Type])
+	case "FLOAT", "DOUBLE":
+		return strconv.ParseFloat(x, 64)
+	case "DATE", "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS":
+		t, err := parseTime(x)
+		if err != nil {
+			return nil, err
+		}
+		if c.Type == "DATE" {
+			return ToDate(t), nil
+		}
+		return ToTimestamp(t, c.Type), nil
+	}
+	return x, nil
+}
+
+// Return the generator of an enum column, picking values among c.Values with c.Weights
+func enumGenerator(r *rand.Rand, c Column) (func(i int) interface{}, error) {
+	if len(c.Values) == 0 {
+		return nil, fmt.Errorf("enum without values")
+	}
+	values := make([]interface{}, len(c.Values))
+	for i, x := range c.Values {
+		v, err := enumValue(x, c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %v: %v", x, err)
+		}
+		values[i] = v
+	}
+	choice, err := weightedChoice(r, len(values), c.Weights)
+	if err != nil {
+		return nil, err
+	}
+	return func(i int) interface{} { return values[choice()] }, nil
+}
+
+// Return the generator of a numeric column, as float64 values
+func numberGenerator(r *rand.Rand, c Column) (func(i int) float64, error) {
+	min, max := math.Inf(-1), math.Inf(1)
+	if c.Min != nil {
+		min = c.Min.Float()
+	}
+	if c.Max != nil {
+		max = c.Max.Float()
+	}
+	if min > max {
+		return nil, fmt.Errorf("min %v greater than max %v", min, max)
+	}
+
+	// Keep values of unbounded distributions in [min, max]
+	clamp := func(x float64) float64 {
+		return math.Max(min, math.Min(max, x))
+	}
+
+	switch c.Generator {
+	case "uniform":
+		if c.Min == nil || c.Max == nil {
+			return nil, fmt.Errorf("uniform generator needs min and max")
+		}
+		return func(i int) float64 { return min + r.Float64()*(max-min) }, nil
+
+	case "normal":
+		stdDev := c.StdDev
+		if stdDev == 0 {
+			stdDev = 1
+		}
+		return func(i int) float64 { return clamp(c.Mean + stdDev*r.NormFloat64()) }, nil
+
+	case "exponential":
+		rate := c.Rate
+		if rate == 0 {
+			rate = 1
+		}
+		if rate < 0 {
+			return nil, fmt.Errorf("negative rate %v", rate)
+		}
+		offset := 0.0
+		if c.Min != nil {
+			offset = min
+		}
+		return func(i int) float64 { return clamp(offset + r.ExpFloat64()/rate) }, nil
+
+	case "zipf":
+		exponent := c.Exponent
+		if exponent == 0 {
+			exponent = 1.1
+		}
+		if exponent <= 1 {
+			return nil, fmt.Errorf("zipf exponent %v must be greater than 1", exponent)
+		}
+		offset, imax := 0.0, 1000.0
+		if c.Min != nil {
+			offset = min
+		}
+		if c.Max != nil {
+			imax = max - offset
+		}
+		z := rand.NewZipf(r, exponent, 1, uint64(imax))
+		return func(i int) float64 { return offset + float64(z.Uint64()) }, nil
+
+	case "sequence":
+		start, step := 1.0, c.Step
+		if c.Min != nil {
+			start = min
+		}
+		if step == 0 {
+			step = 1
+		}
+		return func(i int) float64 { return start + float64(i)*step }, nil
+	}
+	return nil, fmt.Errorf("generator %v not supported for type %v", c.Generator, c.Type)
+}
+
+// Return the generator of an integer column. Sequences of nRows values are
+// checked not to overflow, so they can be used as primary keys
+func integerGenerator(r *rand.Rand, c Column, nRows int) (func(i int) interface{}, error) {
+	// Bounds as the bits of integers, compared as signed or unsigned integers
+	b := intBounds[c.Type]
+	min, max := intBits(toInteger(b.min, c.Type)), intBits(toInteger(b.max, c.Type))
+	less := func(x uint64, y uint64) bool {
+		if c.Type == "UINT_64" {
+			return x < y
+		}
+		return int64(x) < int64(y)
+	}
+	var ok bool
+	if c.Min != nil {
+		if min, ok = c.Min.intBits(c.Type); !ok {
+			return nil, fmt.Errorf("min %v out of the range of %v", c.Min, c.Type)
+		}
+	}
+	if c.Max != nil {
+		if max, ok = c.Max.intBits(c.Type); !ok {
+			return nil, fmt.Errorf("max %v out of the range of %v", c.Max, c.Type)
+		}
+	}
+	if less(max, min) {
+		return nil, fmt.Errorf("min %v greater than max %v", fromBits(min, c.Type), fromBits(max, c.Type))
+	}
+
+	// parquet-go computes the differences of DELTA_BINARY_PACKED values in signed
+	// integers of 32 or 64 bits, and writes wrong values if they can overflow:
+	// uniform values without bounds are narrowed to a range below 2^(bits-2), as
+	// distributions without bounds don't get that far
+	bits := 64
+	if intBitSizes[c.Type] <= 32 {
+		bits = 32
+	}
+	if limit := uint64(1) << (bits - 2); c.Encoding == "DELTA_BINARY_PACKED" && c.Generator != "sequence" && c.Generator != "increasing" && max-min >= limit {
+		if c.Min != nil || c.Max != nil {
+			return nil, fmt.Errorf("DELTA_BINARY_PACKED values of %v need a range (max - min) below 2^%v", c.Type, bits-2)
+		}
+		min, max = 0, limit-1
+		if b.min < 0 {
+			min, max = -limit/2, limit/2-1
+		}
+	}
+
+	switch c.Generator {
+	case "", "uniform":
+		return func(i int) interface{} { return fromBits(min+randomUint64n(r, max-min), c.Type) }, nil
+
+	case "sequence", "increasing":
+		// Fixed step for sequences, random gaps from 1 to step for increasing values
+		step := c.Step
+		if step == 0 {
+			step = 1
+		}
+		if step < 1 || step != math.Trunc(step) || step >= math.MaxUint64 {
+			return nil, fmt.Errorf("step %v is not a positive integer", step)
+		}
+		first, steps := uint64(1), uint64(step)
+		if c.Min != nil {
+			first = min
+		}
+		if nRows > 0 {
+			n := uint64(nRows - 1)
+			if less(max, first) || (n > 0 && (max-first)/n < steps) {
+				return nil, fmt.Errorf("%v values from %v by %v exceed %v", nRows, fromBits(first, c.Type), steps, fromBits(max, c.Type))
+			}
+		}
+
+		if c.Generator == "sequence" {
+			return func(i int) interface{} { return fromBits(first+uint64(i)*steps, c.Type) }, nil
+		}
+		// Values of rows from i start above the largest value of the rows before i,
+		// so that files of consecutive rows can be generated separately
+		var next uint64
+		started := false
+		return func(i int) interface{} {
+			if !started {
+				next, started = first+uint64(i)*steps, true
+			}
+			x := next
+			next += 1 + randomUint64n(r, steps-1)
+			return fromBits(x, c.Type)
+		}, nil
+	}
+
+	number, err := numberGenerator(r, c)
+	if err != nil {
+		return nil, err
+	}
+	return func(i int) interface{} { return toInteger(number(i), c.Type) }, nil
+}
+
+// Return the generator of a text column
+func stringGenerator(r *rand.Rand, c Column) (func(i int) interface{}, error) {
+	switch c.Generator {
+	case "", "random":
+		minLength, maxLength := c.MinLength, c.MaxLength
+		if len(c.LengthWeights) > 0 {
+			// Lengths from minLength with the given weights
+			length, err := weightedChoice(r, len(c.LengthWeights), c.LengthWeights)
+			if err != nil {
+				return nil, fmt.Errorf("length weights: %v", err)
+			}
+			return func(i int) interface{} {
+				n := minLength + length()
+				return randomLetters(r, n, n)
+			}, nil
+		}
+		if minLength == 0 && maxLength == 0 {
+			minLength, maxLength = 3, 14
+		}
+		if maxLength < minLength {
+			maxLength = minLength
+		}
+		return func(i int) interface{} { return randomLetters(r, minLength, maxLength) }, nil
+	case "pattern":
+		if c.Pattern == "" {
+			return nil, fmt.Errorf("pattern generator without pattern")
+		}
+		return func(i int) interface{} { return randomPattern(r, c.Pattern) }, nil
+	case "uuid":
+		return func(i int) interface{} { return randomUUID(r) }, nil
+	}
+	return nil, fmt.Errorf("generator %v not supported for type %v", c.Generator, c.Type)
+}
+
+// Return the generator of a date or timestamp column, between c.From and c.To
+func timeGenerator(r *rand.Rand, c Column) (func(i int) interface{}, error) {
+	from, to := defaultFrom, defaultTo
+	var err error
+	if c.From != "" {
+		if from, err = parseTime(c.From); err != nil {
+			return nil, err
+		}
+	}
+	if c.To != "" {
+		if to, err = parseTime(c.To); err != nil {
+			return nil, err
+		}
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from %v not before to %v", c.From, c.To)
+	}
+
+	value := func(t time.Time) interface{} {
+		if c.Type == "DATE" {
+			return ToDate(t)
+		}
+		return ToTimestamp(t, c.Type)
+	}
+
+	switch c.Generator {
+	case "", "uniform":
+		seconds := to.Unix() - from.Unix()
+		return func(i int) interface{} { return value(time.Unix(from.Unix()+r.Int63n(seconds), 0)) }, nil
+	case "sequence":
+		// Step in days for dates, in seconds for timestamps
+		step := c.Step
+		if step == 0 {
+			step = 1
+		}
+		if c.Type == "DATE" {
+			step *= 24 * 60 * 60
+		}
+		return func(i int) interface{} {
+			return value(from.Add(time.Duration(float64(i) * step * float64(time.Second))))
+		}, nil
+	}
+	return nil, fmt.Errorf("generator %v not supported for type %v", c.Generator, c.Type)
+}
+
+// Return the generator of a column's values for nRows rows, a function of the row index
+func columnGenerator(r *rand.Rand, c Column, nRows int) (func(i int) interface{}, error) {
+	if c.Generator == "enum" {
+		return enumGenerator(r, c)
+	}
+
+	switch c.Type {
+	case "BOOLEAN":
+		if c.Generator != "" {
+			return nil, fmt.Errorf("generator %v not supported for type %v", c.Generator, c.Type)
+		}
+		return func(i int) interface{} { return r.Intn(2) == 1 }, nil
+
+	case "INT32", "INT64", "INT_8", "INT_16", "INT_32", "INT_64", "UINT_8", "UINT_16", "UINT_32", "UINT_64":
+		return integerGenerator(r, c, nRows)
+
+	case "DECIMAL":
+		// Unscaled values of at most Precision digits, generated as INT64 values.
+		// Generators without min are positive, except uniform and normal values
+		limit := int64(math.Pow10(c.Precision)) - 1
+		d := c
+		d.Type = "INT64"
+		if d.Max == nil {
+			d.Max = IntNumber(limit)
+		}
+		if d.Min == nil && (d.Generator == "" || d.Generator == "uniform" || d.Generator == "normal") {
+			d.Min = IntNumber(-limit)
+		}
+		for _, n := range []*Number{d.Min, d.Max} {
+			if n == nil {
+				continue
+			}
+			if x, ok := n.intBits("INT64"); !ok || int64(x) < -limit || int64(x) > limit {
+				return nil, fmt.Errorf("bound %v out of the unscaled values of %v", n, c.TypeName())
+			}
+		}
+		return integerGenerator(r, d, nRows)
+
+	case "FLOAT", "DOUBLE":
+		if c.Generator == "" {
+			c.Generator = "normal"
+			if c.Min != nil && c.Max != nil {
+				c.Generator = "uniform"
+			}
+		}
+		number, err := numberGenerator(r, c)
+		if err != nil {
+			return nil, err
+		}
+		return func(i int) interface{} { return number(i) }, nil
+
+	case "UTF8", "BYTE_ARRAY":
+		return stringGenerator(r, c)
+
+	case "DATE", "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS":
+		return timeGenerator(r, c)
+	}
+	return nil, fmt.Errorf("type %v can't be simulated", c.Type)
+}
+
+// Return a pool of cardinality distinct values of a generator
+func cardinalityPool(generate func(i int) interface{}, cardinality int) []interface{} {
+	pool := make([]interface{}, 0, cardinality)
+	seen := make(map[interface{}]bool)
+	for k := 0; len(pool) < cardinality && k < 100*cardinality; k++ {
+		x := generate(k)
+		if !seen[x] {
+			seen[x] = true
+			pool = append(pool, x)
+		}
+	}
+	if len(pool) < cardinality {
+		logf("Only %v distinct values generated out of cardinality %v", len(pool), cardinality)
+	}
+	return pool
+}
+
+// Return a generator of values picked at random in a pool
+func poolGenerator(r *rand.Rand, pool []interface{}) func(i int) interface{} {
+	return func(i int) interface{} { return pool[r.Intn(len(pool))] }
+}
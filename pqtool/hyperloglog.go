@@ -0,0 +1,60 @@
+package pqtool
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// Number of bits of the hashes selecting the register of a HyperLogLog
+const hllPrecision = 14
+
+// HyperLogLog estimating the number of distinct values of a column with
+// 2^hllPrecision registers (16 KB), with a standard error of about 0.8%
+type hyperLogLog struct {
+	registers []uint8
+}
+
+// Return an empty HyperLogLog
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{registers: make([]uint8, 1<<hllPrecision)}
+}
+
+// Return the 64-bit hash of a value: FNV-1a mixed by the finalizer of SplitMix64,
+// as the high bits of FNV-1a hashes of short values aren't uniform
+func hllHash(value string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	x := h.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// Add a value
+func (h *hyperLogLog) add(value string) {
+	x := hllHash(value)
+	register := x >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h.registers[register] {
+		h.registers[register] = rank
+	}
+}
+
+// Return the estimated number of distinct values added
+func (h *hyperLogLog) count() int64 {
+	m := float64(len(h.registers))
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// Linear counting for small cardinalities
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
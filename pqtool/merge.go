@@ -0,0 +1,474 @@
+package pqtool
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/schema"
+	"github.com/xitongsys/parquet-go/tool/parquet-tools/schematool"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// MergeOptions are the options of Merge
+type MergeOptions struct {
+	TargetSize    int64 // Size of the output files in bytes, a single output file if 0
+	CopyRowGroups bool  // Copy the row groups without decoding them if the schemas and codecs of the files are the same
+	Writer        WriterOptions
+}
+
+// Parquet file to merge, with its footer as written in the file
+type mergeInput struct {
+	filename string
+	footer   *parquet.FileMetaData
+	tree     *schematool.SchemaTree
+}
+
+// Read the footer of a parquet file, without renaming its schema like a parquet reader
+func readFooter(filename string) (*parquet.FileMetaData, error) {
+	fr, err := local.NewLocalFileReader(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't open parquet file '%v': %v", filename, err)
+	}
+	defer fr.Close()
+
+	pr := &reader.ParquetReader{PFile: fr}
+	if err = pr.ReadFooter(); err != nil {
+		return nil, fmt.Errorf("can't read footer of parquet file '%v': %v", filename, err)
+	}
+	return pr.Footer, nil
+}
+
+// Return whether two schema trees have the same columns, with the same names,
+// types and repetition types. The names of the roots are ignored
+func sameSchema(a *schematool.Node, b *schematool.Node, root bool) bool {
+	if !root && !reflect.DeepEqual(a.SE, b.SE) {
+		return false
+	}
+	if len(a.Children) != len(b.Children) {
+		return false
+	}
+	for i := range a.Children {
+		if !sameSchema(a.Children[i], b.Children[i], false) {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the flat columns of the merged files as stored in their footers: the
+// columns of the first file, then the columns of the next files it doesn't
+// have. Columns with the same name must have the same type, and are optional if they are optional or
+// missing in a file
+func mergeFields(inputs []*mergeInput) ([]Field, error) {
+	var fields []Field
+	index := make(map[string]int)
+	for n, in := range inputs {
+		inFields, err := storedFields(schema.NewSchemaHandlerFromSchemaList(in.footer.Schema))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v (nested files can only be merged by copying row groups)", in.filename, err)
+		}
+
+		found := make(map[string]bool)
+		for _, f := range inFields {
+			found[f.Name] = true
+			k, ok := index[f.Name]
+			if !ok {
+				// A new column is missing in the files before
+				f.Optional = f.Optional || n > 0
+				index[f.Name] = len(fields)
+				fields = append(fields, f)
+				continue
+			}
+			if storedTypeName(fields[k]) != storedTypeName(f) {
+				return nil, fmt.Errorf("column %v is %v in %v but %v in %v", f.Name, storedTypeName(fields[k]), inputs[0].filename, storedTypeName(f), in.filename)
+			}
+			fields[k].Optional = fields[k].Optional || f.Optional
+		}
+		for k := range fields {
+			if !found[fields[k].Name] {
+				fields[k].Optional = true
+			}
+		}
+	}
+	return fields, nil
+}
+
+// Return the type of a stored column, with the physical type of decimals and
+// the length of fixed length byte arrays (e.g. DECIMAL(18,3) FIXED_LEN_BYTE_ARRAY(8))
+func storedTypeName(f Field) string {
+	name := f.TypeName()
+	if f.Type == "DECIMAL" && f.BaseType != "" {
+		name += " " + f.BaseType
+	}
+	if f.Length > 0 {
+		name += fmt.Sprintf("(%v)", f.Length)
+	}
+	return name
+}
+
+// Return the names of n output files: the output file name itself for a
+// single file, else the name with the index of the file (e.g. out-00001.parquet)
+func outputFilenames(output string, n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = partFilename(output, i, n)
+	}
+	return names
+}
+
+// Merge parquet files into an output file, or into output files of about
+// opts.TargetSize bytes. The files must have the same schema, or compatible
+// flat schemas whose columns are merged by name. If opts.CopyRowGroups is set
+// and the files have the same schema and the codec of the output, their row
+// groups are copied without being decoded
+func Merge(filenames []string, output string, opts MergeOptions) error {
+	if len(filenames) == 0 {
+		return fmt.Errorf("no files to merge")
+	}
+
+	codec := parquet.CompressionCodec_SNAPPY
+	if opts.Writer.Compression != "" {
+		var err error
+		codec, err = parquet.CompressionCodecFromString(strings.ToUpper(opts.Writer.Compression))
+		if err != nil || !supportedCodecs[codec] {
+			return fmt.Errorf("invalid compression codec %v", opts.Writer.Compression)
+		}
+	}
+
+	// Footers and schema trees of the files
+	inputs := make([]*mergeInput, len(filenames))
+	same, sameCodec := true, true
+	var nRows, size int64
+	for i, filename := range filenames {
+		footer, err := readFooter(filename)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		inputs[i] = &mergeInput{
+			filename: filename,
+			footer:   footer,
+			tree:     schematool.CreateSchemaTree(footer.Schema),
+		}
+		nRows += footer.NumRows
+		size += info.Size()
+
+		same = same && sameSchema(inputs[0].tree.Root, inputs[i].tree.Root, true)
+		for _, rowGroup := range footer.RowGroups {
+			for _, column := range rowGroup.Columns {
+				sameCodec = sameCodec && column.MetaData != nil && column.MetaData.Codec == codec
+			}
+		}
+	}
+
+	if opts.CopyRowGroups {
+		if same && sameCodec {
+			return copyRowGroups(inputs, output, opts.TargetSize)
+		}
+		if !same {
+			logf("Row groups decoded: the files have different schemas")
+		} else {
+			logf("Row groups decoded: the files aren't all compressed with %v", codec)
+		}
+	}
+
+	fields, err := mergeFields(inputs)
+	if err != nil {
+		return err
+	}
+
+	// Number of rows of the output files from the size of a row in the files
+	rowsPerFile := nRows
+	if opts.TargetSize > 0 && size > 0 {
+		rowsPerFile = int64(float64(opts.TargetSize) * float64(nRows) / float64(size))
+	}
+	if rowsPerFile < 1 {
+		rowsPerFile = 1
+	}
+	files := int((nRows + rowsPerFile - 1) / rowsPerFile)
+	if files < 1 {
+		files = 1
+	}
+	return mergeRows(inputs, fields, outputFilenames(output, files), rowsPerFile, opts.Writer)
+}
+
+// Decode the rows of the files and write them to the output files, rowsPerFile
+// rows in each file
+func mergeRows(inputs []*mergeInput, fields []Field, outputs []string, rowsPerFile int64, opts WriterOptions) error {
+	dataType, err := StructType(fields)
+	if err != nil {
+		return err
+	}
+	index := make(map[string]int)
+	for k, f := range fields {
+		index[f.Name] = k
+	}
+
+	var (
+		pw    *Writer
+		part  int
+		nRows int64
+	)
+	closeOutput := func() error {
+		if err := pw.Close(); err != nil {
+			return err
+		}
+		logf("Parquet file %v written with %v rows and %v fields", outputs[part], nRows, len(fields))
+		pw, nRows = nil, 0
+		part++
+		return nil
+	}
+
+	for _, in := range inputs {
+		pr, err := openStoredReader(in.filename)
+		if err != nil {
+			if pw != nil {
+				pw.Close()
+			}
+			return err
+		}
+
+		// Columns of the merged rows of the columns of the file
+		columns := make([]int, len(pr.Fields))
+		for j, f := range pr.Fields {
+			columns[j] = index[f.Name]
+		}
+
+		for {
+			slice, err := pr.Read(1000)
+			if err != nil || slice.Len() == 0 {
+				pr.Close()
+				if err != nil {
+					if pw != nil {
+						pw.Close()
+					}
+					return err
+				}
+				break
+			}
+
+			for i := 0; i < slice.Len(); i++ {
+				if pw == nil {
+					if pw, err = CreateWriterWithOptions(outputs[part], dataType, opts); err != nil {
+						pr.Close()
+						return err
+					}
+				}
+
+				v := pw.NewRow()
+				row := slice.Index(i)
+				for j, k := range columns {
+					field := row.Field(j)
+					if field.Kind() == reflect.Ptr {
+						if field.IsNil() {
+							continue
+						}
+						field = field.Elem()
+					}
+					setField(v.Field(k), field)
+				}
+				if err = pw.Write(v); err != nil {
+					pr.Close()
+					pw.Close()
+					return err
+				}
+
+				nRows++
+				if nRows == rowsPerFile && part < len(outputs)-1 {
+					if err = closeOutput(); err != nil {
+						pr.Close()
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	// Files without rows still give an empty output file
+	if pw == nil && part == 0 {
+		if pw, err = CreateWriterWithOptions(outputs[0], dataType, opts); err != nil {
+			return err
+		}
+	}
+	if pw != nil {
+		return closeOutput()
+	}
+	return nil
+}
+
+// Row group of a parquet file
+type fileRowGroup struct {
+	filename string
+	rg       *parquet.RowGroup
+}
+
+// Return the compressed size of a row group
+func rowGroupSize(rg *parquet.RowGroup) int64 {
+	var size int64
+	for _, column := range rg.Columns {
+		size += column.MetaData.TotalCompressedSize
+	}
+	return size
+}
+
+// Group consecutive row groups into groups of about targetSize bytes (a single
+// group if 0). Each group has at least one row group, which can be bigger than targetSize
+func groupBySize(rowGroups []fileRowGroup, targetSize int64) [][]fileRowGroup {
+	var (
+		groups [][]fileRowGroup
+		size   int64
+	)
+	for _, g := range rowGroups {
+		rgSize := rowGroupSize(g.rg)
+		if len(groups) == 0 || (targetSize > 0 && size > 0 && size+rgSize > targetSize) {
+			groups = append(groups, nil)
+			size = 0
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], g)
+		size += rgSize
+	}
+	if len(groups) == 0 {
+		groups = append(groups, nil)
+	}
+	return groups
+}
+
+// Copy the row groups of files with the same schema into output files of about
+// targetSize bytes (a single file if 0), without decoding them
+func copyRowGroups(inputs []*mergeInput, output string, targetSize int64) error {
+	var rowGroups []fileRowGroup
+	for _, in := range inputs {
+		for _, rg := range in.footer.RowGroups {
+			rowGroups = append(rowGroups, fileRowGroup{in.filename, rg})
+		}
+	}
+	return writeRowGroups(groupBySize(rowGroups, targetSize), output, inputs[0].footer)
+}
+
+// Write a file per group of row groups with the schema and key-value metadata
+// of a footer, copying the row groups without decoding them
+func writeRowGroups(groups [][]fileRowGroup, output string, footer *parquet.FileMetaData) error {
+	outputs := outputFilenames(output, len(groups))
+	for part, group := range groups {
+		w, err := createCopyWriter(outputs[part], footer)
+		if err != nil {
+			return err
+		}
+		for _, g := range group {
+			if err = w.copyRowGroup(g.filename, g.rg); err != nil {
+				w.file.Close()
+				return err
+			}
+		}
+		if err = w.close(); err != nil {
+			return err
+		}
+		logf("Parquet file %v written with %v rows in %v row groups copied", outputs[part], w.footer.NumRows, len(group))
+	}
+	return nil
+}
+
+// Writer of a parquet file whose row groups are copied from other files
+type copyWriter struct {
+	file   *os.File
+	footer *parquet.FileMetaData
+	offset int64
+}
+
+// Create a parquet file with the schema and key-value metadata of a footer
+func createCopyWriter(filename string, footer *parquet.FileMetaData) (*copyWriter, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't create parquet file '%v': %v", filename, err)
+	}
+	if _, err = file.Write([]byte("PAR1")); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("writing parquet file '%v': %v", filename, err)
+	}
+
+	w := &copyWriter{file: file, footer: parquet.NewFileMetaData(), offset: 4}
+	w.footer.Version = footer.Version
+	w.footer.Schema = footer.Schema
+	w.footer.CreatedBy = footer.CreatedBy
+	w.footer.KeyValueMetadata = footer.KeyValueMetadata
+	return w, nil
+}
+
+// Copy the column chunks of a row group of a file, moving their offsets
+func (w *copyWriter) copyRowGroup(filename string, rg *parquet.RowGroup) error {
+	in, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("can't open parquet file '%v': %v", filename, err)
+	}
+	defer in.Close()
+
+	copied := &parquet.RowGroup{
+		TotalByteSize:  rg.TotalByteSize,
+		NumRows:        rg.NumRows,
+		SortingColumns: rg.SortingColumns,
+	}
+	for _, column := range rg.Columns {
+		// The chunk starts with its dictionary page if it has one
+		start := column.MetaData.DataPageOffset
+		if offset := column.MetaData.DictionaryPageOffset; offset != nil && *offset > 0 && *offset < start {
+			start = *offset
+		}
+		if _, err = in.Seek(start, io.SeekStart); err != nil {
+			return fmt.Errorf("reading parquet file '%v': %v", filename, err)
+		}
+		if _, err = io.CopyN(w.file, in, column.MetaData.TotalCompressedSize); err != nil {
+			return fmt.Errorf("copying column chunk of parquet file '%v': %v", filename, err)
+		}
+
+		shift := w.offset - start
+		metaData := *column.MetaData
+		metaData.DataPageOffset += shift
+		if metaData.DictionaryPageOffset != nil {
+			offset := *metaData.DictionaryPageOffset + shift
+			metaData.DictionaryPageOffset = &offset
+		}
+		if metaData.IndexPageOffset != nil {
+			offset := *metaData.IndexPageOffset + shift
+			metaData.IndexPageOffset = &offset
+		}
+		copied.Columns = append(copied.Columns, &parquet.ColumnChunk{
+			FileOffset: column.FileOffset + shift,
+			MetaData:   &metaData,
+		})
+		w.offset += column.MetaData.TotalCompressedSize
+	}
+
+	w.footer.RowGroups = append(w.footer.RowGroups, copied)
+	w.footer.NumRows += rg.NumRows
+	return nil
+}
+
+// Write the footer and close the file
+func (w *copyWriter) close() error {
+	defer w.file.Close()
+
+	ts := thrift.NewTSerializer()
+	ts.Protocol = thrift.NewTCompactProtocolFactory().GetProtocol(ts.Transport)
+	footer, err := ts.Write(context.TODO(), w.footer)
+	if err != nil {
+		return fmt.Errorf("can't encode footer: %v", err)
+	}
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(footer)))
+	for _, data := range [][]byte{footer, size, []byte("PAR1")} {
+		if _, err = w.file.Write(data); err != nil {
+			return fmt.Errorf("writing parquet file: %v", err)
+		}
+	}
+	return nil
+}
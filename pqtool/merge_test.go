@@ -0,0 +1,23 @@
+package pqtool
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// Decoded rows of merged files keep the schema of the files
+func TestMergeRowsKeepsSchema(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.parquet")
+	writeStoredTestFile(t, input, 100)
+	parts := filepath.Join(dir, "part.parquet")
+	if err := Split(input, parts, SplitOptions{Parts: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "output.parquet")
+	if err := Merge(outputFilenames(parts, 3), output, MergeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	checkSameFile(t, output, input)
+}
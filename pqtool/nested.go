@@ -0,0 +1,294 @@
+package pqtool
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Return whether a parquet type is a nested type of simulated columns
+func isNested(parquetType string) bool {
+	switch parquetType {
+	case "LIST", "MAP", "STRUCT":
+		return true
+	}
+	return false
+}
+
+// Return whether the values of a column can be null, stored as pointers
+func (c Column) nullable() bool {
+	return c.Optional || c.NullRatio > 0
+}
+
+// Check and set the types of the items of a list, the key and value of a map
+// or the fields of a struct
+func (c *Column) setNestedTypes() error {
+	switch c.Type {
+	case "LIST":
+		if c.Items == nil {
+			return fmt.Errorf("list %v without items", c.Name)
+		}
+		return c.Items.setChildType(c.Name, "element")
+
+	case "MAP":
+		if c.Key == nil || c.Value == nil {
+			return fmt.Errorf("map %v needs a key and a value", c.Name)
+		}
+		if err := c.Key.setChildType(c.Name, "key"); err != nil {
+			return err
+		}
+		if isNested(c.Key.Type) || c.Key.nullable() {
+			return fmt.Errorf("keys of map %v must be scalars without nulls", c.Name)
+		}
+		return c.Value.setChildType(c.Name, "value")
+
+	case "STRUCT":
+		if len(c.Fields) == 0 {
+			return fmt.Errorf("struct %v without fields", c.Name)
+		}
+		if err := setTypes(c.Fields); err != nil {
+			return fmt.Errorf("struct %v: %v", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// Set the type of the items of a list or the key or value of a map. Lists and
+// maps can't be items of lists or values of maps, but can be fields of their structs
+func (c *Column) setChildType(parent string, name string) error {
+	if c.Name == "" {
+		c.Name = name
+	}
+	if err := c.setType(c.Type); err != nil {
+		return fmt.Errorf("%v of %v: %v", name, parent, err)
+	}
+	if c.Type == "LIST" || c.Type == "MAP" {
+		return fmt.Errorf("%v of %v can't be a %v, use a struct", name, parent, strings.ToLower(c.Type))
+	}
+	return nil
+}
+
+// Return the Go type of the values of a column and its parquet tag items
+// (without name and repetition type), prefixed for list items and map keys and values
+func (c Column) goType(prefix string) (reflect.Type, string, error) {
+	switch c.Type {
+	case "LIST":
+		itemType, itemTag, err := c.Items.goType("value")
+		if err != nil {
+			return nil, "", err
+		}
+		if c.Items.nullable() {
+			itemType = reflect.PtrTo(itemType)
+		}
+		return reflect.SliceOf(itemType), addTag("type=LIST", itemTag), nil
+
+	case "MAP":
+		keyType, keyTag, err := c.Key.goType("key")
+		if err != nil {
+			return nil, "", err
+		}
+		valueType, valueTag, err := c.Value.goType("value")
+		if err != nil {
+			return nil, "", err
+		}
+		if c.Value.nullable() {
+			valueType = reflect.PtrTo(valueType)
+		}
+		return reflect.MapOf(keyType, valueType), addTag(addTag("type=MAP", keyTag), valueTag), nil
+
+	case "STRUCT":
+		structType, err := columnsType(c.Fields)
+		return structType, "", err
+	}
+
+	goType := GoType(c.Type)
+	if goType == nil {
+		return nil, "", fmt.Errorf("invalid type for field %v: %v", c.Name, c.Type)
+	}
+	items := []string{"type", c.Type}
+	if c.Type == "DECIMAL" {
+		items = append(items, "basetype", "INT64", "precision", strconv.Itoa(c.Precision), "scale", strconv.Itoa(c.Scale))
+	}
+	if c.Encoding != "" {
+		items = append(items, "encoding", c.Encoding)
+	}
+	return goType, tagItems(prefix, items...), nil
+}
+
+// Add tag items to a tag
+func addTag(tag string, items string) string {
+	if tag == "" || items == "" {
+		return tag + items
+	}
+	return tag + ", " + items
+}
+
+// Build the reflect structure of a row of columns, nested columns included.
+// Flat columns have the same structure as with StructType
+func columnsType(columns []Column) (reflect.Type, error) {
+	structFields := make([]reflect.StructField, len(columns))
+	names := make(map[string]bool)
+	for i, c := range columns {
+		fieldType, tag, err := c.goType("")
+		if err != nil {
+			return nil, err
+		}
+		tag = addTag("name="+c.Name, tag)
+		if c.nullable() {
+			fieldType = reflect.PtrTo(fieldType)
+			tag += ", repetitiontype=OPTIONAL"
+		}
+
+		structFields[i] = reflect.StructField{
+			Name: FieldName(c.Name, i, names),
+			Type: fieldType,
+			Tag:  reflect.StructTag(fmt.Sprintf(`parquet:"%v"`, tag)),
+		}
+	}
+	return reflect.StructOf(structFields), nil
+}
+
+// Return the generator of the number of items of lists and maps, from
+// min_length to max_length (0 to 5 by default) or with length_weights,
+// and the maximum number of items
+func collectionLength(r *rand.Rand, c Column) (func() int, int, error) {
+	minLength, maxLength := c.MinLength, c.MaxLength
+	if len(c.LengthWeights) > 0 {
+		length, err := weightedChoice(r, len(c.LengthWeights), c.LengthWeights)
+		if err != nil {
+			return nil, 0, fmt.Errorf("length weights: %v", err)
+		}
+		return func() int { return minLength + length() }, minLength + len(c.LengthWeights) - 1, nil
+	}
+	if minLength == 0 && maxLength == 0 {
+		maxLength = 5
+	}
+	if maxLength < minLength {
+		maxLength = minLength
+	}
+	return func() int { return minLength + r.Intn(maxLength-minLength+1) }, maxLength, nil
+}
+
+// Return the generator of the values of a column, nested or not. Pools of
+// values of the children of nested columns with a cardinality are drawn from
+// poolSeed, to be the same in all files
+func valueGenerator(r *rand.Rand, c Column, nRows int, poolSeed int64) (func(i int) interface{}, error) {
+	if isNested(c.Type) {
+		return nestedGenerator(r, c, nRows, poolSeed)
+	}
+	return columnGenerator(r, c, nRows)
+}
+
+// Return the generator of the items of a list, the keys or values of a map or
+// the fields of a struct, as values of type t (nil pointers for nulls). It has
+// its own random sources, like columns
+func childGenerator(seed int64, poolSeed int64, c Column, nValues int, t reflect.Type) (func(i int) reflect.Value, error) {
+	r := rand.New(rand.NewSource(seed))
+	generate, err := valueGenerator(r, c, nValues, poolSeed)
+	if err != nil {
+		return nil, fmt.Errorf("field %v: %v", c.Name, err)
+	}
+	if c.Cardinality > 0 {
+		pool, err := valueGenerator(rand.New(rand.NewSource(poolSeed)), c, nValues, poolSeed)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v", c.Name, err)
+		}
+		generate = poolGenerator(r, cardinalityPool(pool, c.Cardinality))
+	}
+
+	nulls := rand.New(rand.NewSource(columnSeed(seed, "null")))
+	return func(i int) reflect.Value {
+		v := reflect.New(t).Elem()
+		x := generate(i)
+		if c.NullRatio > 0 && nulls.Float64() < c.NullRatio {
+			return v
+		}
+		setField(v, x)
+		return v
+	}, nil
+}
+
+// Return the generator of a list, map or struct column. Items of lists and
+// entries of maps are numbered across rows for their sequences, starting at the
+// first row times the maximum number of items so that files of consecutive rows
+// can be generated separately. Map keys are unique in a map, which can have
+// fewer entries than drawn if there aren't enough distinct keys
+func nestedGenerator(r *rand.Rand, c Column, nRows int, poolSeed int64) (func(i int) interface{}, error) {
+	if c.Generator != "" {
+		return nil, fmt.Errorf("generator %v not supported for type %v", c.Generator, c.Type)
+	}
+	t, _, err := c.goType("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Children are seeded from the column's source and their names
+	seed := r.Int63()
+
+	if c.Type == "STRUCT" {
+		fields := make([]func(i int) reflect.Value, len(c.Fields))
+		for j, f := range c.Fields {
+			if fields[j], err = childGenerator(columnSeed(seed, f.Name), columnSeed(poolSeed, f.Name), f, nRows, t.Field(j).Type); err != nil {
+				return nil, err
+			}
+		}
+		return func(i int) interface{} {
+			v := reflect.New(t).Elem()
+			for j, field := range fields {
+				v.Field(j).Set(field(i))
+			}
+			return v
+		}, nil
+	}
+
+	length, maxLength, err := collectionLength(r, c)
+	if err != nil {
+		return nil, err
+	}
+	k, started := 0, false
+
+	if c.Type == "LIST" {
+		item, err := childGenerator(columnSeed(seed, c.Items.Name), columnSeed(poolSeed, c.Items.Name), *c.Items, nRows*maxLength, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(i int) interface{} {
+			if !started {
+				k, started = i*maxLength, true
+			}
+			n := length()
+			v := reflect.MakeSlice(t, n, n)
+			for j := 0; j < n; j++ {
+				v.Index(j).Set(item(k))
+				k++
+			}
+			return v
+		}, nil
+	}
+
+	key, err := childGenerator(columnSeed(seed, c.Key.Name), columnSeed(poolSeed, c.Key.Name), *c.Key, nRows*maxLength, t.Key())
+	if err != nil {
+		return nil, err
+	}
+	value, err := childGenerator(columnSeed(seed, c.Value.Name), columnSeed(poolSeed, c.Value.Name), *c.Value, nRows*maxLength, t.Elem())
+	if err != nil {
+		return nil, err
+	}
+	return func(i int) interface{} {
+		if !started {
+			k, started = i*maxLength, true
+		}
+		n := length()
+		v := reflect.MakeMapWithSize(t, n)
+		for tries := 0; v.Len() < n && tries < 100*n; tries++ {
+			x, y := key(k), value(k)
+			k++
+			if !v.MapIndex(x).IsValid() {
+				v.SetMapIndex(x, y)
+			}
+		}
+		return v
+	}, nil
+}
@@ -0,0 +1,65 @@
+// Package pqtool converts, inspects and simulates parquet files.
+//
+// It backs the parquet command and can be imported by Go programs to do the
+// same conversions without shelling out. Functions return errors instead of
+// exiting, and report progress (structure, row counts) to Output.
+package pqtool
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Verbose enables debug messages on Output
+	Verbose bool
+
+	// Output receives progress and debug messages, discarded by default
+	Output io.Writer = ioutil.Discard
+)
+
+// If Verbose is set, print a debug message on Output
+func Debug(format string, a ...interface{}) {
+	if Verbose {
+		fmt.Fprintf(Output, format+"\n", a...)
+	}
+}
+
+// Print a progress message on Output
+func logf(format string, a ...interface{}) {
+	fmt.Fprintf(Output, format+"\n", a...)
+}
+
+// Function to add an item (string) to a list (string) with ,\n\t as delimiter
+func addItem(list string, item string) string {
+	if list != "" {
+		list += ",\n\t"
+	}
+	list += item
+	return list
+}
+
+// Quote an SQL identifier (table or column name)
+func quoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// Parse a size in bytes with an optional K, M, G or T suffix (powers of 1024, e.g. 512M)
+func ParseSize(s string) (int64, error) {
+	multiple := int64(1)
+	number := strings.TrimSuffix(strings.ToUpper(s), "B")
+	if n := len(number); n > 0 {
+		if i := strings.IndexByte("KMGT", number[n-1]); i >= 0 {
+			multiple = int64(1) << (10 * uint(i+1))
+			number = number[:n-1]
+		}
+	}
+	size, err := strconv.ParseFloat(number, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %v", s)
+	}
+	return int64(size * float64(multiple)), nil
+}
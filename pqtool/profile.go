@@ -0,0 +1,659 @@
+package pqtool
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ProfileOptions are the options of Profile
+type ProfileOptions struct {
+	Format     string     // Format of the report: text, json or html, text if empty
+	CSV        bool       // The file is a CSV file with a header line, its types detected from its 2nd line
+	CSVOptions CSVOptions // Options of CSV files (e.g. delimiter)
+	TopK       int        // Number of most frequent values of each column, 10 if 0
+	Bins       int        // Number of bins of the histograms, 10 if 0
+	Exact      bool       // Count all the distinct values exactly, using more memory
+	MaxValues  int        // Number of distinct values counted per column, 100000 if 0. Beyond them, distinct counts are estimated with HyperLogLog and top value counts are lower bounds
+	SampleSize int        // Number of values sampled per column for the quantiles, 100000 if 0
+}
+
+// FileProfile is the profile of the columns of a file
+type FileProfile struct {
+	File    string           `json:"file"`
+	Rows    int64            `json:"rows"`
+	Columns []*ColumnProfile `json:"columns"`
+}
+
+// ColumnProfile is the profile of the values of a column. Values are formatted
+// as text in the type of the column (e.g. 2021-03-17 for dates)
+type ColumnProfile struct {
+	Name              string         `json:"name"`
+	Type              string         `json:"type"`
+	Nulls             int64          `json:"nulls"`
+	NullRatio         float64        `json:"null_ratio"`
+	Invalid           int64          `json:"invalid,omitempty"` // Values of CSV files that don't have the type of the column, not profiled
+	Distinct          int64          `json:"distinct"`
+	DistinctEstimated bool           `json:"distinct_estimated,omitempty"` // Distinct count estimated with HyperLogLog
+	Min               string         `json:"min,omitempty"`
+	Max               string         `json:"max,omitempty"`
+	Mean              *float64       `json:"mean,omitempty"`   // Mean of numbers
+	StdDev            *float64       `json:"stddev,omitempty"` // Sample standard deviation of numbers
+	Quantiles         []Quantile     `json:"quantiles,omitempty"`
+	QuantilesSampled  bool           `json:"quantiles_sampled,omitempty"` // Quantiles of a sample of the values
+	Lengths           *LengthProfile `json:"lengths,omitempty"`           // Lengths of strings in characters
+	Top               []ValueCount   `json:"top,omitempty"`
+	TopEstimated      bool           `json:"top_estimated,omitempty"` // Counts of the top values are lower bounds
+	Histogram         []HistogramBin `json:"histogram,omitempty"`     // Equal-width bins of numbers, dates and timestamps
+}
+
+// Quantile is the value below which a ratio of the values are
+type Quantile struct {
+	Ratio float64 `json:"ratio"`
+	Value string  `json:"value"`
+}
+
+// LengthProfile is the distribution of the lengths of strings
+type LengthProfile struct {
+	Min       int            `json:"min"`
+	Max       int            `json:"max"`
+	Mean      float64        `json:"mean"`
+	Histogram []HistogramBin `json:"histogram"`
+}
+
+// ValueCount is a value and its number of occurrences
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// HistogramBin is the number of values from Low (included) to High (excluded,
+// except in the last bin of floats and decimals)
+type HistogramBin struct {
+	Low   string `json:"low"`
+	High  string `json:"high"`
+	Count int64  `json:"count"`
+}
+
+// Ratios of the quantiles of the profiles
+var profileQuantiles = []float64{0.05, 0.25, 0.5, 0.75, 0.95}
+
+// Statistics of a column gathered while reading the rows
+type columnProfiler struct {
+	field       Field
+	profile     *ColumnProfile
+	opts        ProfileOptions
+	ordered     bool // Numbers, dates and timestamps, with quantiles and histograms
+	numeric     bool // Numbers, with a mean and a standard deviation
+	text        bool // Strings, with lengths
+	min, max    reflect.Value
+	n           int64   // Number of numbers
+	mean, m2    float64 // Mean and sum of the squared differences from the mean of numbers (Welford's algorithm)
+	low, high   float64 // Smallest and largest numbers, dates and timestamps
+	increasing  bool    // Whether numbers, dates and timestamps are strictly increasing in the order of the rows
+	last        float64 // Last number, date or timestamp, to check increasing values
+	counts      map[string]int64
+	overflow    bool // More distinct values than opts.MaxValues
+	hll         *hyperLogLog
+	sample      []reflect.Value // Sample of the values for the quantiles (reservoir sampling)
+	seen        int64           // Number of values offered to the sample
+	random      *rand.Rand
+	lengths     int64 // Sum of the lengths of strings
+	bins        []int64
+	width       float64 // Width of the bins
+	lengthBins  []int64
+	lengthWidth float64 // Width of the bins of lengths
+}
+
+// Return whether a field is a date or a timestamp
+func isTemporal(f Field) bool {
+	return f.Type == "DATE" || f.Type == "TIMESTAMP_MILLIS" || f.Type == "TIMESTAMP_MICROS"
+}
+
+// Return a new profiler of a column
+func newColumnProfiler(f Field, opts ProfileOptions) *columnProfiler {
+	kind := GoType(f.Type).Kind()
+	ordered := isSigned(kind) || isUnsigned(kind) || kind == reflect.Float32 || kind == reflect.Float64
+	return &columnProfiler{
+		field:   f,
+		profile: &ColumnProfile{Name: f.Name, Type: f.TypeName()},
+		opts:    opts,
+		ordered: ordered,
+		numeric: ordered && !isTemporal(f),
+		text:    kind == reflect.String,
+		counts:  make(map[string]int64),
+		hll:     newHyperLogLog(),
+		random:  rand.New(rand.NewSource(1)),
+		low:     math.Inf(1),
+		high:    math.Inf(-1),
+	}
+}
+
+// Return the value of a number as a float, dates and timestamps as their
+// integers, and false for NaN
+func numberValue(v reflect.Value, f Field) (float64, bool) {
+	var x float64
+	switch {
+	case f.Type == "DECIMAL":
+		x = float64(v.Int()) / math.Pow10(f.Scale)
+	case isSigned(v.Kind()):
+		x = float64(v.Int())
+	case isUnsigned(v.Kind()):
+		x = float64(v.Uint())
+	default:
+		x = v.Float()
+	}
+	return x, !math.IsNaN(x)
+}
+
+// Add a value of the first pass: counts, extremes, mean and sample
+func (c *columnProfiler) add(v reflect.Value) {
+	key := formatValue(v, c.field)
+	c.hll.add(key)
+	if _, ok := c.counts[key]; ok || c.opts.Exact || len(c.counts) < c.opts.MaxValues {
+		c.counts[key]++
+	} else {
+		// Misra-Gries: the counts of the values that remain are lower bounds of
+		// their frequencies, and the most frequent values remain
+		c.overflow = true
+		for k := range c.counts {
+			if c.counts[k]--; c.counts[k] == 0 {
+				delete(c.counts, k)
+			}
+		}
+	}
+
+	x, isNumber := 0.0, false
+	if c.ordered {
+		if x, isNumber = numberValue(v, c.field); !isNumber {
+			// NaN has no order
+			return
+		}
+		c.low, c.high = math.Min(c.low, x), math.Max(c.high, x)
+	}
+	if !c.min.IsValid() || compareValues(v, c.min, false) < 0 {
+		c.min = reflect.ValueOf(v.Interface())
+	}
+	if !c.max.IsValid() || compareValues(v, c.max, false) > 0 {
+		c.max = reflect.ValueOf(v.Interface())
+	}
+
+	if c.numeric {
+		c.n++
+		delta := x - c.mean
+		c.mean += delta / float64(c.n)
+		c.m2 += delta * (x - c.mean)
+	}
+	if c.ordered {
+		c.increasing = c.seen == 0 || (c.increasing && x > c.last)
+		c.last = x
+
+		// Reservoir sampling
+		c.seen++
+		if len(c.sample) < c.opts.SampleSize {
+			c.sample = append(c.sample, reflect.ValueOf(v.Interface()))
+		} else if i := c.random.Int63n(c.seen); i < int64(c.opts.SampleSize) {
+			c.sample[i] = reflect.ValueOf(v.Interface())
+		}
+	}
+	if c.text {
+		n := utf8.RuneCountInString(v.String())
+		if c.profile.Lengths == nil {
+			c.profile.Lengths = &LengthProfile{Min: n, Max: n}
+		}
+		if n < c.profile.Lengths.Min {
+			c.profile.Lengths.Min = n
+		}
+		if n > c.profile.Lengths.Max {
+			c.profile.Lengths.Max = n
+		}
+		c.lengths += int64(n)
+	}
+}
+
+// Return the width of the bins of a histogram of values from low to high, and
+// the number of bins. Bins of integers have an integer width, and there are at
+// most bins of them even when float64 rounds integers above 2^53
+func binWidth(low float64, high float64, bins int, integer bool) (float64, int) {
+	if !integer {
+		if high == low {
+			return 0, 1
+		}
+		return (high - low) / float64(bins), bins
+	}
+	width := math.Floor((high-low)/float64(bins)) + 1
+	n := int(math.Floor((high-low)/width)) + 1
+	if n > bins {
+		n = bins
+	}
+	return width, n
+}
+
+// Return the index of the bin of a value in a histogram starting at low
+func binIndex(x float64, low float64, width float64, bins int) int {
+	if width == 0 {
+		return 0
+	}
+	i := int(math.Floor((x - low) / width))
+	if i >= bins {
+		i = bins - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
+
+// Add a value of the second pass to the histograms
+func (c *columnProfiler) addToHistograms(v reflect.Value) {
+	if c.ordered {
+		if x, ok := numberValue(v, c.field); ok {
+			c.bins[binIndex(x, c.low, c.width, len(c.bins))]++
+		}
+	}
+	if c.text {
+		n := float64(utf8.RuneCountInString(v.String()))
+		c.lengthBins[binIndex(n, float64(c.profile.Lengths.Min), c.lengthWidth, len(c.lengthBins))]++
+	}
+}
+
+// Prepare the histograms of the second pass, once the extremes are known
+func (c *columnProfiler) startHistograms() {
+	var n int
+	if c.ordered && c.seen > 0 {
+		kind := GoType(c.field.Type).Kind()
+		integer := (isSigned(kind) || isUnsigned(kind)) && c.field.Type != "DECIMAL"
+		c.width, n = binWidth(c.low, c.high, c.opts.Bins, integer)
+		c.bins = make([]int64, n)
+	}
+	if l := c.profile.Lengths; c.text && l != nil {
+		c.lengthWidth, n = binWidth(float64(l.Min), float64(l.Max), c.opts.Bins, true)
+		c.lengthBins = make([]int64, n)
+	}
+}
+
+// Return the bins of a histogram starting at low, the last bin ending at high
+// if its bins have a float width
+func histogramBins(counts []int64, low float64, high float64, width float64, integer bool, format func(float64) string) []HistogramBin {
+	var bins []HistogramBin
+	for i, count := range counts {
+		start, end := low+float64(i)*width, low+float64(i+1)*width
+		if i == len(counts)-1 && !integer {
+			end = high
+		}
+		bins = append(bins, HistogramBin{format(start), format(end), count})
+	}
+	return bins
+}
+
+// Return a bound of a histogram bin as text, dates and timestamps in UTC
+func formatBound(x float64, f Field) string {
+	switch f.Type {
+	case "DATE":
+		return FromDate(int32(math.Floor(x))).Format("2006-01-02")
+	case "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS":
+		return formatValue(reflect.ValueOf(int64(math.Floor(x))), f)
+	}
+	return strconv.FormatFloat(x, 'g', 6, 64)
+}
+
+// Complete the profile of a column once the rows are read
+func (c *columnProfiler) finish(rows int64) *ColumnProfile {
+	p := c.profile
+	// Invalid values of CSV files are read as nulls
+	p.Nulls -= p.Invalid
+	if rows > 0 {
+		p.NullRatio = float64(p.Nulls) / float64(rows)
+	}
+
+	p.Distinct = int64(len(c.counts))
+	if c.overflow {
+		p.Distinct, p.DistinctEstimated = c.hll.count(), true
+	}
+	var top []ValueCount
+	for value, count := range c.counts {
+		top = append(top, ValueCount{value, count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Value < top[j].Value
+	})
+	if len(top) > c.opts.TopK {
+		top = top[:c.opts.TopK]
+	}
+	p.Top, p.TopEstimated = top, c.overflow
+
+	if c.min.IsValid() {
+		p.Min, p.Max = formatValue(c.min, c.field), formatValue(c.max, c.field)
+	}
+	if c.numeric && c.n > 0 {
+		mean := c.mean
+		p.Mean = &mean
+		if c.n > 1 {
+			stdDev := math.Sqrt(c.m2 / float64(c.n-1))
+			p.StdDev = &stdDev
+		}
+	}
+	if len(c.sample) > 0 {
+		// Nearest-rank quantiles
+		sort.Slice(c.sample, func(i, j int) bool { return compareValues(c.sample[i], c.sample[j], false) < 0 })
+		for _, ratio := range profileQuantiles {
+			i := int(math.Ceil(ratio*float64(len(c.sample)))) - 1
+			if i < 0 {
+				i = 0
+			}
+			p.Quantiles = append(p.Quantiles, Quantile{ratio, formatValue(c.sample[i], c.field)})
+		}
+		p.QuantilesSampled = c.seen > int64(len(c.sample))
+	}
+
+	kind := GoType(c.field.Type).Kind()
+	integer := (isSigned(kind) || isUnsigned(kind)) && c.field.Type != "DECIMAL"
+	p.Histogram = histogramBins(c.bins, c.low, c.high, c.width, integer, func(x float64) string { return formatBound(x, c.field) })
+	if l := p.Lengths; l != nil {
+		l.Mean = float64(c.lengths) / float64(rows-p.Nulls-p.Invalid)
+		l.Histogram = histogramBins(c.lengthBins, float64(l.Min), float64(l.Max), c.lengthWidth, true, func(x float64) string { return strconv.Itoa(int(x)) })
+	}
+	return p
+}
+
+// Profile the columns of a flat parquet file, or of a CSV file, and write the
+// report to w in opts.Format. The rows are read twice, the second time for the
+// histograms
+func Profile(w io.Writer, filename string, opts ProfileOptions) (*FileProfile, error) {
+	if opts.TopK == 0 {
+		opts.TopK = 10
+	}
+	if opts.Bins == 0 {
+		opts.Bins = 10
+	}
+	if opts.MaxValues == 0 {
+		opts.MaxValues = 100000
+	}
+	if opts.SampleSize == 0 {
+		opts.SampleSize = 100000
+	}
+	if opts.TopK < 0 || opts.Bins < 0 || opts.MaxValues < 0 || opts.SampleSize < 0 {
+		return nil, fmt.Errorf("invalid profile options")
+	}
+	switch opts.Format {
+	case "":
+		opts.Format = "text"
+	case "text", "json", "html":
+	default:
+		return nil, fmt.Errorf("unknown report format %v, expected text, json or html", opts.Format)
+	}
+
+	columns, rows, err := profileColumns(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &FileProfile{File: filename, Rows: rows}
+	for _, c := range columns {
+		profile.Columns = append(profile.Columns, c.finish(rows))
+	}
+
+	switch opts.Format {
+	case "json":
+		data, err := json.MarshalIndent(profile, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(w, "%s\n", data)
+	case "html":
+		err = profile.writeHTML(w)
+	default:
+		profile.writeText(w)
+	}
+	return profile, err
+}
+
+// Read the rows of a flat parquet file, or of a CSV file, twice to gather the
+// statistics of its columns, the second time for the histograms, and return
+// the profilers of the columns and the number of rows
+func profileColumns(filename string, opts ProfileOptions) ([]*columnProfiler, int64, error) {
+	source, err := openRowSource(filename, opts.CSV, opts.CSVOptions)
+	if err != nil {
+		if !opts.CSV {
+			err = fmt.Errorf("%v (only flat files can be profiled)", err)
+		}
+		return nil, 0, err
+	}
+	columns := make([]*columnProfiler, len(source.fields))
+	for i, f := range source.fields {
+		columns[i] = newColumnProfiler(f, opts)
+	}
+
+	// Values of a row, nil for nulls
+	values := func(row reflect.Value, each func(c *columnProfiler, v reflect.Value)) {
+		for i, c := range columns {
+			v := row.Field(i)
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					each(c, reflect.Value{})
+					continue
+				}
+				v = v.Elem()
+			}
+			each(c, v)
+		}
+	}
+
+	var rows int64
+	err = source.scan(func(row reflect.Value) {
+		rows++
+		values(row, func(c *columnProfiler, v reflect.Value) {
+			if !v.IsValid() {
+				c.profile.Nulls++
+				return
+			}
+			c.add(v)
+		})
+	}, func(field int) {
+		columns[field].profile.Invalid++
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	Debug("First pass: %v rows", rows)
+
+	for _, c := range columns {
+		c.startHistograms()
+	}
+	err = source.scan(func(row reflect.Value) {
+		values(row, func(c *columnProfiler, v reflect.Value) {
+			if v.IsValid() {
+				c.addToHistograms(v)
+			}
+		})
+	}, func(int) {})
+	if err != nil {
+		return nil, 0, err
+	}
+	return columns, rows, nil
+}
+
+// Return the bars of the counts of a histogram, the largest count having width characters
+func histogramBars(bins []HistogramBin, width int) []string {
+	var max int64
+	for _, b := range bins {
+		if b.Count > max {
+			max = b.Count
+		}
+	}
+	bars := make([]string, len(bins))
+	for i, b := range bins {
+		if max > 0 {
+			bars[i] = strings.Repeat("#", int(math.Round(float64(b.Count)/float64(max)*float64(width))))
+		}
+	}
+	return bars
+}
+
+// Write the histogram of a profile as text
+func writeHistogram(w io.Writer, title string, bins []HistogramBin) {
+	fmt.Fprintf(w, "  %v:\n", title)
+	bars := histogramBars(bins, 40)
+	labels := make([]string, len(bins))
+	size := 0
+	for i, b := range bins {
+		labels[i] = b.Low + " - " + b.High
+		if len(labels[i]) > size {
+			size = len(labels[i])
+		}
+	}
+	for i, b := range bins {
+		fmt.Fprintf(w, "    %-*v %8v %v\n", size, labels[i], b.Count, bars[i])
+	}
+}
+
+// Write the profile as text
+func (p *FileProfile) writeText(w io.Writer) {
+	fmt.Fprintf(w, "File: %v\nRows: %v\n", p.File, p.Rows)
+	for _, c := range p.Columns {
+		fmt.Fprintf(w, "\n%v: %v\n", c.Name, c.Type)
+		fmt.Fprintf(w, "  Nulls:       %v (%.2f%%)\n", c.Nulls, 100*c.NullRatio)
+		if c.Invalid > 0 {
+			fmt.Fprintf(w, "  Invalid:     %v\n", c.Invalid)
+		}
+		if c.DistinctEstimated {
+			fmt.Fprintf(w, "  Distinct:    ~%v (HyperLogLog estimate)\n", c.Distinct)
+		} else {
+			fmt.Fprintf(w, "  Distinct:    %v\n", c.Distinct)
+		}
+		if c.Min != "" || c.Max != "" {
+			fmt.Fprintf(w, "  Min:         %v\n  Max:         %v\n", c.Min, c.Max)
+		}
+		if c.Mean != nil {
+			fmt.Fprintf(w, "  Mean:        %.6g\n", *c.Mean)
+		}
+		if c.StdDev != nil {
+			fmt.Fprintf(w, "  Std dev:     %.6g\n", *c.StdDev)
+		}
+		if len(c.Quantiles) > 0 {
+			var items []string
+			for _, q := range c.Quantiles {
+				items = append(items, fmt.Sprintf("p%v %v", q.Ratio*100, q.Value))
+			}
+			sampled := ""
+			if c.QuantilesSampled {
+				sampled = " (sampled)"
+			}
+			fmt.Fprintf(w, "  Quantiles:   %v%v\n", strings.Join(items, ", "), sampled)
+		}
+		if l := c.Lengths; l != nil {
+			fmt.Fprintf(w, "  Lengths:     min %v, mean %.1f, max %v\n", l.Min, l.Mean, l.Max)
+		}
+		if len(c.Top) > 0 {
+			estimated := ""
+			if c.TopEstimated {
+				estimated = " (lower bounds of the counts)"
+			}
+			fmt.Fprintf(w, "  Top values%v:\n", estimated)
+			for _, v := range c.Top {
+				fmt.Fprintf(w, "    %8v %q\n", v.Count, v.Value)
+			}
+		}
+		if len(c.Histogram) > 0 {
+			writeHistogram(w, "Histogram", c.Histogram)
+		}
+		if c.Lengths != nil && len(c.Lengths.Histogram) > 0 {
+			writeHistogram(w, "Length histogram", c.Lengths.Histogram)
+		}
+	}
+}
+
+// Report of a profile as a self-contained HTML page, histograms drawn with CSS
+var profileTemplate = template.Must(template.New("profile").Funcs(template.FuncMap{
+	"percent": func(x float64) string { return fmt.Sprintf("%.2f%%", 100*x) },
+	"number":  func(x float64) string { return fmt.Sprintf("%.6g", x) },
+	"ratio":   func(x float64) string { return fmt.Sprintf("p%v", x*100) },
+	"bars":    histogramWidths,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Profile of {{.File}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h2 { border-bottom: 1px solid #ccc; padding-bottom: 0.2em; margin-top: 2em; }
+h2 small { color: #777; font-weight: normal; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+td, th { padding: 0.15em 0.8em 0.15em 0; text-align: left; vertical-align: top; }
+th { color: #555; font-weight: normal; }
+.count { text-align: right; }
+.bar { background: #4a7ab5; height: 0.9em; display: inline-block; }
+.columns { display: flex; flex-wrap: wrap; gap: 3em; }
+</style>
+</head>
+<body>
+<h1>Profile of {{.File}}</h1>
+<p>{{.Rows}} rows, {{len .Columns}} columns</p>
+<table>
+<tr><th>Column</th><th>Type</th><th class="count">Nulls</th><th class="count">Distinct</th><th>Min</th><th>Max</th></tr>
+{{range .Columns}}<tr><td><a href="#{{.Name}}">{{.Name}}</a></td><td>{{.Type}}</td><td class="count">{{percent .NullRatio}}</td><td class="count">{{if .DistinctEstimated}}~{{end}}{{.Distinct}}</td><td>{{.Min}}</td><td>{{.Max}}</td></tr>
+{{end}}</table>
+{{range .Columns}}
+<h2 id="{{.Name}}">{{.Name}} <small>{{.Type}}</small></h2>
+<div class="columns">
+<table>
+<tr><th>Nulls</th><td>{{.Nulls}} ({{percent .NullRatio}})</td></tr>
+{{if .Invalid}}<tr><th>Invalid</th><td>{{.Invalid}}</td></tr>
+{{end}}<tr><th>Distinct</th><td>{{if .DistinctEstimated}}~{{.Distinct}} (HyperLogLog estimate){{else}}{{.Distinct}}{{end}}</td></tr>
+{{if or .Min .Max}}<tr><th>Min</th><td>{{.Min}}</td></tr>
+<tr><th>Max</th><td>{{.Max}}</td></tr>
+{{end}}{{with .Mean}}<tr><th>Mean</th><td>{{number .}}</td></tr>
+{{end}}{{with .StdDev}}<tr><th>Std dev</th><td>{{number .}}</td></tr>
+{{end}}{{range .Quantiles}}<tr><th>{{ratio .Ratio}}</th><td>{{.Value}}</td></tr>
+{{end}}{{if .QuantilesSampled}}<tr><th></th><td>Quantiles of a sample</td></tr>
+{{end}}{{with .Lengths}}<tr><th>Lengths</th><td>min {{.Min}}, mean {{number .Mean}}, max {{.Max}}</td></tr>
+{{end}}</table>
+{{if .Top}}<table>
+<tr><th>Top values{{if .TopEstimated}} (lower bounds of the counts){{end}}</th><th class="count">Count</th></tr>
+{{range .Top}}<tr><td>{{.Value}}</td><td class="count">{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Histogram}}<table>
+<tr><th colspan="3">Histogram</th></tr>
+{{range bars .Histogram}}<tr><td>{{.Low}} - {{.High}}</td><td class="count">{{.Count}}</td><td><span class="bar" style="width: {{.Width}}px"></span></td></tr>
+{{end}}</table>
+{{end}}{{with .Lengths}}{{if .Histogram}}<table>
+<tr><th colspan="3">Length histogram</th></tr>
+{{range bars .Histogram}}<tr><td>{{.Low}} - {{.High}}</td><td class="count">{{.Count}}</td><td><span class="bar" style="width: {{.Width}}px"></span></td></tr>
+{{end}}</table>
+{{end}}{{end}}</div>
+{{end}}
+</body>
+</html>
+`))
+
+// Bin of a histogram of the HTML report, with the width of its bar in pixels
+type htmlBin struct {
+	HistogramBin
+	Width int
+}
+
+// Return the bins of a histogram with the widths of their bars, the largest 200 pixels
+func histogramWidths(bins []HistogramBin) []htmlBin {
+	bars := histogramBars(bins, 200)
+	result := make([]htmlBin, len(bins))
+	for i, b := range bins {
+		result[i] = htmlBin{b, len(bars[i])}
+	}
+	return result
+}
+
+// Write the profile as a self-contained HTML page
+func (p *FileProfile) writeHTML(w io.Writer) error {
+	return profileTemplate.Execute(w, p)
+}
@@ -0,0 +1,37 @@
+package pqtool
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// Histograms of integers have at most the number of bins, whatever their range
+func TestProfileIntegerBins(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "input.parquet")
+	fields := []Field{{Name: "i64", Type: "INT64"}, {Name: "u64", Type: "UINT_64"}, {Name: "small", Type: "INT32"}}
+	writeRows(t, filename, fields, [][]interface{}{
+		{int64(math.MinInt64), uint64(0), int32(0)},
+		{int64(9007199254740993), uint64(1 << 63), int32(7)},
+		{int64(math.MaxInt64), uint64(math.MaxUint64), int32(25)},
+	})
+
+	profile, err := Profile(ioutil.Discard, filename, ProfileOptions{Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range profile.Columns {
+		var count int64
+		for _, b := range c.Histogram {
+			count += b.Count
+		}
+		if len(c.Histogram) > 10 || count != 3 {
+			t.Errorf("%v: %v bins of %v values, expected at most 10 bins of 3 values", c.Name, len(c.Histogram), count)
+		}
+	}
+	if bins := len(profile.Columns[2].Histogram); bins != 9 {
+		t.Errorf("%v bins of width 3 from 0 to 25, expected 9", bins)
+	}
+}
@@ -0,0 +1,219 @@
+package pqtool
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/schema"
+	"github.com/xitongsys/parquet-go/source"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reader reads the rows of a flat parquet file into structures built from its schema
+type Reader struct {
+	Fields   []Field      // Columns of the file
+	DataType reflect.Type // Structure of a row
+	file     source.ParquetFile
+	pr       *reader.ParquetReader
+	numRows  int
+}
+
+// Open a flat parquet file and build the structure to read its rows
+func OpenReader(filename string) (*Reader, error) {
+	return openReader(filename, SchemaFields)
+}
+
+// Open a flat parquet file to read its values as stored, to copy them unchanged
+func openStoredReader(filename string) (*Reader, error) {
+	return openReader(filename, storedFields)
+}
+
+// Open a flat parquet file and build the structure to read its rows from the
+// fields of its schema
+func openReader(filename string, schemaFields func(sh *schema.SchemaHandler) ([]Field, error)) (*Reader, error) {
+
+	Debug("Creating NewLocalFileReader")
+	fr, err := local.NewLocalFileReader(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't open parquet file '%v': %v", filename, err)
+	}
+
+	// Read the schema without a structure, then read the rows with the structure built from it
+	pr, err := reader.NewParquetReader(fr, nil, 4)
+	if err != nil {
+		fr.Close()
+		return nil, fmt.Errorf("can't create parquet reader: %v", err)
+	}
+	fields, err := schemaFields(pr.SchemaHandler)
+	pr.ReadStop()
+	if err != nil {
+		fr.Close()
+		return nil, err
+	}
+	dataType, err := StructType(fields)
+	if err != nil {
+		fr.Close()
+		return nil, err
+	}
+
+	pr, err = reader.NewParquetReader(fr, reflect.New(dataType).Interface(), 4)
+	if err != nil {
+		fr.Close()
+		return nil, fmt.Errorf("can't create parquet reader: %v", err)
+	}
+
+	return &Reader{
+		Fields:   fields,
+		DataType: dataType,
+		file:     fr,
+		pr:       pr,
+		numRows:  int(pr.GetNumRows()),
+	}, nil
+}
+
+// Return the number of rows of the file
+func (r *Reader) NumRows() int {
+	return int(r.pr.GetNumRows())
+}
+
+// Read the next rows, at most n, into a slice of structures. The slice is empty at the end of the file
+func (r *Reader) Read(n int) (reflect.Value, error) {
+	if n > r.numRows {
+		n = r.numRows
+	}
+	r.numRows -= n
+
+	// type []rowType
+	sliceType := reflect.SliceOf(r.DataType)
+	// var *[]rowType
+	slicePtr := reflect.New(sliceType)
+	// make([]rowType, n, n)
+	slicePtr.Elem().Set(reflect.MakeSlice(sliceType, n, n))
+
+	if n > 0 {
+		if err := r.pr.Read(slicePtr.Interface()); err != nil {
+			return slicePtr.Elem(), fmt.Errorf("reading parquet file: %v", err)
+		}
+	}
+	return slicePtr.Elem(), nil
+}
+
+// Close the reader and its file
+func (r *Reader) Close() {
+	r.pr.ReadStop()
+	r.file.Close()
+}
+
+// Source of the rows of a flat parquet file or of a CSV file
+type rowSource struct {
+	filename string
+	csv      bool
+	csvOpts  CSVOptions
+	fields   []Field
+	dataType reflect.Type
+}
+
+// Open the source of the rows of a parquet or CSV file, and return its fields.
+// The columns of CSV files are optional, empty values being nulls
+func openRowSource(filename string, csv bool, csvOpts CSVOptions) (*rowSource, error) {
+	s := &rowSource{filename: filename, csv: csv, csvOpts: csvOpts}
+	if !csv {
+		pr, err := OpenReader(filename)
+		if err != nil {
+			return nil, err
+		}
+		pr.Close()
+		s.fields = pr.Fields
+		return s, nil
+	}
+
+	if s.csvOpts.Delimiter == "" {
+		s.csvOpts.Delimiter = ","
+	}
+	fields, err := InferCSVSchema(filename, s.csvOpts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fields {
+		fields[i].Optional = true
+	}
+	if s.dataType, err = StructType(fields); err != nil {
+		return nil, err
+	}
+	s.fields = fields
+	return s, nil
+}
+
+// Return whether a value of a CSV file has the type of its column
+func validCSVValue(x string, f Field) bool {
+	var err error
+	switch f.Type {
+	case "INT64":
+		_, err = strconv.ParseInt(x, 10, 64)
+	case "DOUBLE":
+		_, err = strconv.ParseFloat(x, 64)
+	case "DATE", "TIMESTAMP_MILLIS":
+		_, err = time.Parse(f.Layout, x)
+	}
+	return err == nil
+}
+
+// Read the rows of the file, calling row for each row, and invalid for the
+// values of CSV files that don't have the type of their column
+func (s *rowSource) scan(row func(v reflect.Value), invalid func(field int)) error {
+	if !s.csv {
+		pr, err := OpenReader(s.filename)
+		if err != nil {
+			return err
+		}
+		defer pr.Close()
+		for {
+			rows, err := pr.Read(1000)
+			if err != nil {
+				return err
+			}
+			if rows.Len() == 0 {
+				return nil
+			}
+			for i := 0; i < rows.Len(); i++ {
+				row(rows.Index(i))
+			}
+		}
+	}
+
+	file, err := os.Open(s.filename)
+	if err != nil {
+		return fmt.Errorf("can't open CSV file '%v': %v", s.filename, err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Scan()
+	v := reflect.New(s.dataType).Elem()
+	for line := 2; scanner.Scan(); line++ {
+		data := strings.Split(scanner.Text(), s.csvOpts.Delimiter)
+		if len(data) != len(s.fields) {
+			return fmt.Errorf("line %v has %v fields, expected %v", line, len(data), len(s.fields))
+		}
+		for i, f := range s.fields {
+			v.Field(i).Set(reflect.Zero(v.Field(i).Type()))
+			if data[i] == "" {
+				continue
+			}
+			if !validCSVValue(data[i], f) {
+				invalid(i)
+				continue
+			}
+			setField(v.Field(i), parseValue(data[i], f))
+		}
+		row(v)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading CSV file '%v': %v", s.filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package pqtool
+
+import (
+	"fmt"
+	"github.com/xitongsys/parquet-go/parquet"
+	"reflect"
+	"strings"
+)
+
+// RewriteOptions are the options of Rewrite
+type RewriteOptions struct {
+	Encodings []string    // Encodings of columns as NAME=ENCODING, * for all the columns
+	Sort      SortOptions // Order of the rows, the order of the file if no keys
+	Writer    WriterOptions
+}
+
+// Set the encodings of fields given as NAME=ENCODING (e.g. "id=DELTA_BINARY_PACKED"),
+// * setting the encoding of all the fields
+func SetFieldEncodings(fields []Field, encodings []string) error {
+	for _, e := range encodings {
+		elem := strings.SplitN(e, "=", 2)
+		if len(elem) != 2 {
+			return fmt.Errorf("invalid encoding %v, expected NAME=ENCODING", e)
+		}
+		found := false
+		for i := range fields {
+			if fields[i].Name != elem[0] && elem[0] != "*" {
+				continue
+			}
+			encoding, err := ParseEncoding(elem[1], fields[i].Type)
+			if err != nil {
+				return fmt.Errorf("field %v: %v", fields[i].Name, err)
+			}
+			fields[i].Encoding = encoding
+			found = true
+		}
+		if !found {
+			return fmt.Errorf("no column %v for encoding %v", elem[0], elem[1])
+		}
+	}
+	return nil
+}
+
+// Return the dictionary encoding of the column chunks of a row group, or "" for
+// the columns without dictionary. PLAIN_DICTIONARY comes first, as parquet-go
+// lists both dictionary encodings in its chunks
+func dictionaryEncodings(rowGroup *parquet.RowGroup) []string {
+	encodings := make([]string, len(rowGroup.Columns))
+	for i, column := range rowGroup.Columns {
+		if column.MetaData == nil {
+			continue
+		}
+		for _, e := range column.MetaData.Encodings {
+			if e == parquet.Encoding_PLAIN_DICTIONARY || (e == parquet.Encoding_RLE_DICTIONARY && encodings[i] == "") {
+				encodings[i] = e.String()
+			}
+		}
+	}
+	return encodings
+}
+
+// Rewrite a flat parquet file with another layout: codec, sizes of row groups
+// and pages, version of data pages and encodings. The columns keep their
+// dictionary encoding unless opts.Encodings changes it, and the file keeps its
+// key-value metadata. The rows are sorted if opts.Sort has keys
+func Rewrite(filename string, output string, opts RewriteOptions) error {
+	footer, err := readFooter(filename)
+	if err != nil {
+		return err
+	}
+	pr, err := openStoredReader(filename)
+	if err != nil {
+		return fmt.Errorf("%v (only flat files can be rewritten)", err)
+	}
+	defer pr.Close()
+
+	fields := make([]Field, len(pr.Fields))
+	copy(fields, pr.Fields)
+	if len(footer.RowGroups) > 0 {
+		for i, encoding := range dictionaryEncodings(footer.RowGroups[0]) {
+			if i < len(fields) && encoding != "" && fields[i].Type != "BOOLEAN" {
+				fields[i].Encoding = encoding
+			}
+		}
+	}
+	if err = SetFieldEncodings(fields, opts.Encodings); err != nil {
+		return err
+	}
+
+	logf("Structure:")
+	for _, f := range fields {
+		logf("  %v: %v %v", f.Name, f.TypeName(), f.Encoding)
+	}
+
+	// Rows are read with the structure of the reader, and converted to the
+	// same structure with the tags of the new encodings
+	dataType, err := StructType(fields)
+	if err != nil {
+		return err
+	}
+	pw, err := CreateWriterWithOptions(output, dataType, opts.Writer)
+	if err != nil {
+		return err
+	}
+	for _, kv := range footer.KeyValueMetadata {
+		pw.SetMetadata(kv.Key, kv.GetValue())
+	}
+	w, err := sortedWriter(pw, fields, opts.Sort)
+	if err != nil {
+		pw.Close()
+		return err
+	}
+
+	nRows := 0
+	for {
+		slice, err := pr.Read(1000)
+		if err != nil {
+			w.Close()
+			return err
+		}
+		if slice.Len() == 0 {
+			break
+		}
+		for i := 0; i < slice.Len(); i++ {
+			row := reflect.New(dataType).Elem()
+			row.Set(slice.Index(i).Convert(dataType))
+			if err = w.Write(row); err != nil {
+				w.Close()
+				return err
+			}
+		}
+		nRows += slice.Len()
+	}
+
+	if err = w.Close(); err != nil {
+		return err
+	}
+	logf("Parquet file %v written with %v rows and %v fields", output, nRows, len(fields))
+	return nil
+}
@@ -0,0 +1,110 @@
+package pqtool
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xitongsys/parquet-go/parquet"
+)
+
+// Columns of every type kept as stored when files are copied
+var storedTestFields = []Field{
+	{Name: "id", Type: "INT64"},
+	{Name: "i8", Type: "INT_8"},
+	{Name: "u64", Type: "UINT_64", Optional: true},
+	{Name: "d32", Type: "DECIMAL", BaseType: "INT32", Precision: 9, Scale: 2},
+	{Name: "d64", Type: "DECIMAL", Precision: 18, Scale: 4, Optional: true},
+	{Name: "dfixed", Type: "DECIMAL", BaseType: "FIXED_LEN_BYTE_ARRAY", Length: 8, Precision: 18, Scale: 3},
+	{Name: "dbytes", Type: "DECIMAL", BaseType: "BYTE_ARRAY", Precision: 30, Scale: 5},
+	{Name: "fixed", Type: "FIXED_LEN_BYTE_ARRAY", Length: 4},
+	{Name: "json", Type: "JSON"},
+	{Name: "time", Type: "TIME_MILLIS"},
+	{Name: "name", Type: "UTF8", Optional: true},
+}
+
+// Write a file of storedTestFields with n rows
+func writeStoredTestFile(t *testing.T, filename string, n int) {
+	t.Helper()
+	rows := make([][]interface{}, n)
+	for i := range rows {
+		var name interface{}
+		if i%3 != 0 {
+			name = string(rune('a' + i%26))
+		}
+		rows[i] = []interface{}{
+			int64(i), int8(i % 100), uint64(1<<63) + uint64(i), int32(i * 101), int64(i) * 10001,
+			"\x00\x00\x00\x00\x00\x00\x01" + string(rune(i%128)), "\x01" + string(rune(i%128)),
+			"ab" + string(rune('a'+i%26)) + "d", `{"i":1}`, int32(i * 1000), name,
+		}
+	}
+	writeRows(t, filename, storedTestFields, rows)
+}
+
+// Return the schema elements of the columns of a parquet file
+func schemaElements(t *testing.T, filename string) []*parquet.SchemaElement {
+	t.Helper()
+	footer, err := readFooter(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return footer.Schema[1:]
+}
+
+// Return the rows of a parquet file as stored
+func storedRows(t *testing.T, filename string) []interface{} {
+	t.Helper()
+	pr, err := openStoredReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	var rows []interface{}
+	for {
+		slice, err := pr.Read(100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slice.Len() == 0 {
+			return rows
+		}
+		for i := 0; i < slice.Len(); i++ {
+			var values []interface{}
+			for j := 0; j < slice.Index(i).NumField(); j++ {
+				var x interface{}
+				if v := reflect.Indirect(slice.Index(i).Field(j)); v.IsValid() {
+					x = v.Interface()
+				}
+				values = append(values, x)
+			}
+			rows = append(rows, values)
+		}
+	}
+}
+
+// Check that a file has the schema and rows of another
+func checkSameFile(t *testing.T, filename string, expected string) {
+	t.Helper()
+	if a, b := schemaElements(t, filename), schemaElements(t, expected); !reflect.DeepEqual(a, b) {
+		t.Errorf("schema of %v:\n%v\nexpected:\n%v", filepath.Base(filename), a, b)
+	}
+	if a, b := storedRows(t, filename), storedRows(t, expected); !reflect.DeepEqual(a, b) {
+		t.Errorf("rows of %v differ", filepath.Base(filename))
+	}
+}
+
+// Rewritten files keep their schema and values with another layout
+func TestRewriteKeepsSchema(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "input.parquet"), filepath.Join(dir, "output.parquet")
+	writeStoredTestFile(t, input, 100)
+
+	err := Rewrite(input, output, RewriteOptions{
+		Encodings: []string{"id=DELTA_BINARY_PACKED"},
+		Writer:    WriterOptions{Compression: "GZIP", DataPageVersion: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameFile(t, output, input)
+}
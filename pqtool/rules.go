@@ -0,0 +1,580 @@
+package pqtool
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRulesFailed is returned when data quality rules fail with the fail action
+var ErrRulesFailed = errors.New("data quality rules failed")
+
+// Rules are the data quality expectations of the rows of a file, read from a
+// YAML or JSON rules file
+type Rules struct {
+	Rows    *RowCountRule `json:"rows,omitempty" yaml:"rows,omitempty"` // Bounds of the number of rows
+	Columns []ColumnRules `json:"columns,omitempty" yaml:"columns,omitempty"`
+}
+
+// RowCountRule bounds the number of rows of a file
+type RowCountRule struct {
+	Min *int64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max *int64 `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// ColumnRules are the expectations of the values of a column. Null values
+// pass all the rules but not_null
+type ColumnRules struct {
+	Name    string        `json:"name" yaml:"name"`
+	NotNull bool          `json:"not_null,omitempty" yaml:"not_null,omitempty"`
+	Unique  bool          `json:"unique,omitempty" yaml:"unique,omitempty"` // No value twice, the values being kept in memory
+	In      []interface{} `json:"in,omitempty" yaml:"in,omitempty"`         // Allowed values, as text in the type of the column (e.g. 2021-03-17 for dates)
+	Regex   string        `json:"regex,omitempty" yaml:"regex,omitempty"`   // Regular expression found in the text of the values, anchored with ^ and $ to match whole values
+	Min     interface{}   `json:"min,omitempty" yaml:"min,omitempty"`       // Smallest value allowed: number, date, timestamp or string
+	Max     interface{}   `json:"max,omitempty" yaml:"max,omitempty"`       // Largest value allowed
+	Lookup  *LookupRule   `json:"lookup,omitempty" yaml:"lookup,omitempty"` // Values found in a column of another file
+}
+
+// LookupRule expects the values of a column in a column of a reference file
+type LookupRule struct {
+	File   string `json:"file" yaml:"file"`                         // Flat parquet file, or CSV file with a .csv extension, relative to the rules file
+	Column string `json:"column,omitempty" yaml:"column,omitempty"` // Column of the reference file, the checked column if empty
+}
+
+// CheckOptions are the options of the check of the rows of a file against rules
+type CheckOptions struct {
+	Rules      *Rules
+	OnFailure  string // Action on rows failing rules: fail (default), quarantine or report
+	Quarantine string // File of the failing rows with the quarantine action, with their failed rules in an _errors column. Parquet, or CSV with a .csv extension
+	Output     string // File of the passing rows with the quarantine action of Check, none if empty. Parquet, or CSV with a .csv extension
+	Result     string // JSON file of the result, none if empty
+	Samples    int    // Number of failing rows kept per rule in the result, 5 if 0
+	CSV        bool   // The checked file is a CSV file with a header line, its types detected from its 2nd line (Check)
+	Delimiter  string // Field delimiter of CSV files, comma if empty (Check)
+}
+
+// CheckResult is the result of a check, written as JSON to the result file
+type CheckResult struct {
+	File       string        `json:"file"`
+	Passed     bool          `json:"passed"` // All the rules passed
+	Action     string        `json:"action"`
+	Rows       int64         `json:"rows"`
+	FailedRows int64         `json:"failed_rows"`
+	Quarantine string        `json:"quarantine,omitempty"` // File of the quarantined rows
+	Output     string        `json:"output,omitempty"`     // File of the passing rows
+	Rules      []*RuleResult `json:"rules"`
+}
+
+// RuleResult is the result of a rule
+type RuleResult struct {
+	Column   string   `json:"column,omitempty"`   // Checked column, none for the row count
+	Rule     string   `json:"rule"`               // not_null, unique, in, regex, range, lookup, type (CSV values not of the column type) or row_count
+	Expected string   `json:"expected,omitempty"` // Parameters of the rule (e.g. the regular expression)
+	Failures int64    `json:"failures"`
+	Samples  []string `json:"samples,omitempty"` // First failing rows and their values (e.g. row 12: FR)
+}
+
+// Return the rule and its column as text
+func (r *RuleResult) String() string {
+	text := r.Rule
+	if r.Column != "" {
+		text = r.Column + " " + text
+	}
+	if r.Expected != "" {
+		text += " " + r.Expected
+	}
+	return text
+}
+
+// Read a YAML or JSON (.json extension) rules file, resolving the files of
+// lookups relatively to it
+func LoadRules(filename string) (*Rules, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read rules file %v: %v", filename, err)
+	}
+
+	var rules Rules
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&rules)
+	} else {
+		err = yaml.UnmarshalStrict(data, &rules)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules file %v: %v", filename, err)
+	}
+
+	for i, c := range rules.Columns {
+		if c.Name == "" {
+			return nil, fmt.Errorf("rules file %v: column %v without name", filename, i+1)
+		}
+		if c.Lookup != nil {
+			if c.Lookup.File == "" {
+				return nil, fmt.Errorf("rules file %v: lookup of column %v without file", filename, c.Name)
+			}
+			if !filepath.IsAbs(c.Lookup.File) {
+				c.Lookup.File = filepath.Join(filepath.Dir(filename), c.Lookup.File)
+			}
+		}
+	}
+	return &rules, nil
+}
+
+// Rule checking the values of a column
+type columnCheck struct {
+	result *RuleResult
+	column int
+	pass   func(v reflect.Value) bool // Return whether a value passes, v being invalid for nulls
+}
+
+// Check of the rows of a file against rules
+type checker struct {
+	opts       CheckOptions
+	fields     []Field
+	checks     []*columnCheck
+	types      []*RuleResult // Type rule of each column, created on the first invalid CSV value
+	result     *CheckResult
+	quarantine *outputFile
+}
+
+// Return the bound of a range rule comparable to the values of a field: a
+// number of numeric fields (days of dates, units of timestamps), or a string
+func boundValue(x interface{}, f Field) (interface{}, error) {
+	kind := GoType(f.Type).Kind()
+	text := fmt.Sprint(x)
+	switch {
+	case isTemporal(f):
+		t, ok := x.(time.Time)
+		if !ok {
+			var err error
+			if t, err = parseTime(text); err != nil {
+				return nil, err
+			}
+		}
+		if f.Type == "DATE" {
+			return float64(ToDate(t)), nil
+		}
+		return float64(ToTimestamp(t, f.Type)), nil
+	case isSigned(kind) || isUnsigned(kind) || kind == reflect.Float32 || kind == reflect.Float64:
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %v", text)
+		}
+		return n, nil
+	case kind == reflect.String:
+		return text, nil
+	}
+	return nil, fmt.Errorf("no range of %v values", f.TypeName())
+}
+
+// Return whether a value is within the bounds of a range rule, nil bounds being open
+func inRange(v reflect.Value, f Field, min interface{}, max interface{}) bool {
+	if v.Kind() == reflect.String {
+		return (min == nil || v.String() >= min.(string)) && (max == nil || v.String() <= max.(string))
+	}
+	x, ok := numberValue(v, f)
+	return ok && (min == nil || x >= min.(float64)) && (max == nil || x <= max.(float64))
+}
+
+// Return the text of the values of a column of a reference file
+func lookupValues(lookup *LookupRule, column string) (map[string]bool, error) {
+	if lookup.Column != "" {
+		column = lookup.Column
+	}
+	csv := strings.ToLower(filepath.Ext(lookup.File)) == ".csv"
+	source, err := openRowSource(lookup.File, csv, CSVOptions{})
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	for i, f := range source.fields {
+		if f.Name == column {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("no column %v in lookup file %v", column, lookup.File)
+	}
+
+	values := make(map[string]bool)
+	err = source.scan(func(row reflect.Value) {
+		if v := reflect.Indirect(row.Field(index)); v.IsValid() {
+			values[formatValue(v, source.fields[index])] = true
+		}
+	}, func(int) {})
+	Debug("Lookup file %v: %v values of column %v", lookup.File, len(values), column)
+	return values, err
+}
+
+// Return the checks of the rules of a column
+func columnChecks(rules ColumnRules, index int, f Field) ([]*columnCheck, error) {
+	var checks []*columnCheck
+	add := func(rule string, expected string, pass func(v reflect.Value) bool) {
+		checks = append(checks, &columnCheck{
+			result: &RuleResult{Column: f.Name, Rule: rule, Expected: expected},
+			column: index,
+			pass:   pass,
+		})
+	}
+
+	if rules.NotNull {
+		add("not_null", "", func(v reflect.Value) bool { return v.IsValid() })
+	}
+	if rules.Unique {
+		seen := make(map[string]bool)
+		add("unique", "", func(v reflect.Value) bool {
+			if !v.IsValid() {
+				return true
+			}
+			text := formatValue(v, f)
+			if seen[text] {
+				return false
+			}
+			seen[text] = true
+			return true
+		})
+	}
+	if len(rules.In) > 0 {
+		allowed := make(map[string]bool)
+		var items []string
+		for _, x := range rules.In {
+			allowed[fmt.Sprint(x)] = true
+			items = append(items, fmt.Sprint(x))
+		}
+		add("in", "["+strings.Join(items, ", ")+"]", func(v reflect.Value) bool {
+			return !v.IsValid() || allowed[formatValue(v, f)]
+		})
+	}
+	if rules.Regex != "" {
+		re, err := regexp.Compile(rules.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex of column %v: %v", f.Name, err)
+		}
+		add("regex", rules.Regex, func(v reflect.Value) bool {
+			return !v.IsValid() || re.MatchString(formatValue(v, f))
+		})
+	}
+	if rules.Min != nil || rules.Max != nil {
+		var min, max interface{}
+		var err error
+		var bounds []string
+		if rules.Min != nil {
+			if min, err = boundValue(rules.Min, f); err != nil {
+				return nil, fmt.Errorf("invalid min of column %v: %v", f.Name, err)
+			}
+			bounds = append(bounds, fmt.Sprintf(">= %v", rules.Min))
+		}
+		if rules.Max != nil {
+			if max, err = boundValue(rules.Max, f); err != nil {
+				return nil, fmt.Errorf("invalid max of column %v: %v", f.Name, err)
+			}
+			bounds = append(bounds, fmt.Sprintf("<= %v", rules.Max))
+		}
+		add("range", strings.Join(bounds, " and "), func(v reflect.Value) bool {
+			return !v.IsValid() || inRange(v, f, min, max)
+		})
+	}
+	if rules.Lookup != nil {
+		values, err := lookupValues(rules.Lookup, f.Name)
+		if err != nil {
+			return nil, fmt.Errorf("lookup of column %v: %v", f.Name, err)
+		}
+		column := rules.Lookup.Column
+		if column == "" {
+			column = f.Name
+		}
+		add("lookup", rules.Lookup.File+":"+column, func(v reflect.Value) bool {
+			return !v.IsValid() || values[formatValue(v, f)]
+		})
+	}
+	return checks, nil
+}
+
+// Return the check of the rows of a file with fields against the rules of opts
+func newChecker(filename string, fields []Field, opts CheckOptions) (*checker, error) {
+	switch opts.OnFailure {
+	case "":
+		opts.OnFailure = "fail"
+	case "fail", "report", "quarantine":
+	default:
+		return nil, fmt.Errorf("unknown action %v, expected fail, quarantine or report", opts.OnFailure)
+	}
+	if opts.OnFailure == "quarantine" && opts.Quarantine == "" {
+		return nil, fmt.Errorf("the quarantine action needs a quarantine file")
+	}
+	if opts.OnFailure != "quarantine" && opts.Quarantine != "" {
+		return nil, fmt.Errorf("the quarantine file needs the quarantine action")
+	}
+	if opts.Samples == 0 {
+		opts.Samples = 5
+	}
+	if opts.Rules == nil {
+		opts.Rules = &Rules{}
+	}
+
+	c := &checker{
+		opts:   opts,
+		fields: fields,
+		types:  make([]*RuleResult, len(fields)),
+		result: &CheckResult{File: filename, Action: opts.OnFailure, Rules: []*RuleResult{}},
+	}
+	for _, rules := range opts.Rules.Columns {
+		index := -1
+		for i, f := range fields {
+			if f.Name == rules.Name {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("no column %v in %v", rules.Name, filename)
+		}
+		checks, err := columnChecks(rules, index, fields[index])
+		if err != nil {
+			return nil, err
+		}
+		c.checks = append(c.checks, checks...)
+		for _, check := range checks {
+			c.result.Rules = append(c.result.Rules, check.result)
+		}
+	}
+
+	if opts.OnFailure == "quarantine" {
+		quarantineFields := []Field{{Name: "_errors", Type: "UTF8"}}
+		for _, f := range fields {
+			f.Optional, f.Encoding = true, ""
+			quarantineFields = append(quarantineFields, f)
+		}
+		var err error
+		if c.quarantine, err = createOutputFile(opts.Quarantine, quarantineFields); err != nil {
+			return nil, err
+		}
+		c.result.Quarantine = opts.Quarantine
+	}
+	return c, nil
+}
+
+// Record a failure of a rule on a row and the value
+func (c *checker) fail(r *RuleResult, row int64, value string) {
+	r.Failures++
+	if len(r.Samples) < c.opts.Samples {
+		r.Samples = append(r.Samples, fmt.Sprintf("row %v: %v", row, value))
+	}
+}
+
+// Check the values of a row (1st row 1), invalid values being nulls, and
+// invalid the columns of the CSV values that don't have their type. Return
+// the failed rules as column and rule (e.g. age range)
+func (c *checker) check(values []reflect.Value, invalid []int, row int64) []string {
+	var failures []string
+	for _, i := range invalid {
+		if c.types[i] == nil {
+			c.types[i] = &RuleResult{Column: c.fields[i].Name, Rule: "type", Expected: c.fields[i].TypeName()}
+			c.result.Rules = append(c.result.Rules, c.types[i])
+		}
+		c.fail(c.types[i], row, "invalid value")
+		failures = append(failures, c.fields[i].Name+" type")
+	}
+	for _, check := range c.checks {
+		v := values[check.column]
+		if check.pass(v) {
+			continue
+		}
+		value := "null"
+		if v.IsValid() {
+			value = formatValue(v, c.fields[check.column])
+		}
+		c.fail(check.result, row, value)
+		failures = append(failures, check.result.Column+" "+check.result.Rule)
+	}
+	c.result.Rows++
+	if len(failures) > 0 {
+		c.result.FailedRows++
+	}
+	return failures
+}
+
+// Write the values of a failing row and its failures to the quarantine file
+func (c *checker) quarantineRow(values []reflect.Value, failures []string) error {
+	v := reflect.New(c.quarantine.DataType).Elem()
+	setField(v.Field(0), strings.Join(failures, "; "))
+	for i, x := range values {
+		if x.IsValid() {
+			setField(v.Field(i+1), x)
+		}
+	}
+	return c.quarantine.Write(v)
+}
+
+// Return the values of a row, invalid for nulls
+func rowValues(row reflect.Value, values []reflect.Value) []reflect.Value {
+	values = values[:0]
+	for i := 0; i < row.NumField(); i++ {
+		values = append(values, reflect.Indirect(row.Field(i)))
+	}
+	return values
+}
+
+// Check the row count, close the quarantine file, write the report to w and
+// the result file
+func (c *checker) finish(w io.Writer) error {
+	if c.quarantine != nil {
+		err := c.quarantine.Close()
+		c.quarantine = nil
+		if err != nil {
+			return err
+		}
+	}
+
+	result := c.result
+	if r := c.opts.Rules.Rows; r != nil {
+		var bounds []string
+		if r.Min != nil {
+			bounds = append(bounds, fmt.Sprintf(">= %v", *r.Min))
+		}
+		if r.Max != nil {
+			bounds = append(bounds, fmt.Sprintf("<= %v", *r.Max))
+		}
+		rowCount := &RuleResult{Rule: "row_count", Expected: strings.Join(bounds, " and ")}
+		if (r.Min != nil && result.Rows < *r.Min) || (r.Max != nil && result.Rows > *r.Max) {
+			rowCount.Failures = 1
+			rowCount.Samples = []string{fmt.Sprintf("%v rows", result.Rows)}
+		}
+		result.Rules = append(result.Rules, rowCount)
+	}
+	result.Passed = true
+	for _, r := range result.Rules {
+		if r.Failures > 0 {
+			result.Passed = false
+		}
+	}
+
+	if c.opts.Result != "" {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(c.opts.Result, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("can't write result file: %v", err)
+		}
+	}
+	result.writeText(w)
+	return nil
+}
+
+// Close the quarantine file and remove it with the output file, after an error
+func (c *checker) abort() {
+	if c.quarantine != nil {
+		c.quarantine.Close()
+		c.quarantine = nil
+	}
+	for _, filename := range []string{c.result.Quarantine, c.result.Output} {
+		if filename != "" {
+			os.Remove(filename)
+		}
+	}
+}
+
+// Write the result as text
+func (r *CheckResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Rules of %v: %v rows, %v failing rows\n", r.File, r.Rows, r.FailedRows)
+	for _, rule := range r.Rules {
+		if rule.Failures == 0 {
+			fmt.Fprintf(w, "  %v: passed\n", rule)
+			continue
+		}
+		fmt.Fprintf(w, "  %v: %v failures (%v)\n", rule, rule.Failures, strings.Join(rule.Samples, ", "))
+	}
+	switch {
+	case r.Passed:
+		fmt.Fprintf(w, "Rules passed\n")
+	case r.Quarantine != "":
+		fmt.Fprintf(w, "Rules failed, %v rows quarantined to %v\n", r.FailedRows, r.Quarantine)
+	default:
+		fmt.Fprintf(w, "Rules failed\n")
+	}
+}
+
+// Check the rows of a flat parquet file, or of a CSV file (empty values being
+// nulls), against opts.Rules and write the report to w. With the quarantine
+// action, the failing rows are written to opts.Quarantine and the others to
+// opts.Output. Return ErrRulesFailed with the result if rules failed with
+// the fail action
+func Check(w io.Writer, filename string, opts CheckOptions) (*CheckResult, error) {
+	if opts.Output != "" && opts.OnFailure != "quarantine" {
+		return nil, fmt.Errorf("the output file needs the quarantine action")
+	}
+	source, err := openRowSource(filename, opts.CSV, CSVOptions{Delimiter: opts.Delimiter})
+	if err != nil {
+		if !opts.CSV {
+			err = fmt.Errorf("%v (only flat files can be checked)", err)
+		}
+		return nil, err
+	}
+	c, err := newChecker(filename, source.fields, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var output *outputFile
+	if opts.Output != "" {
+		if output, err = createOutputFile(opts.Output, source.fields); err != nil {
+			c.abort()
+			return nil, err
+		}
+		c.result.Output = opts.Output
+	}
+
+	var values []reflect.Value
+	var invalid []int
+	var writeErr error
+	err = source.scan(func(row reflect.Value) {
+		if writeErr != nil {
+			return
+		}
+		values = rowValues(row, values)
+		failures := c.check(values, invalid, c.result.Rows+1)
+		invalid = invalid[:0]
+		switch {
+		case len(failures) > 0 && c.quarantine != nil:
+			writeErr = c.quarantineRow(values, failures)
+		case output != nil:
+			writeErr = output.Write(row)
+		}
+	}, func(field int) {
+		invalid = append(invalid, field)
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if output != nil {
+		if closeErr := output.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		c.abort()
+		return nil, err
+	}
+
+	if err = c.finish(w); err != nil {
+		c.abort()
+		return nil, err
+	}
+	if !c.result.Passed && c.result.Action == "fail" {
+		return c.result, ErrRulesFailed
+	}
+	return c.result, nil
+}
@@ -0,0 +1,42 @@
+package pqtool
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Failed checks remove their quarantine and output files
+func TestCheckErrorRemovesOutputs(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "events.csv")
+	if err := ioutil.WriteFile(input, []byte("id,age\n1,30\n2,200\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := CheckOptions{
+		Rules:      &Rules{Columns: []ColumnRules{{Name: "age", Max: 150}}},
+		OnFailure:  "quarantine",
+		Quarantine: filepath.Join(dir, "bad.csv"),
+		Output:     filepath.Join(dir, "good.parquet"),
+		Result:     filepath.Join(dir, "missing", "result.json"),
+		CSV:        true,
+	}
+	if _, err := Check(ioutil.Discard, input, opts); err == nil {
+		t.Fatal("check without error")
+	}
+	for _, filename := range []string{opts.Quarantine, opts.Output} {
+		if _, err := os.Stat(filename); !os.IsNotExist(err) {
+			t.Errorf("%v not removed", filepath.Base(filename))
+		}
+	}
+
+	opts.Result = ""
+	result, err := Check(ioutil.Discard, input, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rows != 2 || result.FailedRows != 1 {
+		t.Errorf("%v rows, %v failing rows, expected 2 and 1", result.Rows, result.FailedRows)
+	}
+}
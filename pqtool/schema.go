@@ -0,0 +1,352 @@
+package pqtool
+
+import (
+	"fmt"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/schema"
+	"github.com/xitongsys/parquet-go/tool/parquet-tools/schematool"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Field describes a flat parquet column
+type Field struct {
+	Name     string `json:"name" yaml:"name"`                             // Column name in the parquet file
+	Type     string `json:"type" yaml:"type"`                             // Parquet type as written in struct tags (e.g. INT32, UTF8, TIMESTAMP_MILLIS)
+	Optional bool   `json:"optional,omitempty" yaml:"optional,omitempty"` // OPTIONAL column, stored as a pointer with nil as null
+	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"` // Encoding of the column (e.g. PLAIN_DICTIONARY), default if empty
+	Layout   string `json:"layout,omitempty" yaml:"layout,omitempty"`     // Time layout of dates and timestamps stored as text (e.g. in CSV files)
+
+	Precision int `json:"precision,omitempty" yaml:"precision,omitempty"` // Number of digits of DECIMAL columns, at most 18
+	Scale     int `json:"scale,omitempty" yaml:"scale,omitempty"`         // Number of digits after the decimal point of DECIMAL columns
+
+	BaseType string `json:"-" yaml:"-"` // Physical type of DECIMAL columns copied from files (INT32, BYTE_ARRAY or FIXED_LEN_BYTE_ARRAY), INT64 if empty
+	Length   int    `json:"-" yaml:"-"` // Length of FIXED_LEN_BYTE_ARRAY columns copied from files
+}
+
+// Go types storing the values of the parquet types of flat columns
+var goTypes = map[string]reflect.Type{
+	"BOOLEAN":          reflect.TypeOf(false),
+	"INT32":            reflect.TypeOf(int32(0)),
+	"INT64":            reflect.TypeOf(int64(0)),
+	"INT_8":            reflect.TypeOf(int8(0)),
+	"INT_16":           reflect.TypeOf(int16(0)),
+	"INT_32":           reflect.TypeOf(int32(0)),
+	"INT_64":           reflect.TypeOf(int64(0)),
+	"UINT_8":           reflect.TypeOf(uint8(0)),
+	"UINT_16":          reflect.TypeOf(uint16(0)),
+	"UINT_32":          reflect.TypeOf(uint32(0)),
+	"UINT_64":          reflect.TypeOf(uint64(0)),
+	"FLOAT":            reflect.TypeOf(float32(0)),
+	"DOUBLE":           reflect.TypeOf(float64(0)),
+	"BYTE_ARRAY":       reflect.TypeOf(string("")),
+	"UTF8":             reflect.TypeOf(string("")),
+	"DATE":             reflect.TypeOf(int32(0)),
+	"TIMESTAMP_MILLIS": reflect.TypeOf(int64(0)),
+	"TIMESTAMP_MICROS": reflect.TypeOf(int64(0)),
+	"DECIMAL":          reflect.TypeOf(int64(0)), // Unscaled value, stored in INT64
+
+	// Types only copied from files
+	"INT96":                reflect.TypeOf(string("")),
+	"FIXED_LEN_BYTE_ARRAY": reflect.TypeOf(string("")),
+	"JSON":                 reflect.TypeOf(string("")),
+	"BSON":                 reflect.TypeOf(string("")),
+	"INTERVAL":             reflect.TypeOf(string("")),
+	"TIME_MILLIS":          reflect.TypeOf(int32(0)),
+	"TIME_MICROS":          reflect.TypeOf(int64(0)),
+}
+
+// Return the Go type storing the values of a parquet type, nil if not supported
+func GoType(parquetType string) reflect.Type {
+	return goTypes[parquetType]
+}
+
+// Map a type name given on the command line (e.g. INT, INT8, UINT16, FLOAT32, VARCHAR, TIMESTAMP) to a parquet type
+func ParseType(name string) (string, error) {
+	switch strings.ToUpper(name) {
+	case "INT8", "INT_8", "TINYINT":
+		return "INT_8", nil
+	case "INT16", "INT_16", "SMALLINT":
+		return "INT_16", nil
+	case "INT_32":
+		return "INT_32", nil
+	case "INT_64", "BIGINT":
+		return "INT_64", nil
+	case "UINT8", "UINT_8":
+		return "UINT_8", nil
+	case "UINT16", "UINT_16":
+		return "UINT_16", nil
+	case "UINT32", "UINT_32":
+		return "UINT_32", nil
+	case "UINT64", "UINT_64":
+		return "UINT_64", nil
+	case "BOOL", "BOOLEAN":
+		return "BOOLEAN", nil
+	case "INT", "INT32":
+		return "INT32", nil
+	case "INT64":
+		return "INT64", nil
+	case "FLOAT", "FLOAT32":
+		return "FLOAT", nil
+	case "DOUBLE", "FLOAT64":
+		return "DOUBLE", nil
+	case "VARCHAR", "UTF", "UTF8":
+		return "UTF8", nil
+	case "BYTE_ARRAY":
+		return "BYTE_ARRAY", nil
+	case "DATE":
+		return "DATE", nil
+	case "TIMESTAMP", "TIMESTAMP_MILLIS":
+		return "TIMESTAMP_MILLIS", nil
+	case "TIMESTAMP_MICROS":
+		return "TIMESTAMP_MICROS", nil
+	}
+	return "", fmt.Errorf("invalid type %v", name)
+}
+
+// Return the encoding of a column of a parquet type from its name (e.g. plain,
+// DELTA_BINARY_PACKED), checking that parquet-go can write it for this type
+func ParseEncoding(name string, parquetType string) (string, error) {
+	kind := reflect.Invalid
+	if t := GoType(parquetType); t != nil {
+		kind = t.Kind()
+	}
+
+	encoding := strings.ToUpper(name)
+	supported := false
+	switch encoding {
+	case "PLAIN":
+		supported = true
+	case "PLAIN_DICTIONARY", "RLE_DICTIONARY":
+		supported = kind != reflect.Bool
+	case "DELTA_BINARY_PACKED":
+		switch kind {
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			supported = true
+		}
+	case "DELTA_BYTE_ARRAY", "DELTA_LENGTH_BYTE_ARRAY":
+		supported = kind == reflect.String
+	default:
+		return "", fmt.Errorf("invalid encoding %v", name)
+	}
+	if !supported {
+		return "", fmt.Errorf("encoding %v not supported for type %v", encoding, parquetType)
+	}
+	return encoding, nil
+}
+
+// Return the type of a field as shown to users, with the precision and scale of decimals (e.g. DECIMAL(10,2))
+func (f Field) TypeName() string {
+	if f.Type == "DECIMAL" {
+		return fmt.Sprintf("DECIMAL(%v,%v)", f.Precision, f.Scale)
+	}
+	return f.Type
+}
+
+// Return the Go type storing the values of a field, nil if not supported
+func (f Field) goType() reflect.Type {
+	if f.Type == "DECIMAL" && f.BaseType != "" {
+		return GoType(f.BaseType)
+	}
+	return GoType(f.Type)
+}
+
+// Return the parquet struct tag of a field
+func (f Field) Tag() string {
+	tag := fmt.Sprintf("name=%v, type=%v", f.Name, f.Type)
+	if f.Type == "DECIMAL" {
+		baseType := f.BaseType
+		if baseType == "" {
+			baseType = "INT64"
+		}
+		tag += fmt.Sprintf(", basetype=%v, precision=%v, scale=%v", baseType, f.Precision, f.Scale)
+	}
+	if f.Length > 0 {
+		tag += fmt.Sprintf(", length=%v", f.Length)
+	}
+	if f.Encoding != "" {
+		tag += ", encoding=" + f.Encoding
+	}
+	if f.Optional {
+		tag += ", repetitiontype=OPTIONAL"
+	}
+	return fmt.Sprintf(`parquet:"%v"`, tag)
+}
+
+// Return the Go name of the field at index i of a structure, unique among the names already used.
+// Go names only need to be unique, the parquet name is in the tag
+func FieldName(name string, i int, used map[string]bool) string {
+	goName := common.StringToVariableName(name)
+	if goName == "" || used[goName] {
+		goName = fmt.Sprintf("Field%v_%v", i, goName)
+	}
+	used[goName] = true
+	return goName
+}
+
+// Build the reflect structure of a row of fields, optional fields being pointers
+func StructType(fields []Field) (reflect.Type, error) {
+	structFields := make([]reflect.StructField, len(fields))
+	names := make(map[string]bool)
+	for i, f := range fields {
+		fieldType := f.goType()
+		if fieldType == nil {
+			return nil, fmt.Errorf("invalid type for field %v: %v", f.Name, f.Type)
+		}
+		if f.Optional {
+			fieldType = reflect.PtrTo(fieldType)
+		}
+
+		structFields[i] = reflect.StructField{
+			Name: FieldName(f.Name, i, names),
+			Type: fieldType,
+			Tag:  reflect.StructTag(f.Tag()),
+		}
+	}
+	return reflect.StructOf(structFields), nil
+}
+
+// Map a parquet type and converted type (as named by schematool) to the parquet type of a flat column
+func mapParquetType(field_type string, field_type2 string) string {
+
+	switch strings.ToUpper(field_type2) {
+	case "INT_8", "INT_16", "INT_32", "INT_64", "UINT_8", "UINT_16", "UINT_32", "UINT_64",
+		"UTF8", "DATE", "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS":
+		return strings.ToUpper(field_type2)
+	}
+
+	switch strings.ToUpper(field_type) {
+	case "BOOLEAN", "INT32", "INT64", "FLOAT", "DOUBLE", "BYTE_ARRAY":
+		return strings.ToUpper(field_type)
+	}
+
+	return ""
+}
+
+// Return the flat columns of a parquet schema
+func SchemaFields(sh *schema.SchemaHandler) ([]Field, error) {
+
+	tree := schematool.CreateSchemaTree(sh.SchemaElements)
+
+	fields := make([]Field, len(tree.Root.Children))
+	for i, node := range tree.Root.Children {
+		// A reader renames the schema to Go names, the external name is the one in the file
+		name := sh.Infos[i+1].ExName
+		if node.SE.GetNumChildren() > 0 {
+			return nil, fmt.Errorf("nested field %v is not supported", name)
+		}
+
+		field_type, field_type2 := schematool.ParquetTypeToParquetTypeStr(node.SE.Type, node.SE.ConvertedType)
+		fields[i] = Field{
+			Name:     name,
+			Type:     mapParquetType(field_type, field_type2),
+			Optional: node.SE.GetRepetitionType() == parquet.FieldRepetitionType_OPTIONAL,
+		}
+		// Decimals stored in INT64 are read with their scale, other decimals as their unscaled values
+		if field_type2 == "DECIMAL" && field_type == "INT64" {
+			fields[i].Type = "DECIMAL"
+			fields[i].Precision = int(node.SE.GetPrecision())
+			fields[i].Scale = int(node.SE.GetScale())
+		}
+		if fields[i].Type == "" {
+			return nil, fmt.Errorf("invalid type for field %v: %v %v", name, field_type, field_type2)
+		}
+	}
+
+	return fields, nil
+}
+
+// Return the flat columns of a parquet schema as stored, to copy their values
+// unchanged: decimals of any physical type, and the converted types that
+// SchemaFields reads as their physical type (e.g. JSON, TIME_MILLIS)
+func storedFields(sh *schema.SchemaHandler) ([]Field, error) {
+
+	tree := schematool.CreateSchemaTree(sh.SchemaElements)
+
+	fields := make([]Field, len(tree.Root.Children))
+	for i, node := range tree.Root.Children {
+		se := node.SE
+		name := sh.Infos[i+1].ExName
+		if se.GetNumChildren() > 0 {
+			return nil, fmt.Errorf("nested field %v is not supported", name)
+		}
+
+		f := Field{
+			Name:     name,
+			Type:     se.GetType().String(),
+			Optional: se.GetRepetitionType() == parquet.FieldRepetitionType_OPTIONAL,
+		}
+		if se.GetType() == parquet.Type_FIXED_LEN_BYTE_ARRAY {
+			f.Length = int(se.GetTypeLength())
+		}
+		if se.IsSetConvertedType() {
+			switch convertedType := se.GetConvertedType(); convertedType {
+			case parquet.ConvertedType_DECIMAL:
+				f.Type, f.BaseType = "DECIMAL", f.Type
+				f.Precision, f.Scale = int(se.GetPrecision()), int(se.GetScale())
+			case parquet.ConvertedType_ENUM, parquet.ConvertedType_TIME_MILLIS, parquet.ConvertedType_TIME_MICROS,
+				parquet.ConvertedType_JSON, parquet.ConvertedType_BSON, parquet.ConvertedType_INTERVAL,
+				parquet.ConvertedType_UTF8, parquet.ConvertedType_DATE,
+				parquet.ConvertedType_TIMESTAMP_MILLIS, parquet.ConvertedType_TIMESTAMP_MICROS,
+				parquet.ConvertedType_INT_8, parquet.ConvertedType_INT_16, parquet.ConvertedType_INT_32, parquet.ConvertedType_INT_64,
+				parquet.ConvertedType_UINT_8, parquet.ConvertedType_UINT_16, parquet.ConvertedType_UINT_32, parquet.ConvertedType_UINT_64:
+				f.Type = convertedType.String()
+			default:
+				return nil, fmt.Errorf("invalid type for field %v: %v %v", name, se.GetType(), convertedType)
+			}
+		}
+		if f.goType() == nil {
+			return nil, fmt.Errorf("type %v of field %v can't be written", f.Type, name)
+		}
+		fields[i] = f
+	}
+
+	return fields, nil
+}
+
+// Return the days since Unix epoch of a time, as stored in DATE columns
+func ToDate(t time.Time) int32 {
+	days := t.Unix() / 60 / 60 / 24
+	if t.Unix() < 0 && t.Unix()%(60*60*24) != 0 {
+		days--
+	}
+	return int32(days)
+}
+
+// Return the time of a DATE value
+func FromDate(days int32) time.Time {
+	return time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(days))
+}
+
+// Return a time as stored in TIMESTAMP_MILLIS or TIMESTAMP_MICROS columns
+func ToTimestamp(t time.Time, parquetType string) int64 {
+	if parquetType == "TIMESTAMP_MICROS" {
+		return t.Unix()*1000000 + int64(t.Nanosecond())/1000
+	}
+	return t.Unix()*1000 + int64(t.Nanosecond())/1000000
+}
+
+// Return the time of a TIMESTAMP_MILLIS or TIMESTAMP_MICROS value
+func FromTimestamp(x int64, parquetType string) time.Time {
+	if parquetType == "TIMESTAMP_MICROS" {
+		return time.Unix(x/1000000, x%1000000*int64(time.Microsecond)).UTC()
+	}
+	return time.Unix(x/1000, x%1000*int64(time.Millisecond)).UTC()
+}
+
+// Set a value (or a reflect value) in a field of a row, allocating the pointer of optional fields
+func setField(field reflect.Value, x interface{}) {
+	v, ok := x.(reflect.Value)
+	if !ok {
+		v = reflect.ValueOf(x)
+	}
+	if field.Kind() == reflect.Ptr {
+		p := reflect.New(field.Type().Elem())
+		p.Elem().Set(v.Convert(field.Type().Elem()))
+		field.Set(p)
+	} else {
+		field.Set(v.Convert(field.Type()))
+	}
+}
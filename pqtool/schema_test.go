@@ -0,0 +1,88 @@
+package pqtool
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Integer columns of every converted type, read back with their own types
+func TestIntegerTypesRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "integers.parquet")
+	fields := []Field{
+		{Name: "i8", Type: "INT_8"},
+		{Name: "i16", Type: "INT_16"},
+		{Name: "i32", Type: "INT_32"},
+		{Name: "i64", Type: "INT_64"},
+		{Name: "u8", Type: "UINT_8"},
+		{Name: "u16", Type: "UINT_16"},
+		{Name: "u32", Type: "UINT_32"},
+		{Name: "u64", Type: "UINT_64", Optional: true},
+	}
+	rows := [][]interface{}{
+		{int8(math.MinInt8), int16(math.MinInt16), int32(math.MinInt32), int64(math.MinInt64), uint8(0), uint16(0), uint32(0), uint64(0)},
+		{int8(math.MaxInt8), int16(math.MaxInt16), int32(math.MaxInt32), int64(math.MaxInt64), uint8(math.MaxUint8), uint16(math.MaxUint16), uint32(math.MaxUint32), uint64(math.MaxUint64)},
+		{int8(-1), int16(-1), int32(-1), int64(-1), uint8(1), uint16(1), uint32(1), uint64(1) << 63},
+	}
+	writeRows(t, filename, fields, rows)
+
+	pr, err := OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	if !reflect.DeepEqual(pr.Fields, fields) {
+		t.Errorf("fields %v, expected %v", pr.Fields, fields)
+	}
+	read, err := pr.Read(len(rows))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range rows {
+		for j, x := range row {
+			if v := reflect.Indirect(read.Index(i).Field(j)).Interface(); v != x {
+				t.Errorf("row %v, column %v: %v, expected %v", i, fields[j].Name, v, x)
+			}
+		}
+	}
+
+	csvFilename := filepath.Join(dir, "integers.csv")
+	if err = ParquetToCSV(filename, csvFilename, ExportCSVOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(csvFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if expected := "127,32767,2147483647,9223372036854775807,255,65535,4294967295,18446744073709551615"; lines[1] != expected {
+		t.Errorf("CSV line %v, expected %v", lines[1], expected)
+	}
+}
+
+// Write rows of values to a parquet file of fields
+func writeRows(t *testing.T, filename string, fields []Field, rows [][]interface{}) {
+	t.Helper()
+	pw, err := CreateFieldsWriter(filename, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		v := pw.NewRow()
+		for j, x := range row {
+			if x != nil {
+				setField(v.Field(j), x)
+			}
+		}
+		if err = pw.Write(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
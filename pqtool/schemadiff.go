@@ -0,0 +1,293 @@
+package pqtool
+
+import (
+	"fmt"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/tool/parquet-tools/schematool"
+	"io"
+	"strings"
+)
+
+// Compatibility of the schemas of parquet files. Backward compatible schemas
+// can read the files of the previous schema, forward compatible schemas have
+// files the previous schema can read
+type Compatibility int
+
+const (
+	Identical          Compatibility = iota // Same schemas
+	FullyCompatible                         // Backward and forward compatible
+	BackwardCompatible                      // Backward compatible only
+	ForwardCompatible                       // Forward compatible only
+	Incompatible                            // Neither backward nor forward compatible
+)
+
+// Return the name of a compatibility
+func (c Compatibility) String() string {
+	switch c {
+	case Identical:
+		return "identical"
+	case FullyCompatible:
+		return "backward and forward compatible"
+	case BackwardCompatible:
+		return "backward compatible"
+	case ForwardCompatible:
+		return "forward compatible"
+	}
+	return "incompatible"
+}
+
+// Return the compatibility of backward and forward compatible changes
+func compatibility(backward bool, forward bool) Compatibility {
+	switch {
+	case backward && forward:
+		return FullyCompatible
+	case backward:
+		return BackwardCompatible
+	case forward:
+		return ForwardCompatible
+	}
+	return Incompatible
+}
+
+// Return the compatibility of two sets of changes made one after the other
+func (c Compatibility) and(other Compatibility) Compatibility {
+	if c == Identical {
+		return other
+	}
+	if other == Identical {
+		return c
+	}
+	backward := (c == FullyCompatible || c == BackwardCompatible) && (other == FullyCompatible || other == BackwardCompatible)
+	forward := (c == FullyCompatible || c == ForwardCompatible) && (other == FullyCompatible || other == ForwardCompatible)
+	return compatibility(backward, forward)
+}
+
+// SchemaChange is a difference between the schemas of two parquet files
+type SchemaChange struct {
+	Path          string        // Path of the column (e.g. user.address.city)
+	Kind          string        // added, removed, type, logical type, nullability or order
+	Old           string        // Column in the old schema, empty if added
+	New           string        // Column in the new schema, empty if removed
+	Compatibility Compatibility // Compatibility of the change
+}
+
+// Return the repetition of a schema element: required, optional or repeated
+func repetition(se *parquet.SchemaElement) string {
+	switch se.GetRepetitionType() {
+	case parquet.FieldRepetitionType_OPTIONAL:
+		return "optional"
+	case parquet.FieldRepetitionType_REPEATED:
+		return "repeated"
+	}
+	return "required"
+}
+
+// Return the physical type of a schema element (e.g. INT64, FIXED_LEN_BYTE_ARRAY(16)), group if it has children
+func physicalType(node *schematool.Node) string {
+	if len(node.Children) > 0 || node.SE.Type == nil {
+		return "group"
+	}
+	physical, _ := schematool.ParquetTypeToParquetTypeStr(node.SE.Type, nil)
+	if node.SE.GetType() == parquet.Type_FIXED_LEN_BYTE_ARRAY {
+		physical += fmt.Sprintf("(%v)", node.SE.GetTypeLength())
+	}
+	return physical
+}
+
+// Return the logical type of a schema element (e.g. UTF8, DECIMAL(10,2)), empty if none
+func logicalType(se *parquet.SchemaElement) string {
+	_, converted := schematool.ParquetTypeToParquetTypeStr(nil, se.ConvertedType)
+	if converted == "DECIMAL" {
+		converted = fmt.Sprintf("DECIMAL(%v,%v)", se.GetPrecision(), se.GetScale())
+	}
+	return converted
+}
+
+// Return the description of a column (e.g. optional INT32 DATE)
+func describeNode(node *schematool.Node) string {
+	return strings.TrimSpace(fmt.Sprintf("%v %v %v", repetition(node.SE), physicalType(node), logicalType(node.SE)))
+}
+
+// Return the family and the rank of a numeric type, a type of higher rank in
+// the same family holding all the values of a type of lower rank (e.g. INT_8
+// < INT_16 < INT32 < INT64), and false if the type isn't numeric
+func numericRank(node *schematool.Node) (string, int, bool) {
+	switch physicalType(node) + " " + logicalType(node.SE) {
+	case "INT32 INT_8":
+		return "int", 1, true
+	case "INT32 INT_16":
+		return "int", 2, true
+	case "INT32 ", "INT32 INT_32":
+		return "int", 3, true
+	case "INT64 ", "INT64 INT_64":
+		return "int", 4, true
+	case "INT32 UINT_8":
+		return "uint", 1, true
+	case "INT32 UINT_16":
+		return "uint", 2, true
+	case "INT32 UINT_32":
+		return "uint", 3, true
+	case "INT64 UINT_64":
+		return "uint", 4, true
+	case "FLOAT ":
+		return "float", 1, true
+	case "DOUBLE ":
+		return "float", 2, true
+	}
+	// Decimals of the same scale, ranked by precision
+	if node.SE.ConvertedType != nil && node.SE.GetConvertedType() == parquet.ConvertedType_DECIMAL {
+		return fmt.Sprintf("decimal %v", node.SE.GetScale()), int(node.SE.GetPrecision()), true
+	}
+	return "", 0, false
+}
+
+// Return the compatibility of the change of type of a primitive column: widened
+// types are backward compatible, narrowed types forward compatible
+func typeCompatibility(before *schematool.Node, after *schematool.Node) Compatibility {
+	oldFamily, oldRank, oldNumeric := numericRank(before)
+	newFamily, newRank, newNumeric := numericRank(after)
+	if !oldNumeric || !newNumeric || oldFamily != newFamily {
+		return Incompatible
+	}
+	return compatibility(newRank >= oldRank, newRank <= oldRank)
+}
+
+// Return the changes of the children of a group of an old schema in a new schema,
+// matched by name
+func compareNodes(before *schematool.Node, after *schematool.Node, path string) []SchemaChange {
+	var changes []SchemaChange
+	childPath := func(node *schematool.Node) string {
+		if path == "" {
+			return node.SE.Name
+		}
+		return path + "." + node.SE.Name
+	}
+
+	newChildren := make(map[string]*schematool.Node)
+	for _, child := range after.Children {
+		newChildren[child.SE.Name] = child
+	}
+	var oldOrder, newOrder []string
+	for _, child := range before.Children {
+		newChild, ok := newChildren[child.SE.Name]
+		if !ok {
+			// Files of the new schema have no value for a removed required column
+			changes = append(changes, SchemaChange{childPath(child), "removed", describeNode(child), "",
+				compatibility(true, child.SE.GetRepetitionType() != parquet.FieldRepetitionType_REQUIRED)})
+			continue
+		}
+		oldOrder = append(oldOrder, child.SE.Name)
+		changes = append(changes, compareNode(child, newChild, childPath(child))...)
+	}
+
+	oldChildren := make(map[string]bool)
+	for _, child := range before.Children {
+		oldChildren[child.SE.Name] = true
+	}
+	for _, child := range after.Children {
+		if oldChildren[child.SE.Name] {
+			newOrder = append(newOrder, child.SE.Name)
+			continue
+		}
+		// Files of the old schema have no value for an added required column
+		changes = append(changes, SchemaChange{childPath(child), "added", "", describeNode(child),
+			compatibility(child.SE.GetRepetitionType() != parquet.FieldRepetitionType_REQUIRED, true)})
+	}
+
+	if strings.Join(oldOrder, ",") != strings.Join(newOrder, ",") {
+		name := path
+		if name == "" {
+			name = "(root)"
+		}
+		changes = append(changes, SchemaChange{name, "order", strings.Join(oldOrder, ", "), strings.Join(newOrder, ", "), FullyCompatible})
+	}
+	return changes
+}
+
+// Return the changes of a column of an old schema in a new schema
+func compareNode(before *schematool.Node, after *schematool.Node, path string) []SchemaChange {
+	var changes []SchemaChange
+
+	oldRepetition, newRepetition := repetition(before.SE), repetition(after.SE)
+	if oldRepetition != newRepetition {
+		c := Incompatible
+		switch {
+		case oldRepetition == "required" && newRepetition == "optional":
+			c = BackwardCompatible
+		case oldRepetition == "optional" && newRepetition == "required":
+			c = ForwardCompatible
+		}
+		changes = append(changes, SchemaChange{path, "nullability", oldRepetition, newRepetition, c})
+	}
+
+	oldPhysical, newPhysical := physicalType(before), physicalType(after)
+	oldLogical, newLogical := logicalType(before.SE), logicalType(after.SE)
+	switch {
+	case (oldPhysical == "group") != (newPhysical == "group"):
+		changes = append(changes, SchemaChange{path, "type", describeNode(before), describeNode(after), Incompatible})
+		return changes
+	case oldPhysical == "group":
+		if oldLogical != newLogical {
+			changes = append(changes, SchemaChange{path, "logical type", oldLogical, newLogical, Incompatible})
+		}
+		return append(changes, compareNodes(before, after, path)...)
+	case oldPhysical != newPhysical:
+		changes = append(changes, SchemaChange{path, "type",
+			strings.TrimSpace(oldPhysical + " " + oldLogical), strings.TrimSpace(newPhysical + " " + newLogical),
+			typeCompatibility(before, after)})
+	case oldLogical != newLogical:
+		changes = append(changes, SchemaChange{path, "logical type", oldLogical, newLogical, typeCompatibility(before, after)})
+	}
+	return changes
+}
+
+// Return the changes of the columns of an old schema in a new schema, and the
+// compatibility of the new schema
+func CompareSchemas(before *schematool.SchemaTree, after *schematool.SchemaTree) ([]SchemaChange, Compatibility) {
+	changes := compareNodes(before.Root, after.Root, "")
+	c := Identical
+	for _, change := range changes {
+		c = c.and(change.Compatibility)
+	}
+	return changes, c
+}
+
+// Write the changes of the schemas of parquet files compared to the schema of
+// the first file, and return the compatibility of all the schemas with the
+// schema of the first file
+func SchemaDiff(w io.Writer, filenames []string) (Compatibility, error) {
+	if len(filenames) < 2 {
+		return Incompatible, fmt.Errorf("schema diff needs at least two files")
+	}
+	base, err := readFooter(filenames[0])
+	if err != nil {
+		return Incompatible, err
+	}
+	baseTree := schematool.CreateSchemaTree(base.Schema)
+
+	result := Identical
+	for _, filename := range filenames[1:] {
+		footer, err := readFooter(filename)
+		if err != nil {
+			return Incompatible, err
+		}
+		changes, c := CompareSchemas(baseTree, schematool.CreateSchemaTree(footer.Schema))
+		result = result.and(c)
+
+		fmt.Fprintf(w, "%v -> %v: %v\n", filenames[0], filename, c)
+		for _, change := range changes {
+			description := change.Old + " -> " + change.New
+			switch change.Kind {
+			case "added":
+				description = change.New
+			case "removed":
+				description = change.Old
+			}
+			fmt.Fprintf(w, "  %-12v %v: %v (%v)\n", change.Kind, change.Path, description, change.Compatibility)
+		}
+	}
+	if len(filenames) > 2 {
+		fmt.Fprintf(w, "All files: %v\n", result)
+	}
+	return result, nil
+}
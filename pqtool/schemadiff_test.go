@@ -0,0 +1,177 @@
+package pqtool
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/xitongsys/parquet-go/tool/parquet-tools/schematool"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Columns of the schema the other schemas are compared to
+var baseSchemaFields = []Field{
+	{Name: "id", Type: "INT_32"},
+	{Name: "score", Type: "FLOAT"},
+	{Name: "name", Type: "UTF8", Optional: true},
+	{Name: "amount", Type: "DECIMAL", Precision: 10, Scale: 2},
+}
+
+// Return the base columns with the column named name replaced by f, removed if
+// f has no name, and f appended if there's no such column
+func changeField(name string, f Field) []Field {
+	var fields []Field
+	found := false
+	for _, base := range baseSchemaFields {
+		if base.Name != name {
+			fields = append(fields, base)
+			continue
+		}
+		found = true
+		if f.Name != "" {
+			fields = append(fields, f)
+		}
+	}
+	if !found {
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// Write an empty parquet file of fields and return its name
+func writeSchemaFile(t *testing.T, dir string, name string, fields []Field) string {
+	t.Helper()
+	filename := filepath.Join(dir, name+".parquet")
+	writeRows(t, filename, fields, nil)
+	return filename
+}
+
+// Return the schema tree of a parquet file
+func schemaTree(t *testing.T, filename string) *schematool.SchemaTree {
+	t.Helper()
+	footer, err := readFooter(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schematool.CreateSchemaTree(footer.Schema)
+}
+
+func TestCompareSchemas(t *testing.T) {
+	reordered := []Field{baseSchemaFields[1], baseSchemaFields[0], baseSchemaFields[2], baseSchemaFields[3]}
+	tests := []struct {
+		name          string
+		fields        []Field
+		kind          string
+		compatibility Compatibility
+	}{
+		{"identical", baseSchemaFields, "", Identical},
+		{"widened int", changeField("id", Field{Name: "id", Type: "INT64"}), "type", BackwardCompatible},
+		{"narrowed int", changeField("id", Field{Name: "id", Type: "INT_16"}), "logical type", ForwardCompatible},
+		{"widened float", changeField("score", Field{Name: "score", Type: "DOUBLE"}), "type", BackwardCompatible},
+		{"widened decimal", changeField("amount", Field{Name: "amount", Type: "DECIMAL", Precision: 12, Scale: 2}), "logical type", BackwardCompatible},
+		{"narrowed decimal", changeField("amount", Field{Name: "amount", Type: "DECIMAL", Precision: 8, Scale: 2}), "logical type", ForwardCompatible},
+		{"decimal scale", changeField("amount", Field{Name: "amount", Type: "DECIMAL", Precision: 10, Scale: 3}), "logical type", Incompatible},
+		{"int to unsigned", changeField("id", Field{Name: "id", Type: "UINT_32"}), "logical type", Incompatible},
+		{"int to string", changeField("id", Field{Name: "id", Type: "UTF8"}), "type", Incompatible},
+		{"required to optional", changeField("id", Field{Name: "id", Type: "INT_32", Optional: true}), "nullability", BackwardCompatible},
+		{"optional to required", changeField("name", Field{Name: "name", Type: "UTF8"}), "nullability", ForwardCompatible},
+		{"added optional", changeField("email", Field{Name: "email", Type: "UTF8", Optional: true}), "added", FullyCompatible},
+		{"added required", changeField("email", Field{Name: "email", Type: "UTF8"}), "added", ForwardCompatible},
+		{"removed optional", changeField("name", Field{}), "removed", FullyCompatible},
+		{"removed required", changeField("id", Field{}), "removed", BackwardCompatible},
+		{"reordered", reordered, "order", FullyCompatible},
+	}
+
+	dir := t.TempDir()
+	base := schemaTree(t, writeSchemaFile(t, dir, "base", baseSchemaFields))
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			after := schemaTree(t, writeSchemaFile(t, dir, fmt.Sprint(i), test.fields))
+			changes, c := CompareSchemas(base, after)
+			if c != test.compatibility {
+				t.Errorf("%v, expected %v", c, test.compatibility)
+			}
+			if test.kind == "" {
+				if len(changes) != 0 {
+					t.Errorf("changes %+v, expected none", changes)
+				}
+				return
+			}
+			if len(changes) != 1 || changes[0].Kind != test.kind || changes[0].Compatibility != test.compatibility {
+				t.Errorf("changes %+v, expected a change of %v %v", changes, test.kind, test.compatibility)
+			}
+		})
+	}
+}
+
+func TestCompatibilityAnd(t *testing.T) {
+	tests := []struct {
+		a, b     Compatibility
+		expected Compatibility
+	}{
+		{Identical, Identical, Identical},
+		{Identical, ForwardCompatible, ForwardCompatible},
+		{BackwardCompatible, Identical, BackwardCompatible},
+		{FullyCompatible, FullyCompatible, FullyCompatible},
+		{FullyCompatible, BackwardCompatible, BackwardCompatible},
+		{ForwardCompatible, FullyCompatible, ForwardCompatible},
+		{BackwardCompatible, BackwardCompatible, BackwardCompatible},
+		{BackwardCompatible, ForwardCompatible, Incompatible},
+		{Incompatible, Identical, Incompatible},
+		{FullyCompatible, Incompatible, Incompatible},
+	}
+	for _, test := range tests {
+		if c := test.a.and(test.b); c != test.expected {
+			t.Errorf("%v and %v: %v, expected %v", test.a, test.b, c, test.expected)
+		}
+		if c := test.b.and(test.a); c != test.expected {
+			t.Errorf("%v and %v: %v, expected %v", test.b, test.a, c, test.expected)
+		}
+	}
+}
+
+// Several changes to a column or several files combine their compatibilities
+func TestSchemaDiffFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := writeSchemaFile(t, dir, "base", baseSchemaFields)
+	widened := writeSchemaFile(t, dir, "widened", changeField("id", Field{Name: "id", Type: "INT64"}))
+	added := writeSchemaFile(t, dir, "added", changeField("email", Field{Name: "email", Type: "UTF8", Optional: true}))
+	nullable := writeSchemaFile(t, dir, "nullable", changeField("score", Field{Name: "score", Type: "DOUBLE", Optional: true}))
+
+	// Widened and nullable: backward compatible changes of the same column
+	changes, c := CompareSchemas(schemaTree(t, base), schemaTree(t, nullable))
+	if len(changes) != 2 || c != BackwardCompatible {
+		t.Errorf("changes %+v: %v, expected 2 backward compatible changes", changes, c)
+	}
+
+	tests := []struct {
+		filenames []string
+		expected  Compatibility
+	}{
+		{[]string{base, base}, Identical},
+		{[]string{base, added}, FullyCompatible},
+		{[]string{base, widened, added}, BackwardCompatible},
+		{[]string{base, added, nullable}, BackwardCompatible},
+		{[]string{widened, base}, ForwardCompatible},
+		{[]string{widened, base, added}, ForwardCompatible},
+		{[]string{base, widened, nullable, base}, BackwardCompatible},
+		{[]string{widened, base, nullable}, Incompatible},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		c, err := SchemaDiff(&b, test.filenames)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != test.expected {
+			t.Errorf("%v: %v, expected %v\n%v", test.filenames, c, test.expected, b.String())
+		}
+		if len(test.filenames) > 2 && !strings.HasSuffix(b.String(), fmt.Sprintf("All files: %v\n", test.expected)) {
+			t.Errorf("%v: output %q, expected the compatibility of all files", test.filenames, b.String())
+		}
+	}
+
+	if _, err := SchemaDiff(&bytes.Buffer{}, []string{base}); err == nil {
+		t.Errorf("schema diff of one file, expected an error")
+	}
+}
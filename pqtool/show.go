@@ -0,0 +1,70 @@
+package pqtool
+
+// Ref: https://github.com/xitongsys/parquet-go/blob/master/example/convert_to_json.go,
+// https://github.com/xitongsys/parquet-go/blob/4c59bed5d5a62d392c5cbfb53482dbc6686ff238/tool/parquet-tools/parquet-tools.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/tool/parquet-tools/schematool"
+	"github.com/xitongsys/parquet-go/tool/parquet-tools/sizetool"
+	"io"
+)
+
+// Print the schema, row count, size and content of a parquet file
+func Show(w io.Writer, parquet_filename string) error {
+
+	fr, err := local.NewLocalFileReader(parquet_filename)
+	if err != nil {
+		return fmt.Errorf("can't open file %v: %v", parquet_filename, err)
+	}
+	defer fr.Close()
+
+	pr, err := reader.NewParquetReader(fr, nil, 4)
+	if err != nil {
+		return fmt.Errorf("can't create parquet reader: %v", err)
+	}
+	defer pr.ReadStop()
+
+	// Schema
+	withTags := true
+	tree := schematool.CreateSchemaTree(pr.SchemaHandler.SchemaElements)
+	fmt.Fprintln(w, "----- Go struct -----")
+	fmt.Fprintf(w, "%s\n", tree.OutputStruct(withTags))
+	fmt.Fprintln(w, "----- Json schema -----")
+	fmt.Fprintf(w, "%s\n", tree.OutputJsonSchema())
+
+	// Rows count
+	num := int(pr.GetNumRows())
+	fmt.Fprintln(w, "----- Rows -----")
+	fmt.Fprintf(w, "%v\n", pr.GetNumRows())
+	fmt.Fprintln(w)
+
+	// File size
+	withPrettySize := true
+	sizeCompressed := sizetool.GetParquetFileSize(parquet_filename, pr, withPrettySize, false)
+	sizeUncompressed := sizetool.GetParquetFileSize(parquet_filename, pr, withPrettySize, true)
+	fmt.Fprintln(w, "----- Size -----")
+	fmt.Fprintf(w, "Compressed: %v\n", sizeCompressed)
+	fmt.Fprintf(w, "Uncompressed: %v\n", sizeUncompressed)
+	fmt.Fprintln(w)
+
+	// JSON content
+	res, err := pr.ReadByNumber(num)
+	if err != nil {
+		return fmt.Errorf("can't read: %v", err)
+	}
+
+	jsonBs, err := json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("can't to json: %v", err)
+	}
+
+	fmt.Fprintln(w, "Content:")
+	fmt.Fprintf(w, "%s\n", jsonBs)
+	fmt.Fprintln(w)
+
+	return nil
+}
@@ -0,0 +1,820 @@
+package pqtool
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"hash/fnv"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// String of characters to pick from when creating random strings
+const characters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Column of simulated data and the generator of its values
+type Column struct {
+	Field `yaml:",inline"`
+
+	Seed          *int64    `json:"seed,omitempty" yaml:"seed,omitempty"`                     // Seed of the column's values, derived from the simulation seed if nil
+	Generator     string    `json:"generator,omitempty" yaml:"generator,omitempty"`           // uniform, normal, exponential, zipf, sequence, increasing, random, pattern, uuid or enum
+	Min           *Number   `json:"min,omitempty" yaml:"min,omitempty"`                       // Minimum of numbers (unscaled values of decimals), start of number sequences, minimum of the type if nil
+	Max           *Number   `json:"max,omitempty" yaml:"max,omitempty"`                       // Maximum of numbers, maximum of the type if nil
+	Mean          float64   `json:"mean,omitempty" yaml:"mean,omitempty"`                     // Mean of the normal distribution
+	StdDev        float64   `json:"stddev,omitempty" yaml:"stddev,omitempty"`                 // Standard deviation of the normal distribution, 1 if 0
+	Rate          float64   `json:"rate,omitempty" yaml:"rate,omitempty"`                     // Rate of the exponential distribution, 1 if 0
+	Exponent      float64   `json:"exponent,omitempty" yaml:"exponent,omitempty"`             // Exponent (> 1) of the zipf distribution, 1.1 if 0
+	Step          float64   `json:"step,omitempty" yaml:"step,omitempty"`                     // Step of sequences (days for dates, seconds for timestamps), maximum gap of increasing integers, 1 if 0
+	MinLength     int       `json:"min_length,omitempty" yaml:"min_length,omitempty"`         // Minimum length of random strings, minimum number of items of lists and maps
+	MaxLength     int       `json:"max_length,omitempty" yaml:"max_length,omitempty"`         // Maximum length of random strings, maximum number of items of lists and maps
+	LengthWeights []float64 `json:"length_weights,omitempty" yaml:"length_weights,omitempty"` // Weights of the lengths of random strings (or numbers of items) from min_length
+	Pattern       string    `json:"pattern,omitempty" yaml:"pattern,omitempty"`               // Pattern of strings: # digit, ? letter, * letter or digit
+	Values        []string  `json:"values,omitempty" yaml:"values,omitempty"`                 // Values of enums
+	Weights       []float64 `json:"weights,omitempty" yaml:"weights,omitempty"`               // Weights of the enum values, uniform if empty
+	Cardinality   int       `json:"cardinality,omitempty" yaml:"cardinality,omitempty"`       // Number of distinct values, unlimited if 0
+	From          string    `json:"from,omitempty" yaml:"from,omitempty"`                     // First date or timestamp, Jan 1st 2021 if empty
+	To            string    `json:"to,omitempty" yaml:"to,omitempty"`                         // Last date or timestamp (excluded), Jan 1st 2023 if empty
+	NullRatio     float64   `json:"null_ratio,omitempty" yaml:"null_ratio,omitempty"`         // Probability of a null, making the column OPTIONAL if > 0
+	References    string    `json:"references,omitempty" yaml:"references,omitempty"`         // Referenced column TABLE.COLUMN, whose values are used as foreign keys
+	Fanout        string    `json:"fanout,omitempty" yaml:"fanout,omitempty"`                 // Distribution of the rows among the referenced values: uniform (default) or zipf
+	OrphanRatio   float64   `json:"orphan_ratio,omitempty" yaml:"orphan_ratio,omitempty"`     // Probability of a foreign key not in the referenced column
+	Items         *Column   `json:"items,omitempty" yaml:"items,omitempty"`                   // Items of LIST columns
+	Key           *Column   `json:"key,omitempty" yaml:"key,omitempty"`                       // Keys of MAP columns
+	Value         *Column   `json:"value,omitempty" yaml:"value,omitempty"`                   // Values of MAP columns
+	Fields        []Column  `json:"fields,omitempty" yaml:"fields,omitempty"`                 // Fields of STRUCT columns
+}
+
+// Number is a bound of the values of a column. Integers are kept exactly,
+// beyond the 2^53 of float64, and written as integers in spec files (e.g.
+// -2083423991 rather than -2.083423991e+09)
+type Number struct {
+	float   float64     // Value of the number
+	integer interface{} // Exact value of integers as an int64, or an uint64 above math.MaxInt64, nil for other numbers
+}
+
+// Return a number of a float64, an integer if it is one
+func FloatNumber(x float64) *Number {
+	n := &Number{float: x}
+	switch {
+	case x != math.Trunc(x):
+	case x >= math.MinInt64 && x < math.MaxInt64:
+		n.integer = int64(x)
+	case x >= 0 && x < math.MaxUint64:
+		n.integer = uint64(x)
+	}
+	return n
+}
+
+// Return a number of an int64
+func IntNumber(x int64) *Number {
+	return &Number{float: float64(x), integer: x}
+}
+
+// Return a number of an uint64
+func UintNumber(x uint64) *Number {
+	if x <= math.MaxInt64 {
+		return IntNumber(int64(x))
+	}
+	return &Number{float: float64(x), integer: x}
+}
+
+// Return a number from its text, an integer if it is one
+func parseNumber(text string) (*Number, error) {
+	if x, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return IntNumber(x), nil
+	}
+	if x, err := strconv.ParseUint(text, 10, 64); err == nil {
+		return UintNumber(x), nil
+	}
+	x, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %v", text)
+	}
+	return FloatNumber(x), nil
+}
+
+// Return the value of a number as a float64, rounded above 2^53
+func (n Number) Float() float64 {
+	return n.float
+}
+
+// Return the bits of a number as a value of an integer type, as intBits
+// returns them, and whether it is in the range of the type. Numbers that
+// aren't integers are rounded
+func (n Number) intBits(parquetType string) (uint64, bool) {
+	b := intBounds[parquetType]
+	switch x := n.integer.(type) {
+	case int64:
+		if parquetType == "UINT_64" {
+			return uint64(x), x >= 0
+		}
+		return uint64(x), float64(x) >= b.min && float64(x) <= b.max
+	case uint64:
+		return x, parquetType == "UINT_64"
+	}
+	return intBits(toInteger(n.float, parquetType)), n.float >= b.min && n.float <= b.max
+}
+
+// Return a number as an int64 or an uint64 if it is an integer, a float64 otherwise
+func (n Number) value() interface{} {
+	if n.integer != nil {
+		return n.integer
+	}
+	return n.float
+}
+
+// Return the text of a number
+func (n Number) String() string {
+	return fmt.Sprint(n.value())
+}
+
+// Return the value of a number encoded in YAML
+func (n Number) MarshalYAML() (interface{}, error) {
+	return n.value(), nil
+}
+
+// Set a number from its YAML value
+func (n *Number) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	switch x := v.(type) {
+	case int:
+		*n = *IntNumber(int64(x))
+	case int64:
+		*n = *IntNumber(x)
+	case uint64:
+		*n = *UintNumber(x)
+	case float64:
+		*n = *FloatNumber(x)
+	default:
+		return fmt.Errorf("invalid number %v", v)
+	}
+	return nil
+}
+
+// Return the JSON encoding of a number
+func (n Number) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.value())
+}
+
+// Set a number from its JSON encoding
+func (n *Number) UnmarshalJSON(data []byte) error {
+	x, err := parseNumber(string(data))
+	if err != nil {
+		return err
+	}
+	*n = *x
+	return nil
+}
+
+// Spec declares the columns of a simulation, or its tables for related files, read from a YAML or JSON file
+type Spec struct {
+	Seed    *int64   `json:"seed,omitempty" yaml:"seed,omitempty"` // Seed of the simulation, a random seed if nil
+	Rows    int      `json:"rows,omitempty" yaml:"rows,omitempty"` // Number of rows
+	Columns []Column `json:"columns,omitempty" yaml:"columns,omitempty"`
+	Tables  []Table  `json:"tables,omitempty" yaml:"tables,omitempty"`
+}
+
+// SimulateOptions are the options of Simulate
+type SimulateOptions struct {
+	Seed       *int64  // Seed of the simulation, a random seed if nil
+	NullRatio  float64 // Probability of a null in columns without their own null ratio
+	Files      int     // Number of files the rows are split into, 1 if 0
+	Workers    int     // Number of files written in parallel, number of CPUs if 0
+	TargetSize int64   // Total size of the files in bytes, used instead of the number of rows if > 0
+	CSV        bool    // Also write the rows of each file to a CSV file with the same name and a .csv extension
+	Writer     WriterOptions
+}
+
+// Number of rows of the sample file estimating the size of a row
+const sampleRows = 10000
+
+// Simulation of the columns of a table, whose rows can be split into files
+// generated in parallel. Each file has its own random sources, derived from
+// the seeds of the columns and the index of the file, so that files are the
+// same whatever the number of workers
+type simulation struct {
+	nRows    int                // Total number of rows
+	columns  []Column           // Columns, with their null ratio
+	dataType reflect.Type       // Structure of the rows
+	names    []string           // Names of the columns, qualified by the table name
+	seeds    []int64            // Seeds of the columns
+	pools    [][]interface{}    // Values of the columns with a cardinality, shared by all files
+	keys     map[string]*keySet // Values of referenced columns and foreign keys
+	writer   WriterOptions      // Layout of the files
+	csv      bool               // Also write the rows to CSV files
+	first    *sources           // Sources of the first file, which built the pools
+}
+
+// Random sources of the values and nulls of the columns of a file
+type sources struct {
+	values []func(i int) interface{}
+	nulls  []*rand.Rand
+}
+
+// Return the seed of a column derived from the simulation seed and the column
+// name, so that adding or removing a column doesn't change the other columns
+func columnSeed(seed int64, name string) int64 {
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, seed)
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// Set the parquet type and default encoding of a column from the type name given by the user
+func (c *Column) setType(name string) error {
+	if nested := strings.ToUpper(name); isNested(nested) {
+		if c.Encoding != "" {
+			return fmt.Errorf("field %v: encoding of a %v, set it on its items, key, value or fields", c.Name, nested)
+		}
+		c.Type = nested
+		return c.setNestedTypes()
+	}
+	var (
+		parquetType string
+		err         error
+	)
+	if upper := strings.ToUpper(name); upper == "DECIMAL" || strings.HasPrefix(upper, "DECIMAL(") {
+		// Precision and scale of the type (e.g. DECIMAL(10,2)) or of the spec
+		if upper == "DECIMAL" {
+			upper = fmt.Sprintf("DECIMAL(%v,%v)", c.Precision, c.Scale)
+		}
+		f, err := parseTypeSpec(upper)
+		if err != nil {
+			return fmt.Errorf("field %v: %v", c.Name, err)
+		}
+		parquetType, c.Precision, c.Scale = f.Type, f.Precision, f.Scale
+	} else if parquetType, err = ParseType(name); err != nil {
+		return fmt.Errorf("invalid type for field %v: %v", c.Name, name)
+	}
+	c.Type = parquetType
+	if c.Encoding != "" {
+		if c.Encoding, err = ParseEncoding(c.Encoding, parquetType); err != nil {
+			return fmt.Errorf("field %v: %v", c.Name, err)
+		}
+	} else if parquetType == "UTF8" {
+		c.Encoding = "PLAIN_DICTIONARY"
+	}
+	return nil
+}
+
+// Set the encodings of columns given as NAME=ENCODING (e.g. "id=DELTA_BINARY_PACKED"),
+// overriding the spec. Columns of tables are named TABLE.COLUMN, and * sets the
+// encoding of all the scalar columns
+func SetEncodings(spec *Spec, encodings []string) error {
+	type column struct {
+		name string
+		c    *Column
+	}
+	var columns []column
+	for i := range spec.Columns {
+		columns = append(columns, column{spec.Columns[i].Name, &spec.Columns[i]})
+	}
+	for _, t := range spec.Tables {
+		for i := range t.Columns {
+			columns = append(columns, column{t.Name + "." + t.Columns[i].Name, &t.Columns[i]})
+		}
+	}
+
+	for _, e := range encodings {
+		elem := strings.SplitN(e, "=", 2)
+		if len(elem) != 2 {
+			return fmt.Errorf("invalid encoding %v, expected NAME=ENCODING", e)
+		}
+		found := false
+		for _, col := range columns {
+			if col.name != elem[0] && (elem[0] != "*" || isNested(col.c.Type)) {
+				continue
+			}
+			encoding, err := ParseEncoding(elem[1], col.c.Type)
+			if err != nil {
+				return fmt.Errorf("field %v: %v", col.name, err)
+			}
+			col.c.Encoding = encoding
+			found = true
+		}
+		if !found {
+			return fmt.Errorf("no column %v for encoding %v", elem[0], elem[1])
+		}
+	}
+	return nil
+}
+
+// Parse column definitions NAME:TYPE[:SEED] (e.g. "X:INT32" or "X:INT32:42")
+func ParseColumns(columns []string) ([]Column, error) {
+	result := make([]Column, len(columns))
+	for i, column := range columns {
+		// Split ith column (e.g. "X:INT32" -> ["X", "INT32"])
+		elem := strings.Split(column, ":")
+		if len(elem) != 2 && len(elem) != 3 {
+			return nil, fmt.Errorf("invalid column %v", column)
+		}
+
+		result[i].Name = strings.ToLower(elem[0])
+		if err := result[i].setType(elem[1]); err != nil {
+			return nil, err
+		}
+
+		if len(elem) == 3 {
+			seed, err := strconv.ParseInt(elem[2], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid seed for field %v: %v", elem[0], elem[2])
+			}
+			result[i].Seed = &seed
+		}
+	}
+	return result, nil
+}
+
+// Read a simulation spec from a JSON file (.json extension) or a YAML file
+func LoadSpec(filename string) (*Spec, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read spec file %v: %v", filename, err)
+	}
+
+	var spec Spec
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&spec)
+	} else {
+		err = yaml.UnmarshalStrict(data, &spec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec file %v: %v", filename, err)
+	}
+
+	if (len(spec.Columns) == 0) == (len(spec.Tables) == 0) {
+		return nil, fmt.Errorf("spec file %v needs either columns or tables", filename)
+	}
+	if err = setTypes(spec.Columns); err != nil {
+		return nil, fmt.Errorf("spec file %v: %v", filename, err)
+	}
+	for _, t := range spec.Tables {
+		if t.Name == "" || len(t.Columns) == 0 {
+			return nil, fmt.Errorf("spec file %v: tables need a name and columns", filename)
+		}
+		if err = setTypes(t.Columns); err != nil {
+			return nil, fmt.Errorf("spec file %v: table %v: %v", filename, t.Name, err)
+		}
+	}
+	return &spec, nil
+}
+
+// Check the names and set the parquet types of columns read from a spec file
+func setTypes(columns []Column) error {
+	for i := range columns {
+		if columns[i].Name == "" {
+			return fmt.Errorf("column %v without name", i+1)
+		}
+		if err := columns[i].setType(columns[i].Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write a simulation spec to a JSON file (.json extension) or a YAML file
+func SaveSpec(filename string, spec *Spec) error {
+	var (
+		data []byte
+		err  error
+	)
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		data, err = json.MarshalIndent(spec, "", "  ")
+	} else {
+		data, err = yaml.Marshal(spec)
+	}
+	if err != nil {
+		return fmt.Errorf("can't encode spec: %v", err)
+	}
+	if err = ioutil.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("can't write spec file %v: %v", filename, err)
+	}
+	return nil
+}
+
+// Check that the name, enum values and pattern of a column can be written in
+// CSV files, which have no quotes (as ParquetToCSV writes them)
+func checkCSVText(c Column) error {
+	for _, x := range append([]string{c.Name, c.Pattern}, c.Values...) {
+		if strings.ContainsAny(x, ",\n\r") {
+			return fmt.Errorf("field %v: %q can't be written to a CSV file with a comma or a line break", c.Name, x)
+		}
+	}
+	return nil
+}
+
+// Prepare the simulation of nRows rows of the columns of a table ("" for a
+// single file), checking that all columns can be simulated
+func newSimulation(table string, nRows int, columns []Column, seed int64, opts SimulateOptions, keys map[string]*keySet) (*simulation, error) {
+	sim := &simulation{
+		nRows:   nRows,
+		columns: make([]Column, len(columns)),
+		names:   make([]string, len(columns)),
+		seeds:   make([]int64, len(columns)),
+		pools:   make([][]interface{}, len(columns)),
+		keys:    keys,
+		writer:  opts.Writer,
+		csv:     opts.CSV,
+	}
+	for j, c := range columns {
+		if opts.CSV && isNested(c.Type) {
+			return nil, fmt.Errorf("field %v: CSV files can't have %v columns", c.Name, c.Type)
+		}
+		if opts.CSV {
+			if err := checkCSVText(c); err != nil {
+				return nil, err
+			}
+		}
+		if c.NullRatio == 0 {
+			c.NullRatio = opts.NullRatio
+		}
+		if c.NullRatio < 0 || c.NullRatio > 1 {
+			return nil, fmt.Errorf("field %v: null ratio %v not between 0 and 1", c.Name, c.NullRatio)
+		}
+		if c.NullRatio > 0 {
+			c.Optional = true
+		}
+
+		sim.names[j] = c.Name
+		if table != "" {
+			sim.names[j] = table + "." + c.Name
+		}
+		sim.seeds[j] = columnSeed(seed, sim.names[j])
+		if c.Seed != nil {
+			sim.seeds[j] = *c.Seed
+		}
+		Debug("Column %v seed: %v", sim.names[j], sim.seeds[j])
+
+		sim.columns[j] = c
+	}
+
+	// parquet-go adds the values of dictionaries from the goroutines encoding
+	// the pages in any order, so files with dictionaries are encoded by one
+	// goroutine to be reproducible
+	if hasDictionary(sim.columns) && sim.writer.Parallelism != 1 {
+		Debug("Parallelism %v set to 1 for dictionary-encoded columns", sim.writer.Parallelism)
+		sim.writer.Parallelism = 1
+	}
+
+	var err error
+	if sim.dataType, err = columnsType(sim.columns); err != nil {
+		return nil, err
+	}
+	sim.first, err = sim.sources(0)
+	return sim, err
+}
+
+// Return whether columns, or the items, keys, values or fields of nested
+// columns, are dictionary-encoded
+func hasDictionary(columns []Column) bool {
+	for _, c := range columns {
+		if c.Encoding == "PLAIN_DICTIONARY" || c.Encoding == "RLE_DICTIONARY" {
+			return true
+		}
+		for _, n := range []*Column{c.Items, c.Key, c.Value} {
+			if n != nil && hasDictionary([]Column{*n}) {
+				return true
+			}
+		}
+		if hasDictionary(c.Fields) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the random sources of the file of index part. The first file uses the
+// seeds of the columns and builds the pools of values of the columns with a cardinality
+func (sim *simulation) sources(part int) (*sources, error) {
+	src := &sources{
+		values: make([]func(i int) interface{}, len(sim.columns)),
+		nulls:  make([]*rand.Rand, len(sim.columns)),
+	}
+	for j, c := range sim.columns {
+		s := sim.seeds[j]
+		if part > 0 {
+			s = columnSeed(s, fmt.Sprintf("part %v", part))
+		}
+		r := rand.New(rand.NewSource(s))
+
+		generate, err := valueGenerator(r, c, sim.nRows, sim.seeds[j])
+		if err == nil && c.References != "" {
+			generate, err = referenceGenerator(r, c, sim.keys[c.References], generate)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v", sim.names[j], err)
+		}
+		if c.Cardinality > 0 {
+			if sim.pools[j] == nil {
+				sim.pools[j] = cardinalityPool(generate, c.Cardinality)
+			}
+			generate = poolGenerator(r, sim.pools[j])
+		}
+		src.values[j] = generate
+
+		// Nulls have their own random source, so the values don't depend on the null ratio
+		src.nulls[j] = rand.New(rand.NewSource(columnSeed(s, "null")))
+	}
+	return src, nil
+}
+
+// Write the file of index part among files, with its share of the rows.
+// The values of the columns referenced by foreign keys are added to the keys
+func (sim *simulation) writePart(filename string, part int, files int) (int, error) {
+	start := part*(sim.nRows/files) + minInt(part, sim.nRows%files)
+	n := sim.nRows / files
+	if part < sim.nRows%files {
+		n++
+	}
+
+	src := sim.first
+	if part > 0 {
+		var err error
+		if src, err = sim.sources(part); err != nil {
+			return 0, err
+		}
+	}
+
+	collected := make([]*keySet, len(sim.columns))
+	for j := range sim.columns {
+		collected[j] = sim.keys[sim.names[j]]
+	}
+
+	pw, err := CreateWriterWithOptions(filename, sim.dataType, sim.writer)
+	if err != nil {
+		return 0, err
+	}
+
+	// CSV file of the same rows, in the format of ParquetToCSV with a header
+	var (
+		csv     *csvFile
+		fields  []Field
+		csvOpts = ExportCSVOptions{Header: true}
+	)
+	// Remove the files of the part on errors
+	abort := func() {
+		pw.Close()
+		os.Remove(filename)
+		if csv != nil {
+			os.Remove(csv.file.Name())
+		}
+	}
+	if sim.csv {
+		if csv, err = createCSV(csvFilename(filename)); err != nil {
+			abort()
+			return 0, err
+		}
+		defer csv.file.Close()
+		for _, c := range sim.columns {
+			fields = append(fields, c.Field)
+		}
+		if _, err = csv.WriteString(csvOpts.header(fields)); err != nil {
+			abort()
+			return 0, fmt.Errorf("writing CSV file: %v", err)
+		}
+	}
+
+	// Simulate the rows from start to start+n
+	for i := start; i < start+n; i++ {
+
+		// Create new reflect slice/array to store the row with the rights data types
+		v := pw.NewRow()
+
+		// Enter random value into new variable, leaving nil pointers for nulls
+		for j, generate := range src.values {
+			x := generate(i)
+			if sim.columns[j].NullRatio > 0 && src.nulls[j].Float64() < sim.columns[j].NullRatio {
+				continue
+			}
+			if collected[j] != nil {
+				if err = collected[j].add(x); err != nil {
+					abort()
+					return 0, fmt.Errorf("field %v: %v", sim.names[j], err)
+				}
+			}
+			setField(v.Field(j), x)
+		}
+
+		// Write row to parquet file
+		if err = pw.Write(v); err != nil {
+			abort()
+			return 0, err
+		}
+		if csv != nil {
+			if _, err = csv.WriteString(csvOpts.line(v, fields)); err != nil {
+				abort()
+				return 0, fmt.Errorf("writing CSV file: %v", err)
+			}
+		}
+	}
+
+	// Close parquet file write
+	if err = pw.Close(); err != nil {
+		os.Remove(filename)
+		if csv != nil {
+			os.Remove(csv.file.Name())
+		}
+		return 0, err
+	}
+	if csv != nil {
+		if err = csv.Flush(); err != nil {
+			os.Remove(filename)
+			os.Remove(csv.file.Name())
+			return 0, fmt.Errorf("writing CSV file: %v", err)
+		}
+	}
+	return n, nil
+}
+
+// CSV file written with a buffer
+type csvFile struct {
+	*bufio.Writer
+	file *os.File
+}
+
+// Create a CSV file
+func createCSV(filename string) (*csvFile, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't create CSV file: %v", err)
+	}
+	return &csvFile{bufio.NewWriter(file), file}, nil
+}
+
+// Return the name of the CSV file written with a parquet file
+func csvFilename(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + ".csv"
+}
+
+// Return the smallest of two integers
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Return the name of the file of index part among files: the file name itself
+// for a single file, else the file name with the index (e.g. test-00001.parquet)
+func partFilename(filename string, part int, files int) string {
+	if files == 1 {
+		return filename
+	}
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%v-%05d%v", strings.TrimSuffix(filename, ext), part, ext)
+}
+
+// Return the number of rows of files of opts.TargetSize bytes in total, estimated
+// from the size of a sample file written next to filename
+func estimateRows(filename string, columns []Column, seed int64, opts SimulateOptions) (int, error) {
+	sample, err := ioutil.TempFile(filepath.Dir(filename), "simulate-*.parquet")
+	if err != nil {
+		return 0, fmt.Errorf("can't create sample file: %v", err)
+	}
+	sample.Close()
+	defer os.Remove(sample.Name())
+
+	sim, err := newSimulation("", sampleRows, columns, seed, opts, nil)
+	if err != nil {
+		return 0, err
+	}
+	sim.csv = false
+	if _, err = sim.writePart(sample.Name(), 0, 1); err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(sample.Name())
+	if err != nil {
+		return 0, err
+	}
+
+	rowSize := float64(info.Size()) / sampleRows
+	nRows := int(float64(opts.TargetSize) / rowSize)
+	logf("Estimated %v rows of %.1f bytes for %v bytes", nRows, rowSize, opts.TargetSize)
+	return nRows, nil
+}
+
+// Return the seed of a simulation, a random seed if nil
+func simulationSeed(seed *int64) int64 {
+	if seed != nil {
+		return *seed
+	}
+	return time.Now().UnixNano()
+}
+
+// Write a parquet file of nRows rows of random values, or opts.Files files of
+// nRows rows in total written by opts.Workers workers. Each column has its own
+// random source, so the files are reproducible for a given seed
+func Simulate(filename string, nRows int, columns []Column, opts SimulateOptions) error {
+
+	seed := simulationSeed(opts.Seed)
+	logf("Seed: %v", seed)
+
+	var err error
+	if opts.TargetSize > 0 {
+		if nRows, err = estimateRows(filename, columns, seed, opts); err != nil {
+			return err
+		}
+	}
+
+	sim, err := newSimulation("", nRows, columns, seed, opts, nil)
+	if err != nil {
+		return err
+	}
+
+	files, workers := opts.Files, opts.Workers
+	if files < 1 {
+		files = 1
+	}
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	if workers > files {
+		workers = files
+	}
+
+	// Workers write the files of the parts sent on a channel, skipping them after an error
+	begin := time.Now()
+	parts := make(chan int)
+	errs := make([]error, files)
+	var (
+		wg     sync.WaitGroup
+		failed int32
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range parts {
+				if atomic.LoadInt32(&failed) != 0 {
+					continue
+				}
+				name := partFilename(filename, part, files)
+				n, err := sim.writePart(name, part, files)
+				if err != nil {
+					errs[part] = err
+					atomic.StoreInt32(&failed, 1)
+					continue
+				}
+				logf("Parquet file %v written with %v rows and %v fields", name, n, len(sim.columns))
+			}
+		}()
+	}
+	for part := 0; part < files; part++ {
+		parts <- part
+	}
+	close(parts)
+	wg.Wait()
+
+	for _, err = range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	// Throughput report
+	if files > 1 || opts.TargetSize > 0 {
+		elapsed := time.Since(begin)
+		var size int64
+		for part := 0; part < files; part++ {
+			if info, err := os.Stat(partFilename(filename, part, files)); err == nil {
+				size += info.Size()
+			}
+		}
+		logf("%v rows and %v bytes written in %v files in %v: %.0f rows/s, %.1f MB/s", nRows, size, files,
+			elapsed.Round(time.Millisecond), float64(nRows)/elapsed.Seconds(), float64(size)/1e6/elapsed.Seconds())
+	}
+	return nil
+}
+
+// Write the parquet file of a table. The seeds of the columns are derived from
+// the seed and the table and column names. The values of the columns of the
+// table in keys are added to keys, and foreign keys are picked in keys
+func simulateTable(filename string, table string, nRows int, columns []Column, seed int64, opts SimulateOptions, keys map[string]*keySet) error {
+	sim, err := newSimulation(table, nRows, columns, seed, opts, keys)
+	if err != nil {
+		return err
+	}
+	if _, err = sim.writePart(filename, 0, 1); err != nil {
+		return err
+	}
+	logf("Parquet file %v written with %v rows and %v fields", filename, nRows, len(columns))
+	return nil
+}
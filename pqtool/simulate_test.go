@@ -0,0 +1,136 @@
+package pqtool
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// A seed of 0 is a seed like any other, not a random seed
+func TestSimulateZeroSeed(t *testing.T) {
+	dir := t.TempDir()
+	columns, err := ParseColumns([]string{"X:INT64", "S:UTF8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seed int64
+	var rows [][]interface{}
+	for _, name := range []string{"a.parquet", "b.parquet"} {
+		filename := filepath.Join(dir, name)
+		if err = Simulate(filename, 20, columns, SimulateOptions{Seed: &seed}); err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, storedRows(t, filename))
+	}
+	if !reflect.DeepEqual(rows[0], rows[1]) {
+		t.Errorf("different rows with the same seed 0")
+	}
+}
+
+// Files simulated with the same seed are the same byte for byte, with
+// dictionary-encoded strings encoded in parallel and files written by several workers
+func TestSimulateReproducible(t *testing.T) {
+	dir := t.TempDir()
+	columns, err := ParseColumns([]string{"X:INT64", "S:UTF8", "E:UTF8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns[2].Generator, columns[2].Values = "enum", []string{"a", "b", "c", "d"}
+	seed := int64(42)
+	for _, files := range []int{1, 4} {
+		sums := make([][]string, 2)
+		for run := range sums {
+			filename := filepath.Join(dir, fmt.Sprintf("run%v.parquet", run))
+			opts := SimulateOptions{Seed: &seed, Files: files, Workers: files, Writer: WriterOptions{PageSize: 1024, Parallelism: 4}}
+			if err = Simulate(filename, 20000, columns, opts); err != nil {
+				t.Fatal(err)
+			}
+			for part := 0; part < files; part++ {
+				data, err := os.ReadFile(partFilename(filename, part, files))
+				if err != nil {
+					t.Fatal(err)
+				}
+				sums[run] = append(sums[run], fmt.Sprintf("%x", md5.Sum(data)))
+			}
+		}
+		if !reflect.DeepEqual(sums[0], sums[1]) {
+			t.Errorf("%v files: different md5 sums %v and %v with the same seed", files, sums[0], sums[1])
+		}
+	}
+}
+
+// Integer bounds of spec files are exact beyond 2^53
+func TestSimulateIntegerBounds(t *testing.T) {
+	dir := t.TempDir()
+	specs := map[string]string{
+		"spec.yaml": `columns:
+- {name: a, type: INT64, generator: sequence, min: 9007199254740993}
+- {name: b, type: UINT_64, min: 18446744073709551613, max: 18446744073709551615}
+- {name: c, type: INT64, min: -9007199254740995, max: -9007199254740993}
+`,
+		"spec.json": `{"columns": [
+{"name": "a", "type": "INT64", "generator": "sequence", "min": 9007199254740993},
+{"name": "b", "type": "UINT_64", "min": 18446744073709551613, "max": 18446744073709551615},
+{"name": "c", "type": "INT64", "min": -9007199254740995, "max": -9007199254740993}
+]}`,
+	}
+	seed := int64(1)
+	for name, text := range specs {
+		specFile := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(specFile, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		spec, err := LoadSpec(specFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filename := filepath.Join(dir, "output.parquet")
+		if err = Simulate(filename, 100, spec.Columns, SimulateOptions{Seed: &seed}); err != nil {
+			t.Fatal(err)
+		}
+		for i, row := range storedRows(t, filename) {
+			values := row.([]interface{})
+			if a := values[0].(int64); a != 9007199254740993+int64(i) {
+				t.Errorf("%v: value %v of the sequence at row %v", name, a, i)
+			}
+			if b := values[1].(uint64); b < 18446744073709551613 {
+				t.Errorf("%v: value %v below the min at row %v", name, b, i)
+			}
+			if c := values[2].(int64); c < -9007199254740995 || c > -9007199254740993 {
+				t.Errorf("%v: value %v out of the bounds at row %v", name, c, i)
+			}
+		}
+
+		// Saved specs keep the bounds
+		if err = SaveSpec(specFile, spec); err != nil {
+			t.Fatal(err)
+		}
+		saved, err := LoadSpec(specFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, c := range saved.Columns {
+			if *c.Min != *spec.Columns[j].Min {
+				t.Errorf("%v: min %v of %v saved as %v", name, spec.Columns[j].Min, c.Name, c.Min)
+			}
+		}
+	}
+
+	// Sequences can end at the maximum of the type, not beyond
+	columns := []Column{{Field: Field{Name: "a", Type: "INT64"}, Generator: "sequence", Min: IntNumber(math.MaxInt64 - 2)}}
+	if err := setTypes(columns); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "max.parquet")
+	if err := Simulate(filename, 3, columns, SimulateOptions{Seed: &seed}); err != nil {
+		t.Error(err)
+	}
+	if err := Simulate(filename, 4, columns, SimulateOptions{Seed: &seed}); err == nil {
+		t.Errorf("no error for a sequence beyond the maximum of INT64")
+	}
+}
@@ -0,0 +1,364 @@
+package pqtool
+
+import (
+	"container/heap"
+	"fmt"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/parquet"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SortKey is a column rows are sorted by
+type SortKey struct {
+	Column     string // Name of the column
+	Descending bool   // Descending order instead of ascending
+	NullsFirst bool   // Nulls before the other values instead of after them
+}
+
+// SortOptions are the options to sort the rows of a file by key columns
+type SortOptions struct {
+	Keys    []SortKey // Columns the rows are sorted by, in the order of the input if empty
+	Memory  int64     // Size of the rows sorted in memory before they are spilled to temporary files, 256 MB if 0
+	TempDir string    // Directory of the temporary files, the default directory for temporary files if empty
+}
+
+// Parse sort keys given as NAME[:asc|:desc][:nulls_first|:nulls_last] (e.g. "event_time:desc")
+func ParseSortKeys(keys []string) ([]SortKey, error) {
+	result := make([]SortKey, len(keys))
+	for i, key := range keys {
+		elem := strings.Split(key, ":")
+		if elem[0] == "" {
+			return nil, fmt.Errorf("invalid sort key %v", key)
+		}
+		result[i].Column = elem[0]
+		for _, option := range elem[1:] {
+			switch strings.ToLower(option) {
+			case "asc":
+				result[i].Descending = false
+			case "desc":
+				result[i].Descending = true
+			case "nulls_first":
+				result[i].NullsFirst = true
+			case "nulls_last":
+				result[i].NullsFirst = false
+			default:
+				return nil, fmt.Errorf("invalid order %v of sort key %v, expected asc, desc, nulls_first or nulls_last", option, key)
+			}
+		}
+	}
+	return result, nil
+}
+
+// Destination of the rows of a conversion: a parquet writer, or a sorter
+// writing the rows in order when it's closed
+type rowWriter interface {
+	Write(v reflect.Value) error
+	Close() error
+}
+
+// Key column of a sorter
+type sortColumn struct {
+	field      int
+	descending bool
+	nullsFirst bool
+}
+
+// Sorter of the rows written to a parquet writer. Rows are sorted in memory up
+// to opts.Memory bytes, then spilled to temporary parquet files merged when
+// the sorter is closed
+type sorter struct {
+	pw      *Writer
+	columns []sortColumn
+	opts    SortOptions
+	rows    []reflect.Value // Rows in memory
+	size    int64           // Estimated size of the rows in memory
+	spills  []string        // Temporary files of sorted rows
+}
+
+// Return the writer of the rows to a parquet writer, sorting them if opts has keys
+func sortedWriter(pw *Writer, fields []Field, opts SortOptions) (rowWriter, error) {
+	if len(opts.Keys) == 0 {
+		return pw, nil
+	}
+	if opts.Memory == 0 {
+		opts.Memory = 256 << 20
+	}
+
+	s := &sorter{pw: pw, opts: opts}
+	var sortingColumns []*parquet.SortingColumn
+	for _, key := range opts.Keys {
+		field := -1
+		for i, f := range fields {
+			if f.Name == key.Column {
+				field = i
+			}
+		}
+		if field < 0 {
+			return nil, fmt.Errorf("no column %v to sort by", key.Column)
+		}
+		s.columns = append(s.columns, sortColumn{field, key.Descending, key.NullsFirst})
+		sortingColumns = append(sortingColumns, &parquet.SortingColumn{
+			ColumnIdx:  int32(field),
+			Descending: key.Descending,
+			NullsFirst: key.NullsFirst,
+		})
+	}
+	pw.SetSortingColumns(sortingColumns)
+	return s, nil
+}
+
+// Compare the values of two rows in a key column. Nulls are first or last
+// whatever the order of the other values
+func (c sortColumn) compare(a reflect.Value, b reflect.Value) int {
+	x, y := a.Field(c.field), b.Field(c.field)
+	if isNull(x) || isNull(y) {
+		return compareValues(x, y, c.nullsFirst)
+	}
+	result := compareValues(x, y, false)
+	if c.descending {
+		result = -result
+	}
+	return result
+}
+
+// Return whether a value is null, a nil pointer
+func isNull(v reflect.Value) bool {
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// Compare two values of the same kind, optional values being pointers, nil for nulls
+func compareValues(x reflect.Value, y reflect.Value, nullsFirst bool) int {
+	xNull, yNull := isNull(x), isNull(y)
+	switch {
+	case xNull && yNull:
+		return 0
+	case xNull || yNull:
+		if xNull == nullsFirst {
+			return -1
+		}
+		return 1
+	}
+	x, y = reflect.Indirect(x), reflect.Indirect(y)
+
+	switch {
+	case isSigned(x.Kind()):
+		return compareOrdered(x.Int() < y.Int(), x.Int() > y.Int())
+	case isUnsigned(x.Kind()):
+		return compareOrdered(x.Uint() < y.Uint(), x.Uint() > y.Uint())
+	case x.Kind() == reflect.Float32 || x.Kind() == reflect.Float64:
+		return compareOrdered(x.Float() < y.Float(), x.Float() > y.Float())
+	case x.Kind() == reflect.String:
+		return strings.Compare(x.String(), y.String())
+	case x.Kind() == reflect.Bool:
+		return compareOrdered(!x.Bool() && y.Bool(), x.Bool() && !y.Bool())
+	}
+	return 0
+}
+
+// Return -1 if less, 1 if greater, else 0
+func compareOrdered(less bool, greater bool) int {
+	if less {
+		return -1
+	}
+	if greater {
+		return 1
+	}
+	return 0
+}
+
+// Return whether row a comes before row b
+func (s *sorter) less(a reflect.Value, b reflect.Value) bool {
+	for _, c := range s.columns {
+		if result := c.compare(a, b); result != 0 {
+			return result < 0
+		}
+	}
+	return false
+}
+
+// Add a row, spilling the rows in memory to a temporary file if they reach the memory size
+func (s *sorter) Write(v reflect.Value) error {
+	row := reflect.New(v.Type()).Elem()
+	row.Set(v)
+	s.rows = append(s.rows, row)
+	s.size += common.SizeOf(v)
+	if s.size >= s.opts.Memory {
+		return s.spill()
+	}
+	return nil
+}
+
+// Sort the rows in memory, keeping the order of the rows with the same keys
+func (s *sorter) sortRows() {
+	sort.SliceStable(s.rows, func(i, j int) bool { return s.less(s.rows[i], s.rows[j]) })
+}
+
+// Write the rows in memory, sorted, to a temporary file
+func (s *sorter) spill() error {
+	file, err := ioutil.TempFile(s.opts.TempDir, "sort-*.parquet")
+	if err != nil {
+		return fmt.Errorf("can't create temporary file: %v", err)
+	}
+	file.Close()
+	s.spills = append(s.spills, file.Name())
+	Debug("Spilling %v rows to %v", len(s.rows), file.Name())
+
+	s.sortRows()
+	pw, err := CreateWriterWithOptions(file.Name(), s.pw.DataType, WriterOptions{
+		Compression:  "UNCOMPRESSED",
+		RowGroupSize: 16 << 20,
+	})
+	if err != nil {
+		return err
+	}
+	for _, row := range s.rows {
+		if err = pw.Write(row); err != nil {
+			pw.Close()
+			return err
+		}
+	}
+	s.rows, s.size = nil, 0
+	return pw.Close()
+}
+
+// Rows of a parquet file read in batches
+type batchReader struct {
+	pr    *Reader
+	index int           // Index of the file among the files read together (e.g. merged temporary files)
+	rows  reflect.Value // Current batch of rows
+	next  int           // Index of the current row in the batch
+}
+
+// Return a reader of the rows of a parquet file in batches on its first row,
+// and false if the file has no rows
+func newBatchReader(pr *Reader, index int) (*batchReader, bool, error) {
+	r := &batchReader{pr: pr, index: index, next: -1, rows: reflect.MakeSlice(reflect.SliceOf(pr.DataType), 0, 0)}
+	ok, err := r.advance()
+	return r, ok, err
+}
+
+// Return the current row
+func (r *batchReader) row() reflect.Value {
+	return r.rows.Index(r.next)
+}
+
+// Move to the next row, and return false at the end of the file
+func (r *batchReader) advance() (bool, error) {
+	r.next++
+	if r.next < r.rows.Len() {
+		return true, nil
+	}
+	var err error
+	if r.rows, err = r.pr.Read(1000); err != nil {
+		return false, err
+	}
+	r.next = 0
+	return r.rows.Len() > 0, nil
+}
+
+// Heap of the temporary files by their current rows
+type spillHeap struct {
+	s       *sorter
+	readers []*batchReader
+}
+
+func (h *spillHeap) Len() int { return len(h.readers) }
+
+func (h *spillHeap) Less(i, j int) bool {
+	a, b := h.readers[i], h.readers[j]
+	if h.s.less(a.row(), b.row()) {
+		return true
+	}
+	return !h.s.less(b.row(), a.row()) && a.index < b.index
+}
+
+func (h *spillHeap) Swap(i, j int) { h.readers[i], h.readers[j] = h.readers[j], h.readers[i] }
+
+func (h *spillHeap) Push(x interface{}) { h.readers = append(h.readers, x.(*batchReader)) }
+
+func (h *spillHeap) Pop() interface{} {
+	r := h.readers[len(h.readers)-1]
+	h.readers = h.readers[:len(h.readers)-1]
+	return r
+}
+
+// Write the sorted rows to the parquet writer and close it, merging the temporary files if any
+func (s *sorter) Close() error {
+	defer func() {
+		for _, name := range s.spills {
+			os.Remove(name)
+		}
+	}()
+
+	if len(s.spills) == 0 {
+		s.sortRows()
+		for _, row := range s.rows {
+			if err := s.pw.Write(row); err != nil {
+				s.pw.Close()
+				return err
+			}
+		}
+		return s.pw.Close()
+	}
+
+	if len(s.rows) > 0 {
+		if err := s.spill(); err != nil {
+			s.pw.Close()
+			return err
+		}
+	}
+	logf("Merging %v sorted temporary files", len(s.spills))
+
+	h := &spillHeap{s: s}
+	defer func() {
+		for _, r := range h.readers {
+			r.pr.Close()
+		}
+	}()
+	for i, name := range s.spills {
+		pr, err := OpenReader(name)
+		if err != nil {
+			s.pw.Close()
+			return err
+		}
+		r, ok, err := newBatchReader(pr, i)
+		if err != nil {
+			pr.Close()
+			s.pw.Close()
+			return err
+		}
+		if ok {
+			h.readers = append(h.readers, r)
+		} else {
+			pr.Close()
+		}
+	}
+	heap.Init(h)
+
+	// Rows of the temporary files have the structure of the writer with the tags of a reader
+	row := reflect.New(s.pw.DataType).Elem()
+	for h.Len() > 0 {
+		r := h.readers[0]
+		row.Set(r.row().Convert(s.pw.DataType))
+		if err := s.pw.Write(row); err != nil {
+			s.pw.Close()
+			return err
+		}
+		ok, err := r.advance()
+		if err != nil {
+			s.pw.Close()
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			r.pr.Close()
+			heap.Pop(h)
+		}
+	}
+	return s.pw.Close()
+}
@@ -0,0 +1,84 @@
+package pqtool
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// Rows are sorted ascending or descending with nulls first or last, in memory
+// or through temporary files
+func TestSortNulls(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.parquet")
+	fields := []Field{{Name: "id", Type: "INT64"}, {Name: "k", Type: "INT32", Optional: true}}
+	rows := make([][]interface{}, 2000)
+	for i := range rows {
+		var k interface{}
+		if i%5 != 2 {
+			k = int32((i * 7919) % 1000)
+		}
+		rows[i] = []interface{}{int64(i), k}
+	}
+	writeRows(t, input, fields, rows)
+
+	for _, descending := range []bool{false, true} {
+		for _, nullsFirst := range []bool{false, true} {
+			for _, memory := range []int64{0, 4 << 10} {
+				key := SortKey{Column: "k", Descending: descending, NullsFirst: nullsFirst}
+				t.Run(fmt.Sprintf("%+v memory %v", key, memory), func(t *testing.T) {
+					output := filepath.Join(dir, "output.parquet")
+					opts := RewriteOptions{Sort: SortOptions{Keys: []SortKey{key}, Memory: memory, TempDir: dir}}
+					if err := Rewrite(input, output, opts); err != nil {
+						t.Fatal(err)
+					}
+					checkSorted(t, output, key, len(rows))
+				})
+			}
+		}
+	}
+}
+
+// Check that the second column of a file is sorted by a key, and that the file has n rows
+func checkSorted(t *testing.T, filename string, key SortKey, n int) {
+	t.Helper()
+	footer, err := readFooter(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rg := range footer.RowGroups {
+		if len(rg.SortingColumns) != 1 || rg.SortingColumns[0].Descending != key.Descending || rg.SortingColumns[0].NullsFirst != key.NullsFirst {
+			t.Errorf("sorting columns %v", rg.SortingColumns)
+		}
+	}
+
+	rows := storedRows(t, filename)
+	if len(rows) != n {
+		t.Fatalf("%v rows, expected %v", len(rows), n)
+	}
+	ids := make(map[interface{}]bool)
+	var nulls, values int
+	var last int32
+	for i, row := range rows {
+		id, k := row.([]interface{})[0], row.([]interface{})[1]
+		ids[id] = true
+		if k == nil {
+			if values > 0 && key.NullsFirst || nulls > 0 && !key.NullsFirst && nulls+values != i {
+				t.Fatalf("null at row %v after %v values", i, values)
+			}
+			nulls++
+			continue
+		}
+		if nulls > 0 && !key.NullsFirst {
+			t.Fatalf("value at row %v after %v nulls", i, nulls)
+		}
+		if x := k.(int32); values > 0 && (x < last && !key.Descending || x > last && key.Descending) {
+			t.Fatalf("value %v at row %v after %v", x, i, last)
+		}
+		last = k.(int32)
+		values++
+	}
+	if nulls != n/5 || len(ids) != n {
+		t.Errorf("%v nulls and %v distinct ids, expected %v and %v", nulls, len(ids), n/5, n)
+	}
+}
@@ -0,0 +1,221 @@
+package pqtool
+
+import (
+	"fmt"
+	"github.com/xitongsys/parquet-go/parquet"
+	"os"
+)
+
+// SplitOptions are the options of Split, which splits a file by one of Rows, Parts, TargetSize or RowGroups
+type SplitOptions struct {
+	Rows       int64         // Number of rows of each part, the last one having the remaining rows
+	Parts      int           // Number of parts with the same number of rows (give or take one)
+	TargetSize int64         // Size of the parts in bytes, made of whole row groups, or of decoded rows if a row group is larger
+	RowGroups  bool          // One part per row group
+	Writer     WriterOptions // Layout of decoded parts, with the codec of the file if Writer.Compression is empty
+}
+
+// Return the number of rows of each part of a file of nRows rows split by rows or into parts
+func partRows(nRows int64, opts SplitOptions) []int64 {
+	var counts []int64
+	if opts.Rows > 0 {
+		for start := int64(0); start < nRows; start += opts.Rows {
+			counts = append(counts, minInt64(opts.Rows, nRows-start))
+		}
+	} else {
+		parts := int64(opts.Parts)
+		if parts > nRows {
+			parts = nRows
+		}
+		for part := int64(0); part < parts; part++ {
+			n := nRows / parts
+			if part < nRows%parts {
+				n++
+			}
+			counts = append(counts, n)
+		}
+	}
+	if len(counts) == 0 {
+		counts = append(counts, 0)
+	}
+	return counts
+}
+
+// Return the codec of the first column chunk of a file, SNAPPY if it has no
+// rows or a codec parquet-go can't write
+func fileCodec(footer *parquet.FileMetaData) parquet.CompressionCodec {
+	if len(footer.RowGroups) > 0 && len(footer.RowGroups[0].Columns) > 0 {
+		if md := footer.RowGroups[0].Columns[0].MetaData; md != nil && supportedCodecs[md.Codec] {
+			return md.Codec
+		}
+	}
+	return parquet.CompressionCodec_SNAPPY
+}
+
+// Return the smallest of two integers
+func minInt64(a int64, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Return the groups of row groups with the given numbers of rows, or false if
+// the parts don't start at row groups
+func groupByRows(rowGroups []fileRowGroup, counts []int64) ([][]fileRowGroup, bool) {
+	var groups [][]fileRowGroup
+	i := 0
+	for _, count := range counts {
+		var (
+			group []fileRowGroup
+			rows  int64
+		)
+		for rows < count && i < len(rowGroups) {
+			group = append(group, rowGroups[i])
+			rows += rowGroups[i].rg.NumRows
+			i++
+		}
+		if rows != count {
+			return nil, false
+		}
+		groups = append(groups, group)
+	}
+	return groups, true
+}
+
+// Split a parquet file into parts named like the output file with their index
+// (e.g. out-00001.parquet), keeping its schema and key-value metadata. Parts by
+// row group or size are made of whole row groups copied without being decoded.
+// Parts by rows are copied too if they start at row groups, else the rows of the
+// file are decoded (flat files only) and written with opts.Writer
+func Split(filename string, output string, opts SplitOptions) error {
+	modes := 0
+	for _, set := range []bool{opts.Rows > 0, opts.Parts > 0, opts.TargetSize > 0, opts.RowGroups} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		return fmt.Errorf("split needs one of a number of rows, a number of parts, a size or row groups")
+	}
+
+	footer, err := readFooter(filename)
+	if err != nil {
+		return err
+	}
+	rowGroups := make([]fileRowGroup, len(footer.RowGroups))
+	for i, rg := range footer.RowGroups {
+		rowGroups[i] = fileRowGroup{filename, rg}
+	}
+
+	switch {
+	case opts.RowGroups:
+		groups := make([][]fileRowGroup, len(rowGroups))
+		for i, g := range rowGroups {
+			groups[i] = []fileRowGroup{g}
+		}
+		if len(groups) == 0 {
+			groups = append(groups, nil)
+		}
+		return writeRowGroups(groups, output, footer)
+
+	case opts.TargetSize > 0:
+		// Row groups larger than the size are split into parts of decoded rows,
+		// whose number is estimated from the size of a row in the file
+		var size, largest int64
+		for _, rg := range footer.RowGroups {
+			rgSize := rowGroupSize(rg)
+			size += rgSize
+			if rgSize > largest {
+				largest = rgSize
+			}
+		}
+		if largest <= opts.TargetSize || !isFlatSchema(footer) {
+			return writeRowGroups(groupBySize(rowGroups, opts.TargetSize), output, footer)
+		}
+		rows := int64(float64(opts.TargetSize) * float64(footer.NumRows) / float64(size))
+		if rows < 1 {
+			rows = 1
+		}
+		logf("Rows decoded: row groups of up to %v bytes are larger than %v bytes, about %v rows per part", largest, opts.TargetSize, rows)
+		counts := partRows(footer.NumRows, SplitOptions{Rows: rows})
+		return splitRows(filename, footer, counts, outputFilenames(output, len(counts)), opts.Writer)
+	}
+
+	counts := partRows(footer.NumRows, opts)
+	if groups, ok := groupByRows(rowGroups, counts); ok {
+		return writeRowGroups(groups, output, footer)
+	}
+	return splitRows(filename, footer, counts, outputFilenames(output, len(counts)), opts.Writer)
+}
+
+// Return whether the schema of a file has only flat columns
+func isFlatSchema(footer *parquet.FileMetaData) bool {
+	for _, se := range footer.Schema[1:] {
+		if se.GetNumChildren() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Decode the rows of a flat file and write counts[i] rows to outputs[i], with
+// the column types of the footer and, by default, the codec of the file. The
+// parts are removed on errors
+func splitRows(filename string, footer *parquet.FileMetaData, counts []int64, outputs []string, opts WriterOptions) error {
+	if opts.Compression == "" {
+		opts.Compression = fileCodec(footer).String()
+	}
+	pr, err := openStoredReader(filename)
+	if err != nil {
+		return fmt.Errorf("%v (nested files can only be split by row groups or size)", err)
+	}
+	defer pr.Close()
+
+	for part, count := range counts {
+		pw, err := CreateWriterWithOptions(outputs[part], pr.DataType, opts)
+		if err != nil {
+			removeFiles(outputs[:part])
+			return err
+		}
+		abort := func() {
+			pw.Close()
+			removeFiles(outputs[:part+1])
+		}
+		for _, kv := range footer.KeyValueMetadata {
+			pw.SetMetadata(kv.Key, kv.GetValue())
+		}
+
+		for rows := int64(0); rows < count; {
+			slice, err := pr.Read(int(minInt64(1000, count-rows)))
+			if err == nil && slice.Len() == 0 {
+				err = fmt.Errorf("parquet file '%v' has fewer rows than its footer", filename)
+			}
+			if err != nil {
+				abort()
+				return err
+			}
+			for i := 0; i < slice.Len(); i++ {
+				if err = pw.Write(slice.Index(i)); err != nil {
+					abort()
+					return err
+				}
+			}
+			rows += int64(slice.Len())
+		}
+
+		if err = pw.Close(); err != nil {
+			removeFiles(outputs[:part+1])
+			return err
+		}
+		logf("Parquet file %v written with %v rows and %v fields", outputs[part], count, len(pr.Fields))
+	}
+	return nil
+}
+
+// Remove files, ignoring errors
+func removeFiles(filenames []string) {
+	for _, name := range filenames {
+		os.Remove(name)
+	}
+}
@@ -0,0 +1,96 @@
+package pqtool
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xitongsys/parquet-go/parquet"
+)
+
+// Parts of decoded rows keep the schema and codec of the file
+func TestSplitRowsKeepsSchema(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.parquet")
+	writeStoredTestFile(t, filepath.Join(dir, "snappy.parquet"), 100)
+	if err := Rewrite(filepath.Join(dir, "snappy.parquet"), input, RewriteOptions{Writer: WriterOptions{Compression: "GZIP"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "part.parquet")
+	if err := Split(input, output, SplitOptions{Parts: 3}); err != nil {
+		t.Fatal(err)
+	}
+	var rows []interface{}
+	for _, part := range outputFilenames(output, 3) {
+		if a, b := schemaElements(t, part), schemaElements(t, input); !reflect.DeepEqual(a, b) {
+			t.Errorf("schema of %v:\n%v\nexpected:\n%v", filepath.Base(part), a, b)
+		}
+		footer, err := readFooter(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if codec := fileCodec(footer); codec != parquet.CompressionCodec_GZIP {
+			t.Errorf("codec of %v: %v, expected GZIP", filepath.Base(part), codec)
+		}
+		rows = append(rows, storedRows(t, part)...)
+	}
+	if !reflect.DeepEqual(rows, storedRows(t, input)) {
+		t.Errorf("rows of the parts differ from the file")
+	}
+}
+
+// A file whose row group is larger than the size is split into parts of decoded rows
+func TestSplitSizeDecodesRowGroup(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.parquet")
+	writeStoredTestFile(t, input, 3000)
+	footer, err := readFooter(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(footer.RowGroups) != 1 {
+		t.Fatalf("%v row groups, expected 1", len(footer.RowGroups))
+	}
+
+	output := filepath.Join(dir, "part.parquet")
+	if err = Split(input, output, SplitOptions{TargetSize: rowGroupSize(footer.RowGroups[0]) / 3}); err != nil {
+		t.Fatal(err)
+	}
+	parts, err := filepath.Glob(filepath.Join(dir, "part-*.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) < 3 {
+		t.Fatalf("%v parts, expected at least 3", len(parts))
+	}
+	var rows []interface{}
+	for _, part := range parts {
+		rows = append(rows, storedRows(t, part)...)
+	}
+	if !reflect.DeepEqual(rows, storedRows(t, input)) {
+		t.Errorf("rows of the parts differ from the file")
+	}
+}
+
+// Parts of decoded rows are removed when a part can't be written
+func TestSplitRowsRemovesParts(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.parquet")
+	writeStoredTestFile(t, input, 100)
+
+	output := filepath.Join(dir, "part.parquet")
+	outputs := outputFilenames(output, 3)
+	if err := os.Mkdir(outputs[1], 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Split(input, output, SplitOptions{Parts: 3}); err == nil {
+		t.Fatal("no error writing a part over a directory")
+	}
+	for _, part := range []string{outputs[0], outputs[2]} {
+		if _, err := os.Stat(part); !os.IsNotExist(err) {
+			t.Errorf("part %v not removed", filepath.Base(part))
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package pqtool
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLiteOptions are the options to convert between parquet and SQLite
+type SQLiteOptions struct {
+	Table     string // Table to write (parquet file name if empty) or to read
+	Query     string // Query whose result is converted to parquet, instead of a table
+	Replace   bool   // Drop the table first if it exists
+	BatchSize int    // Rows per transaction, 1000 if zero
+}
+
+// Map the parquet type of a field to a SQLite column declaration
+func sqliteType(parquetType string) string {
+	switch parquetType {
+	case "BOOLEAN":
+		return "BOOLEAN"
+	case "INT32", "INT64", "INT_8", "INT_16", "INT_32", "INT_64", "UINT_8", "UINT_16", "UINT_32", "UINT_64":
+		return "INTEGER"
+	case "FLOAT", "DOUBLE":
+		return "REAL"
+	case "DECIMAL":
+		return "NUMERIC"
+	case "UTF8":
+		return "TEXT"
+	case "BYTE_ARRAY":
+		return "BLOB"
+	case "DATE":
+		return "DATE"
+	case "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS":
+		return "TIMESTAMP"
+	}
+	return ""
+}
+
+// Return the SQLite value of a parquet value of a field, and an error for
+// UINT_64 values above the largest SQLite integer (2^63-1)
+func sqliteValue(v reflect.Value, f Field) (interface{}, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	switch f.Type {
+	case "BOOLEAN":
+		return v.Bool(), nil
+	case "INT32", "INT64", "INT_8", "INT_16", "INT_32", "INT_64", "UINT_8", "UINT_16", "UINT_32":
+		return toInt(v), nil
+	case "UINT_64":
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("value %v of column %v is above the largest SQLite integer", v.Uint(), f.Name)
+		}
+		return int64(v.Uint()), nil
+	case "FLOAT", "DOUBLE":
+		return v.Float(), nil
+	case "DECIMAL":
+		return toDecimal(v, f.Scale).FloatString(f.Scale), nil
+	case "BYTE_ARRAY":
+		return []byte(v.String()), nil
+	case "DATE":
+		return FromDate(int32(v.Int())).Format("2006-01-02"), nil
+	case "TIMESTAMP_MILLIS":
+		return FromTimestamp(v.Int(), f.Type).Format("2006-01-02 15:04:05.000"), nil
+	case "TIMESTAMP_MICROS":
+		return FromTimestamp(v.Int(), f.Type).Format("2006-01-02 15:04:05.000000"), nil
+	}
+	return v.String(), nil
+}
+
+// Read a parquet file and insert its rows into a new SQLite table, one transaction per batch
+func ParquetToSQLite(parquet_filename string, sqlite_filename string, opts SQLiteOptions) error {
+	if opts.Table == "" {
+		opts.Table = strings.TrimSuffix(filepath.Base(parquet_filename), filepath.Ext(parquet_filename))
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+
+	pr, err := OpenReader(parquet_filename)
+	if err != nil {
+		return err
+	}
+	defer pr.Close()
+
+	logf("Structure:")
+	columns_list := ""
+	names_list := ""
+	values_list := ""
+	for _, f := range pr.Fields {
+		sqlType := sqliteType(f.Type)
+		if !f.Optional {
+			sqlType += " NOT NULL"
+		}
+		logf("  %v: %v -> %v", f.Name, f.Type, sqlType)
+		columns_list = addItem(columns_list, quoteIdentifier(f.Name)+" "+sqlType)
+		names_list = addItem(names_list, quoteIdentifier(f.Name))
+		values_list = addItem(values_list, "?")
+	}
+	createTable := fmt.Sprintf("CREATE TABLE %v (\n\t%v\n)", quoteIdentifier(opts.Table), columns_list)
+	insert := fmt.Sprintf("INSERT INTO %v (\n\t%v\n) VALUES (\n\t%v\n)", quoteIdentifier(opts.Table), names_list, values_list)
+
+	// Open SQLite database and create table
+	Debug("Open SQLite database %v", sqlite_filename)
+	db, err := sql.Open("sqlite3", sqlite_filename)
+	if err != nil {
+		return fmt.Errorf("can't open SQLite database '%v': %v", sqlite_filename, err)
+	}
+	defer db.Close()
+
+	if opts.Replace {
+		Debug("Dropping table %v", opts.Table)
+		if _, err = db.Exec("DROP TABLE IF EXISTS " + quoteIdentifier(opts.Table)); err != nil {
+			return fmt.Errorf("can't drop table %v: %v", opts.Table, err)
+		}
+	}
+	Debug("%v", createTable)
+	if _, err = db.Exec(createTable); err != nil {
+		return fmt.Errorf("can't create table %v: %v", opts.Table, err)
+	}
+
+	nRows := 0
+	values := make([]interface{}, len(pr.Fields))
+	for {
+		slice, err := pr.Read(opts.BatchSize)
+		if err != nil {
+			return err
+		}
+		if slice.Len() == 0 {
+			break
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("can't begin transaction: %v", err)
+		}
+		stmt, err := tx.Prepare(insert)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("can't prepare insert: %v", err)
+		}
+
+		for i := 0; i < slice.Len(); i++ {
+			for j, f := range pr.Fields {
+				if values[j], err = sqliteValue(slice.Index(i).Field(j), f); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				stmt.Close()
+				tx.Rollback()
+				return err
+			}
+			if _, err = stmt.Exec(values...); err != nil {
+				stmt.Close()
+				tx.Rollback()
+				return fmt.Errorf("inserting into %v: %v", opts.Table, err)
+			}
+		}
+
+		stmt.Close()
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("can't commit transaction: %v", err)
+		}
+		Debug("Inserted %v rows", slice.Len())
+		nRows += slice.Len()
+	}
+
+	logf("SQLite table %v written with %v rows and %v fields", opts.Table, nRows, len(pr.Fields))
+	return nil
+}
+
+// Map a SQLite declared column type to a parquet type following the SQLite
+// type affinity rules, with DATE and DATETIME/TIMESTAMP recognized as dates and timestamps
+func sqliteParquetType(declType string) string {
+
+	t := strings.ToUpper(declType)
+	switch {
+	case t == "BOOLEAN" || t == "BOOL":
+		return "BOOLEAN"
+	case t == "DATE":
+		return "DATE"
+	case t == "DATETIME" || t == "TIMESTAMP":
+		return "TIMESTAMP_MILLIS"
+	case strings.Contains(t, "INT"):
+		return "INT64"
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return "UTF8"
+	case strings.Contains(t, "BLOB"):
+		return "BYTE_ARRAY"
+	}
+
+	// REAL, FLOAT, DOUBLE and NUMERIC affinity
+	return "DOUBLE"
+}
+
+// Map the value of an expression column, which has no declared type, to a parquet type
+func sqliteValueType(x interface{}) string {
+	switch x.(type) {
+	case int64:
+		return "INT64"
+	case float64:
+		return "DOUBLE"
+	case []byte:
+		return "BYTE_ARRAY"
+	}
+	return "UTF8"
+}
+
+// Read the NOT NULL constraints of a table, by column name
+func sqliteNotNull(db *sql.DB, table string) (map[string]bool, error) {
+	notNull := make(map[string]bool)
+
+	rows, err := db.Query("PRAGMA table_info(" + quoteIdentifier(table) + ")")
+	if err != nil {
+		return nil, fmt.Errorf("can't read table info of %v: %v", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, isNotNull, pk int
+		var name, declType string
+		var defaultValue interface{}
+		if err = rows.Scan(&cid, &name, &declType, &isNotNull, &defaultValue, &pk); err != nil {
+			return nil, fmt.Errorf("can't read table info of %v: %v", table, err)
+		}
+		notNull[name] = isNotNull == 1
+	}
+
+	return notNull, rows.Err()
+}
+
+// Parse a date or timestamp stored as text
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02T15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02 15:04",
+		"2006-01-02T15:04",
+		"2006-01-02",
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("can't parse date '%v'", s)
+}
+
+// Convert a SQLite value into the parquet representation of its column
+func parquetValue(x interface{}, parquetType string) (interface{}, error) {
+	var err error
+
+	// Text and integer dates are converted to time first
+	if parquetType == "DATE" || parquetType == "TIMESTAMP_MILLIS" {
+		switch a := x.(type) {
+		case string:
+			x, err = parseTime(a)
+		case []byte:
+			x, err = parseTime(string(a))
+		case int64:
+			x = time.Unix(a, 0).UTC()
+		case float64:
+			x = time.Unix(int64(a), 0).UTC()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch a := x.(type) {
+	case time.Time:
+		switch parquetType {
+		case "DATE":
+			return ToDate(a), nil
+		case "TIMESTAMP_MILLIS":
+			return ToTimestamp(a, parquetType), nil
+		}
+		x = a.Format("2006-01-02 15:04:05.000")
+	case []byte:
+		x = string(a)
+	}
+
+	switch parquetType {
+	case "BOOLEAN":
+		switch a := x.(type) {
+		case bool:
+			return a, nil
+		case int64:
+			return a != 0, nil
+		case float64:
+			return a != 0, nil
+		case string:
+			var b bool
+			if b, err = strconv.ParseBool(a); err == nil {
+				return b, nil
+			}
+		}
+	case "INT64":
+		switch a := x.(type) {
+		case int64:
+			return a, nil
+		case float64:
+			return int64(a), nil
+		case bool:
+			if a {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case string:
+			var i int64
+			if i, err = strconv.ParseInt(a, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+	case "DOUBLE":
+		switch a := x.(type) {
+		case float64:
+			return a, nil
+		case int64:
+			return float64(a), nil
+		case string:
+			var f float64
+			if f, err = strconv.ParseFloat(a, 64); err == nil {
+				return f, nil
+			}
+		}
+	case "UTF8", "BYTE_ARRAY":
+		return fmt.Sprint(x), nil
+	}
+
+	return nil, fmt.Errorf("can't convert '%v' to %v: %v", x, parquetType, err)
+}
+
+// Convert a SQLite table or query result to parquet, with types derived from the column declarations
+func SQLiteToParquet(sqlite_filename string, parquet_filename string, opts SQLiteOptions) error {
+	if (opts.Table == "") == (opts.Query == "") {
+		return fmt.Errorf("either a table or a query is required")
+	}
+
+	Debug("Open SQLite database %v", sqlite_filename)
+	if _, err := os.Stat(sqlite_filename); err != nil {
+		return fmt.Errorf("can't open SQLite database '%v': %v", sqlite_filename, err)
+	}
+	db, err := sql.Open("sqlite3", sqlite_filename)
+	if err != nil {
+		return fmt.Errorf("can't open SQLite database '%v': %v", sqlite_filename, err)
+	}
+	defer db.Close()
+
+	// SQLite columns are nullable unless declared NOT NULL
+	notNull := make(map[string]bool)
+	query := opts.Query
+	if opts.Table != "" {
+		if notNull, err = sqliteNotNull(db, opts.Table); err != nil {
+			return err
+		}
+		query = "SELECT * FROM " + quoteIdentifier(opts.Table)
+	}
+
+	Debug("%v", query)
+	rows, err := db.Query(query)
+	if err != nil {
+		return fmt.Errorf("can't run query: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("can't read columns: %v", err)
+	}
+
+	// Read the first row ahead of the schema detection, to type the expression columns
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	hasRow := rows.Next()
+	if hasRow {
+		if err = rows.Scan(valuePtrs...); err != nil {
+			return fmt.Errorf("reading row 1: %v", err)
+		}
+	}
+
+	logf("Structure:")
+	fields := make([]Field, len(columns))
+	for i, column := range columns {
+		fields[i] = Field{
+			Name:     column.Name(),
+			Type:     sqliteParquetType(column.DatabaseTypeName()),
+			Optional: !notNull[column.Name()],
+		}
+		if column.DatabaseTypeName() == "" {
+			fields[i].Type = sqliteValueType(values[i])
+		}
+		logf("  %v: %v -> %v", column.Name(), column.DatabaseTypeName(), fields[i].Type)
+	}
+
+	pw, err := CreateFieldsWriter(parquet_filename, fields)
+	if err != nil {
+		return err
+	}
+
+	// Loop through each row
+	nRows := 0
+	for hasRow {
+		v := pw.NewRow()
+		for j, f := range fields {
+			if values[j] == nil {
+				if !f.Optional {
+					pw.Close()
+					return fmt.Errorf("null value in NOT NULL column %v", f.Name)
+				}
+				continue
+			}
+			x, err := parquetValue(values[j], f.Type)
+			if err != nil {
+				pw.Close()
+				return err
+			}
+			setField(v.Field(j), x)
+		}
+		if err = pw.Write(v); err != nil {
+			pw.Close()
+			return err
+		}
+		nRows++
+
+		if hasRow = rows.Next(); hasRow {
+			if err = rows.Scan(valuePtrs...); err != nil {
+				pw.Close()
+				return fmt.Errorf("reading row %v: %v", nRows+1, err)
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		pw.Close()
+		return fmt.Errorf("reading SQLite database '%v': %v", sqlite_filename, err)
+	}
+
+	if err = pw.Close(); err != nil {
+		return err
+	}
+
+	logf("Parquet file %v written with %v rows and %v fields", parquet_filename, nRows, len(fields))
+	return nil
+}
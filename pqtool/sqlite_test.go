@@ -0,0 +1,13 @@
+package pqtool
+
+import (
+	"testing"
+)
+
+// Files exported to SQLite and imported back have the same values, except
+// decimals read back from NUMERIC columns as doubles
+func TestSQLiteRoundTrip(t *testing.T) {
+	checkRoundTrip(t, "db", len(roundTripFields)-1,
+		func(in, out string) error { return ParquetToSQLite(in, out, SQLiteOptions{Table: "t", BatchSize: 7}) },
+		func(in, out string) error { return SQLiteToParquet(in, out, SQLiteOptions{Table: "t"}) })
+}
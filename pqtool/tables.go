@@ -0,0 +1,201 @@
+package pqtool
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Table of a multi-table simulation, written to its own parquet file
+type Table struct {
+	Name    string   `json:"name" yaml:"name"`
+	File    string   `json:"file,omitempty" yaml:"file,omitempty"` // Parquet file, relative to the output directory, NAME.parquet if empty
+	Rows    int      `json:"rows" yaml:"rows"`
+	Columns []Column `json:"columns" yaml:"columns"`
+}
+
+// Values of a referenced column, which must be unique like primary keys
+type keySet struct {
+	parquetType string
+	values      []interface{}
+	index       map[interface{}]bool
+}
+
+// Add a value to a key set, returning an error if it's already there
+func (k *keySet) add(x interface{}) error {
+	if k.index[x] {
+		return fmt.Errorf("duplicate value %v in a referenced column", x)
+	}
+	k.index[x] = true
+	k.values = append(k.values, x)
+	return nil
+}
+
+// Return the generator of a foreign key, picking values of the referenced column
+// with the fan-out distribution of the column. With the orphan ratio of the
+// column, it returns instead values of the column's own generator that aren't
+// in the referenced column
+func referenceGenerator(r *rand.Rand, c Column, keys *keySet, generate func(i int) interface{}) (func(i int) interface{}, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("unknown referenced column %v", c.References)
+	}
+	if keys.parquetType != c.Type {
+		return nil, fmt.Errorf("type %v doesn't match type %v of referenced column %v", c.Type, keys.parquetType, c.References)
+	}
+	n := len(keys.values)
+	if n == 0 {
+		return nil, fmt.Errorf("referenced column %v has no values", c.References)
+	}
+	if c.OrphanRatio < 0 || c.OrphanRatio > 1 {
+		return nil, fmt.Errorf("orphan ratio %v not between 0 and 1", c.OrphanRatio)
+	}
+
+	var pick func() int
+	switch c.Fanout {
+	case "", "uniform":
+		pick = func() int { return r.Intn(n) }
+	case "zipf":
+		// Few referenced values get most rows, in a random order of the referenced values
+		exponent := c.Exponent
+		if exponent == 0 {
+			exponent = 1.1
+		}
+		if exponent <= 1 {
+			return nil, fmt.Errorf("zipf exponent %v must be greater than 1", exponent)
+		}
+		z := rand.NewZipf(r, exponent, 1, uint64(n-1))
+		order := r.Perm(n)
+		pick = func() int { return order[z.Uint64()] }
+	default:
+		return nil, fmt.Errorf("invalid fanout %v", c.Fanout)
+	}
+
+	warned := false
+	return func(i int) interface{} {
+		if c.OrphanRatio > 0 && r.Float64() < c.OrphanRatio {
+			for k := 0; k < 1000; k++ {
+				if x := generate(i); !keys.index[x] {
+					return x
+				}
+			}
+			if !warned {
+				logf("Can't generate orphans of %v outside of %v, use min and max", c.Name, c.References)
+				warned = true
+			}
+		}
+		return keys.values[pick()]
+	}, nil
+}
+
+// Return the tables in the order they must be written, referenced tables first
+func orderTables(tables []Table) ([]Table, error) {
+	index := make(map[string]int)
+	for i, t := range tables {
+		if _, ok := index[t.Name]; ok {
+			return nil, fmt.Errorf("duplicate table %v", t.Name)
+		}
+		index[t.Name] = i
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make([]int, len(tables))
+	ordered := make([]Table, 0, len(tables))
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("circular references through table %v", tables[i].Name)
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		for _, c := range tables[i].Columns {
+			if c.References == "" {
+				continue
+			}
+			table := strings.SplitN(c.References, ".", 2)[0]
+			j, ok := index[table]
+			if !ok {
+				return fmt.Errorf("column %v.%v references unknown table %v", tables[i].Name, c.Name, table)
+			}
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		ordered = append(ordered, tables[i])
+		return nil
+	}
+
+	for i := range tables {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// Write the parquet files of related tables in a directory. Foreign keys (columns
+// with references) take the values of the referenced columns, generated first,
+// so that joins between the files match
+func SimulateTables(dir string, tables []Table, opts SimulateOptions) error {
+
+	seed := simulationSeed(opts.Seed)
+	logf("Seed: %v", seed)
+
+	ordered, err := orderTables(tables)
+	if err != nil {
+		return err
+	}
+
+	// Key sets of the referenced columns, filled when their table is written
+	columns := make(map[string]Column)
+	for _, t := range tables {
+		for _, c := range t.Columns {
+			columns[t.Name+"."+c.Name] = c
+		}
+	}
+	keys := make(map[string]*keySet)
+	for _, t := range tables {
+		for _, c := range t.Columns {
+			if c.References == "" {
+				continue
+			}
+			referenced, ok := columns[c.References]
+			if !ok {
+				return fmt.Errorf("column %v.%v references unknown column %v", t.Name, c.Name, c.References)
+			}
+			if isNested(referenced.Type) {
+				return fmt.Errorf("column %v.%v references nested column %v", t.Name, c.Name, c.References)
+			}
+			keys[c.References] = &keySet{
+				parquetType: referenced.Type,
+				index:       make(map[interface{}]bool),
+			}
+		}
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("can't create directory %v: %v", dir, err)
+	}
+
+	for _, t := range ordered {
+		filename := t.File
+		if filename == "" {
+			filename = t.Name + ".parquet"
+		}
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(dir, filename)
+		}
+		if err = simulateTable(filename, t.Name, t.Rows, t.Columns, seed, opts, keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,510 @@
+package pqtool
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TransformOptions are the options of Transform, applied in this order: drop,
+// rename, cast, add and order
+type TransformOptions struct {
+	Drop   []string // Names of the dropped columns
+	Rename []string // Renamed columns as OLD=NEW
+	Cast   []string // Cast columns as NAME=TYPE, TYPE being DECIMAL(PRECISION,SCALE), DATE:LAYOUT or TIMESTAMP:LAYOUT to parse text, or a type of ParseType
+	Add    []string // Added columns as NAME:TYPE=VALUE, all null if VALUE is empty
+	Order  []string // Names of the first columns in order, the other columns following in their order
+	Lossy  bool     // Cast columns are optional and lossy casts are null, instead of failing
+	Writer WriterOptions
+}
+
+// Maximum number of lossy rows reported for a column
+const maxLossyRows = 10
+
+// Column of the output of a transform
+type columnTransform struct {
+	source    int         // Index of the column in the input rows, -1 for an added column
+	from      Field       // Input column
+	to        Field       // Output column
+	cast      bool        // Values are cast from the type of the input column
+	value     interface{} // Value of an added column, nil for null
+	lossy     int64       // Number of lossy casts
+	lossyRows []int64     // First rows of the lossy casts
+}
+
+// Parse the type of a cast or added column: DECIMAL(PRECISION,SCALE), DATE:LAYOUT or
+// TIMESTAMP:LAYOUT (layout of the text cast to dates and timestamps), or a type of ParseType
+func parseTypeSpec(spec string) (Field, error) {
+	var f Field
+	upper := strings.ToUpper(strings.TrimSpace(spec))
+	if strings.HasPrefix(upper, "DECIMAL(") && strings.HasSuffix(upper, ")") {
+		elem := strings.Split(upper[len("DECIMAL("):len(upper)-1], ",")
+		if len(elem) != 2 {
+			return f, fmt.Errorf("invalid type %v, expected DECIMAL(PRECISION,SCALE)", spec)
+		}
+		precision, err1 := strconv.Atoi(strings.TrimSpace(elem[0]))
+		scale, err2 := strconv.Atoi(strings.TrimSpace(elem[1]))
+		if err1 != nil || err2 != nil || precision < 1 || precision > 18 || scale < 0 || scale > precision {
+			return f, fmt.Errorf("invalid type %v, decimals need a precision from 1 to 18 and a scale from 0 to the precision", spec)
+		}
+		return Field{Type: "DECIMAL", Precision: precision, Scale: scale}, nil
+	}
+
+	elem := strings.SplitN(spec, ":", 2)
+	parquetType, err := ParseType(elem[0])
+	if err != nil {
+		return f, err
+	}
+	f.Type = parquetType
+	if len(elem) == 2 {
+		if castKind(parquetType) != "time" {
+			return f, fmt.Errorf("invalid type %v, only dates and timestamps have a layout", spec)
+		}
+		f.Layout = elem[1]
+	}
+	return f, nil
+}
+
+// Return the kind of the values of a parquet type for casts: bool, number, text or time
+func castKind(parquetType string) string {
+	switch parquetType {
+	case "BOOLEAN":
+		return "bool"
+	case "UTF8", "BYTE_ARRAY":
+		return "text"
+	case "DATE", "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS":
+		return "time"
+	}
+	return "number"
+}
+
+// Return whether values of a type can be cast to another type. Dates and
+// timestamps can only be cast to and from dates, timestamps and text
+func canCast(from Field, to Field) bool {
+	a, b := castKind(from.Type), castKind(to.Type)
+	return (a == "time") == (b == "time") || a == "text" || b == "text"
+}
+
+// Return the number of a numeric or boolean value, nil for NaN and infinite floats
+func toRat(v reflect.Value, f Field) *big.Rat {
+	switch {
+	case f.Type == "DECIMAL":
+		return toDecimal(v, f.Scale)
+	case isSigned(v.Kind()):
+		return new(big.Rat).SetInt64(v.Int())
+	case isUnsigned(v.Kind()):
+		return new(big.Rat).SetFrac(new(big.Int).SetUint64(v.Uint()), big.NewInt(1))
+	case v.Kind() == reflect.Bool:
+		if v.Bool() {
+			return big.NewRat(1, 1)
+		}
+		return new(big.Rat)
+	}
+	return new(big.Rat).SetFloat64(v.Float())
+}
+
+// Return the value of a number in a numeric or boolean type, and whether it's exact
+func fromRat(r *big.Rat, to Field) (interface{}, bool) {
+	if r == nil {
+		return nil, false
+	}
+	kind := GoType(to.Type).Kind()
+	switch {
+	case to.Type == "DECIMAL":
+		n := new(big.Rat).Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(to.Scale)), nil)))
+		limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(to.Precision)), nil)
+		if !n.IsInt() || new(big.Int).Abs(n.Num()).Cmp(limit) >= 0 {
+			return nil, false
+		}
+		return n.Num().Int64(), true
+	case isSigned(kind):
+		if !r.IsInt() || !r.Num().IsInt64() || reflect.Zero(GoType(to.Type)).OverflowInt(r.Num().Int64()) {
+			return nil, false
+		}
+		return r.Num().Int64(), true
+	case isUnsigned(kind):
+		if !r.IsInt() || !r.Num().IsUint64() || reflect.Zero(GoType(to.Type)).OverflowUint(r.Num().Uint64()) {
+			return nil, false
+		}
+		return r.Num().Uint64(), true
+	case kind == reflect.Float32:
+		f, exact := r.Float32()
+		return f, exact || sameDecimal(float64(f), kind, r)
+	case kind == reflect.Float64:
+		f, exact := r.Float64()
+		return f, exact || sameDecimal(f, kind, r)
+	case kind == reflect.Bool:
+		if r.Sign() != 0 && r.Cmp(big.NewRat(1, 1)) != 0 {
+			return nil, false
+		}
+		return r.Sign() != 0, true
+	}
+	return nil, false
+}
+
+// Return the time of a date or timestamp value
+func toTime(v reflect.Value, f Field) time.Time {
+	if f.Type == "DATE" {
+		return FromDate(int32(v.Int()))
+	}
+	return FromTimestamp(v.Int(), f.Type)
+}
+
+// Return the value of a time in a date or timestamp type, and whether it's exact
+func fromTime(t time.Time, to Field) (interface{}, bool) {
+	switch to.Type {
+	case "DATE":
+		days := ToDate(t)
+		return days, FromDate(days).Equal(t)
+	case "TIMESTAMP_MILLIS":
+		return ToTimestamp(t, to.Type), t.Nanosecond()%int(time.Millisecond) == 0
+	}
+	return ToTimestamp(t, to.Type), t.Nanosecond()%int(time.Microsecond) == 0
+}
+
+// Return whether a column has floats
+func isFloat(f Field) bool {
+	return f.Type == "FLOAT" || f.Type == "DOUBLE"
+}
+
+// Return the decimal of a scale nearest to a float or a float32 (e.g. 12.34 for the double
+// 12.339999999999999857891452847979962825775146484375), nil if it isn't the same float
+func nearestDecimal(x float64, kind reflect.Kind, scale int) *big.Rat {
+	scaled := math.Round(x * math.Pow10(scale))
+	if math.IsNaN(scaled) || math.IsInf(scaled, 0) {
+		return nil
+	}
+	n, _ := new(big.Float).SetFloat64(scaled).Int(nil)
+	r := new(big.Rat).SetFrac(n, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+	if kind == reflect.Float32 {
+		if f, _ := r.Float32(); float64(f) != x {
+			return nil
+		}
+	} else if f, _ := r.Float64(); f != x {
+		return nil
+	}
+	return r
+}
+
+// Return whether a number is a decimal (e.g. from text or a DECIMAL column) whose
+// nearest float is a float, 12.34 and the double nearest to it being the same value
+func sameDecimal(x float64, kind reflect.Kind, r *big.Rat) bool {
+	scaled := new(big.Rat).Set(r)
+	for scale := 0; scale <= 18; scale++ {
+		if scaled.IsInt() {
+			d := nearestDecimal(x, kind, scale)
+			return d != nil && d.Cmp(r) == 0
+		}
+		scaled.Mul(scaled, big.NewRat(10, 1))
+	}
+	return false
+}
+
+// Return the value of a column cast to the type of another column, and whether
+// the cast is lossless. Text is parsed, and dates and timestamps in text have
+// the layout of the output column (2006-01-02 and 2006-01-02 15:04:05 by default)
+func castValue(v reflect.Value, from Field, to Field) (interface{}, bool) {
+	switch castKind(to.Type) {
+	case "text":
+		if castKind(from.Type) == "text" {
+			return v.String(), true
+		}
+		return formatValue(v, from), true
+
+	case "time":
+		if castKind(from.Type) == "time" {
+			return fromTime(toTime(v, from), to)
+		}
+		layout := to.Layout
+		if layout == "" {
+			layout = "2006-01-02 15:04:05"
+			if to.Type == "DATE" {
+				layout = "2006-01-02"
+			}
+		}
+		t, err := time.Parse(layout, strings.TrimSpace(v.String()))
+		if err != nil {
+			return nil, false
+		}
+		return fromTime(t, to)
+	}
+
+	if castKind(from.Type) != "text" {
+		// NaN and infinite floats have no number but are kept by floats
+		if isFloat(from) && isFloat(to) && (math.IsNaN(v.Float()) || math.IsInf(v.Float(), 0)) {
+			return v.Float(), true
+		}
+		if isFloat(from) && to.Type == "DECIMAL" {
+			return fromRat(nearestDecimal(v.Float(), v.Kind(), to.Scale), to)
+		}
+		return fromRat(toRat(v, from), to)
+	}
+	text := strings.TrimSpace(v.String())
+	if to.Type == "BOOLEAN" {
+		b, err := strconv.ParseBool(text)
+		return b, err == nil
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, false
+	}
+	return fromRat(r, to)
+}
+
+// Set the value of an output column from an input row, failing on lossy casts unless allowed
+func (c *columnTransform) set(field reflect.Value, row reflect.Value, index int64, lossy bool) error {
+	if c.source < 0 {
+		if c.value != nil {
+			setField(field, c.value)
+		}
+		return nil
+	}
+
+	v := row.Field(c.source)
+	if !c.cast {
+		field.Set(v)
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	x, ok := castValue(v, c.from, c.to)
+	if !ok {
+		if !lossy {
+			return fmt.Errorf("row %v: value %v of column %v can't be cast to %v without loss", index, formatValue(v, c.from), c.from.Name, c.to.TypeName())
+		}
+		c.lossy++
+		if len(c.lossyRows) < maxLossyRows {
+			c.lossyRows = append(c.lossyRows, index)
+		}
+		return nil
+	}
+	setField(field, x)
+	return nil
+}
+
+// Return the index of the column with a name, -1 if none
+func findColumn(columns []*columnTransform, name string) int {
+	for i, c := range columns {
+		if c.to.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Return the output columns of a transform of columns
+func transformColumns(fields []Field, opts TransformOptions) ([]*columnTransform, error) {
+	columns := make([]*columnTransform, len(fields))
+	for i, f := range fields {
+		columns[i] = &columnTransform{source: i, from: f, to: f}
+	}
+
+	for _, name := range opts.Drop {
+		i := findColumn(columns, name)
+		if i < 0 {
+			return nil, fmt.Errorf("no column %v to drop", name)
+		}
+		columns = append(columns[:i], columns[i+1:]...)
+	}
+
+	for _, r := range opts.Rename {
+		elem := strings.SplitN(r, "=", 2)
+		if len(elem) != 2 || elem[1] == "" {
+			return nil, fmt.Errorf("invalid rename %v, expected OLD=NEW", r)
+		}
+		i := findColumn(columns, elem[0])
+		if i < 0 {
+			return nil, fmt.Errorf("no column %v to rename", elem[0])
+		}
+		if findColumn(columns, elem[1]) >= 0 {
+			return nil, fmt.Errorf("can't rename %v to %v, the column already exists", elem[0], elem[1])
+		}
+		columns[i].to.Name = elem[1]
+	}
+
+	for _, cast := range opts.Cast {
+		elem := strings.SplitN(cast, "=", 2)
+		if len(elem) != 2 {
+			return nil, fmt.Errorf("invalid cast %v, expected NAME=TYPE", cast)
+		}
+		i := findColumn(columns, elem[0])
+		if i < 0 {
+			return nil, fmt.Errorf("no column %v to cast", elem[0])
+		}
+		to, err := parseTypeSpec(elem[1])
+		if err != nil {
+			return nil, fmt.Errorf("column %v: %v", elem[0], err)
+		}
+		c := columns[i]
+		if !canCast(c.from, to) {
+			return nil, fmt.Errorf("can't cast column %v from %v to %v", elem[0], c.from.TypeName(), to.TypeName())
+		}
+		to.Name, to.Optional = c.to.Name, c.from.Optional || opts.Lossy
+		c.to, c.cast = to, true
+	}
+
+	for _, add := range opts.Add {
+		elem := strings.SplitN(add, "=", 2)
+		spec := strings.SplitN(elem[0], ":", 2)
+		if len(elem) != 2 || len(spec) != 2 {
+			return nil, fmt.Errorf("invalid column %v, expected NAME:TYPE=VALUE", add)
+		}
+		if findColumn(columns, spec[0]) >= 0 {
+			return nil, fmt.Errorf("can't add column %v, the column already exists", spec[0])
+		}
+		to, err := parseTypeSpec(spec[1])
+		if err != nil {
+			return nil, fmt.Errorf("column %v: %v", spec[0], err)
+		}
+		to.Name = spec[0]
+		c := &columnTransform{source: -1, to: to}
+		if elem[1] == "" {
+			c.to.Optional = true
+		} else {
+			var ok bool
+			if c.value, ok = castValue(reflect.ValueOf(elem[1]), Field{Type: "UTF8"}, to); !ok {
+				return nil, fmt.Errorf("invalid value %v for column %v of type %v", elem[1], to.Name, to.TypeName())
+			}
+		}
+		columns = append(columns, c)
+	}
+
+	ordered := make([]*columnTransform, 0, len(columns))
+	for _, name := range opts.Order {
+		i := findColumn(columns, name)
+		if i < 0 {
+			return nil, fmt.Errorf("no column %v to order", name)
+		}
+		ordered = append(ordered, columns[i])
+		columns = append(columns[:i], columns[i+1:]...)
+	}
+	return append(ordered, columns...), nil
+}
+
+// Return the description of the output column of a transform
+func (c *columnTransform) String() string {
+	switch {
+	case c.source < 0 && c.value == nil:
+		return fmt.Sprintf("%v: %v (added, null)", c.to.Name, c.to.TypeName())
+	case c.source < 0:
+		return fmt.Sprintf("%v: %v (added, %v)", c.to.Name, c.to.TypeName(), formatValue(reflect.ValueOf(c.value), c.to))
+	}
+	s := fmt.Sprintf("%v: %v", c.to.Name, c.to.TypeName())
+	if c.cast {
+		s += fmt.Sprintf(" (cast from %v)", c.from.TypeName())
+	}
+	if c.from.Name != c.to.Name {
+		s += fmt.Sprintf(" (renamed from %v)", c.from.Name)
+	}
+	return s
+}
+
+// Transform the columns of a flat parquet file: drop, rename, cast, add and order
+// columns. Casts fail at the first value they can't convert without loss (e.g.
+// 1.5 to INT64, 300 to INT_8, text that isn't a date), unless opts.Lossy makes
+// these values null and reports their rows. Columns copied without cast keep
+// their dictionary encoding, and the file keeps its key-value metadata except
+// the Avro schema
+func Transform(filename string, output string, opts TransformOptions) error {
+	footer, err := readFooter(filename)
+	if err != nil {
+		return err
+	}
+	pr, err := OpenReader(filename)
+	if err != nil {
+		return fmt.Errorf("%v (only flat files can be transformed)", err)
+	}
+	defer pr.Close()
+
+	columns, err := transformColumns(pr.Fields, opts)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("no column left to write")
+	}
+
+	var encodings []string
+	if len(footer.RowGroups) > 0 {
+		encodings = dictionaryEncodings(footer.RowGroups[0])
+	}
+	fields := make([]Field, len(columns))
+	logf("Structure:")
+	for i, c := range columns {
+		fields[i] = c.to
+		if c.source >= 0 && !c.cast && c.source < len(encodings) && encodings[c.source] != "" && c.to.Type != "BOOLEAN" {
+			fields[i].Encoding = encodings[c.source]
+		}
+		logf("  %v", c)
+	}
+
+	dataType, err := StructType(fields)
+	if err != nil {
+		return err
+	}
+	pw, err := CreateWriterWithOptions(output, dataType, opts.Writer)
+	if err != nil {
+		return err
+	}
+	for _, kv := range footer.KeyValueMetadata {
+		if kv.Key != AvroSchemaKey {
+			pw.SetMetadata(kv.Key, kv.GetValue())
+		}
+	}
+	// Remove the output file on errors
+	abort := func() {
+		pw.Close()
+		os.Remove(output)
+	}
+
+	var nRows int64
+	for {
+		slice, err := pr.Read(1000)
+		if err != nil {
+			abort()
+			return err
+		}
+		if slice.Len() == 0 {
+			break
+		}
+		for i := 0; i < slice.Len(); i++ {
+			nRows++
+			row := reflect.New(dataType).Elem()
+			for j, c := range columns {
+				if err = c.set(row.Field(j), slice.Index(i), nRows, opts.Lossy); err != nil {
+					abort()
+					return err
+				}
+			}
+			if err = pw.Write(row); err != nil {
+				abort()
+				return err
+			}
+		}
+	}
+
+	if err = pw.Close(); err != nil {
+		os.Remove(output)
+		return err
+	}
+	for _, c := range columns {
+		if c.lossy > 0 {
+			rows := fmt.Sprint(c.lossyRows)
+			if c.lossy > int64(len(c.lossyRows)) {
+				rows = strings.TrimSuffix(rows, "]") + " ...]"
+			}
+			logf("Column %v: %v values can't be cast to %v without loss and are null, rows %v", c.to.Name, c.lossy, c.to.TypeName(), rows)
+		}
+	}
+	logf("Parquet file %v written with %v rows and %v fields", output, nRows, len(fields))
+	return nil
+}
@@ -0,0 +1,52 @@
+package pqtool
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Casts convert values exactly, or fail without leaving an output file unless lossy
+func TestTransformCasts(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "input.parquet"), filepath.Join(dir, "output.parquet")
+	writeRows(t, input, []Field{{Name: "n", Type: "INT64"}, {Name: "x", Type: "DOUBLE"}, {Name: "s", Type: "UTF8"}}, [][]interface{}{
+		{int64(1), 1.25, "03/17/2021"},
+		{int64(300), -2.5, "12/31/2020"},
+	})
+
+	opts := TransformOptions{
+		Rename: []string{"s=day"},
+		Cast:   []string{"n=INT32", "x=DECIMAL(10,2)", "day=DATE:01/02/2006"},
+		Add:    []string{"source:UTF8=a,b"},
+	}
+	if err := Transform(input, output, opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"n=1,x=1.25,day=2021-03-17,source=a,b",
+		"n=300,x=-2.50,day=2020-12-31,source=a,b",
+	}
+	if rows := rowsText(t, output); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("rows %v, expected %v", rows, expected)
+	}
+
+	// 300 isn't an INT_8
+	opts = TransformOptions{Cast: []string{"n=INT_8"}}
+	if err := Transform(input, output, opts); err == nil {
+		t.Errorf("cast of 300 to INT_8 without error")
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("output of the failed cast not removed")
+	}
+
+	opts.Lossy = true
+	if err := Transform(input, output, opts); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"n=1,x=1.25,s=03/17/2021", ",x=-2.5,s=12/31/2020"}
+	if rows := rowsText(t, output); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("rows %v, expected %v", rows, expected)
+	}
+}
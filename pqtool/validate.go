@@ -0,0 +1,547 @@
+package pqtool
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/compress"
+	"github.com/xitongsys/parquet-go/encoding"
+	"github.com/xitongsys/parquet-go/layout"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/schema"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+)
+
+// ValidationProblem is a problem found in a parquet file, located by row group,
+// column and page when it's in a column chunk
+type ValidationProblem struct {
+	RowGroup int    // Index of the row group from 0, -1 if the problem is in the file
+	Column   string // Path of the column (e.g. user.address.city), empty if the problem isn't in a column chunk
+	Page     int    // Index of the page in the column chunk from 0, -1 if the problem isn't in a page
+	Message  string // Description of the problem
+}
+
+// Return the location and the description of a problem (e.g. row group 2, column id, page 7: ...)
+func (p ValidationProblem) String() string {
+	var location []string
+	if p.RowGroup >= 0 {
+		location = append(location, fmt.Sprintf("row group %v", p.RowGroup))
+	}
+	if p.Column != "" {
+		location = append(location, "column "+p.Column)
+	}
+	if p.Page >= 0 {
+		location = append(location, fmt.Sprintf("page %v", p.Page))
+	}
+	if len(location) == 0 {
+		return "file: " + p.Message
+	}
+	return strings.Join(location, ", ") + ": " + p.Message
+}
+
+// Validator of a parquet file, reporting its problems as they're found
+type validator struct {
+	w        io.Writer
+	file     *os.File
+	size     int64
+	dataEnd  int64 // Offset of the footer, end of the column chunks
+	footer   *parquet.FileMetaData
+	sh       *schema.SchemaHandler
+	problems []ValidationProblem
+	pages    int64 // Number of pages checked
+}
+
+// Location of the problems found in a column chunk
+type chunkLocation struct {
+	rowGroup int
+	column   string
+}
+
+// Report a problem
+func (v *validator) report(rowGroup int, column string, page int, format string, a ...interface{}) {
+	p := ValidationProblem{rowGroup, column, page, fmt.Sprintf(format, a...)}
+	v.problems = append(v.problems, p)
+	fmt.Fprintln(v.w, p)
+}
+
+// Report a problem of a column chunk
+func (l chunkLocation) report(v *validator, page int, format string, a ...interface{}) {
+	v.report(l.rowGroup, l.column, page, format, a...)
+}
+
+// Call f, returning the panics of parquet-go on corrupt data as errors
+func catch(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return f()
+}
+
+// Decode a thrift structure in the compact protocol, and return the number of bytes read
+func decodeThrift(data []byte, s interface {
+	Read(context.Context, thrift.TProtocol) error
+}) (int, error) {
+	buf := &thrift.TMemoryBuffer{Buffer: bytes.NewBuffer(data)}
+	err := catch(func() error { return s.Read(context.TODO(), thrift.NewTCompactProtocol(buf)) })
+	return len(data) - buf.Len(), err
+}
+
+// Read the bytes of the file at an offset
+func (v *validator) readAt(offset int64, n int64) ([]byte, error) {
+	data := make([]byte, n)
+	if _, err := v.file.ReadAt(data, offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Check the magic bytes, the length of the footer and decode it, returning false
+// if the row groups can't be checked
+func (v *validator) checkFooter() bool {
+	if v.size < 12 {
+		v.report(-1, "", -1, "file of %v bytes too small for a parquet file", v.size)
+		return false
+	}
+	head, err := v.readAt(0, 4)
+	if err != nil {
+		v.report(-1, "", -1, "can't read the header: %v", err)
+		return false
+	}
+	tail, err := v.readAt(v.size-8, 8)
+	if err != nil {
+		v.report(-1, "", -1, "can't read the end of the file: %v", err)
+		return false
+	}
+	if string(head) != "PAR1" {
+		v.report(-1, "", -1, "invalid magic bytes %q at the start of the file, expected \"PAR1\"", head)
+	}
+	switch string(tail[4:]) {
+	case "PAR1":
+	case "PARE":
+		v.report(-1, "", -1, "encrypted footer, not supported")
+		return false
+	default:
+		v.report(-1, "", -1, "invalid magic bytes %q at the end of the file, expected \"PAR1\" (truncated file?)", tail[4:])
+		return false
+	}
+
+	length := int64(binary.LittleEndian.Uint32(tail[:4]))
+	if length == 0 || length > v.size-12 {
+		v.report(-1, "", -1, "invalid footer length %v for a file of %v bytes", length, v.size)
+		return false
+	}
+	v.dataEnd = v.size - 8 - length
+	data, err := v.readAt(v.dataEnd, length)
+	if err != nil {
+		v.report(-1, "", -1, "can't read the footer: %v", err)
+		return false
+	}
+	v.footer = parquet.NewFileMetaData()
+	if n, err := decodeThrift(data, v.footer); err != nil {
+		v.report(-1, "", -1, "can't decode the footer metadata: %v", err)
+		return false
+	} else if n != len(data) {
+		v.report(-1, "", -1, "footer metadata of %v bytes followed by %v unexpected bytes", n, len(data)-n)
+	}
+
+	if len(v.footer.Schema) == 0 {
+		v.report(-1, "", -1, "footer has no schema")
+		return false
+	}
+	// The schema handler renames the schema elements like a parquet reader
+	elements := make([]*parquet.SchemaElement, len(v.footer.Schema))
+	for i, se := range v.footer.Schema {
+		copied := *se
+		elements[i] = &copied
+	}
+	if err = catch(func() error {
+		v.sh = schema.NewSchemaHandlerFromSchemaList(elements)
+		return nil
+	}); err != nil {
+		v.report(-1, "", -1, "invalid schema: %v", err)
+		return false
+	}
+
+	var rows int64
+	for _, rg := range v.footer.RowGroups {
+		rows += rg.NumRows
+	}
+	if rows != v.footer.NumRows {
+		v.report(-1, "", -1, "footer has %v rows but its row groups have %v rows", v.footer.NumRows, rows)
+	}
+	return true
+}
+
+// Check the column chunks of a row group
+func (v *validator) checkRowGroup(index int, rg *parquet.RowGroup) {
+	columns := v.sh.ValueColumns
+	if len(rg.Columns) != len(columns) {
+		v.report(index, "", -1, "%v column chunks for %v columns in the schema", len(rg.Columns), len(columns))
+	}
+	if rg.NumRows < 0 {
+		v.report(index, "", -1, "invalid number of rows %v", rg.NumRows)
+	}
+	for i, chunk := range rg.Columns {
+		name := fmt.Sprintf("#%v", i)
+		if i < len(columns) {
+			name = strings.Join(common.StrToPath(v.sh.InPathToExPath[columns[i]])[1:], ".")
+		}
+		location := chunkLocation{index, name}
+		if chunk.MetaData == nil {
+			location.report(v, -1, "no column metadata")
+			continue
+		}
+		if path := strings.Join(chunk.MetaData.PathInSchema, "."); i < len(columns) && path != name {
+			location.report(v, -1, "column chunk of another column %v", path)
+			continue
+		}
+		if i < len(columns) {
+			v.checkChunk(location, columns[i], rg.NumRows, chunk.MetaData)
+		}
+	}
+}
+
+// Pages and values of a column chunk
+type chunkState struct {
+	location   chunkLocation
+	se         *parquet.SchemaElement
+	path       []string // Path of the column in the schema handler
+	order      common.FuncTable
+	dictionary *layout.Page
+	values     int64 // Number of values of the data pages
+	rows       int64 // Number of rows of the data pages
+	nulls      int64 // Number of nulls of the decoded data pages
+	counted    bool  // Whether the values and rows of all the data pages are counted
+	decoded    bool  // Whether the values of all the data pages are decoded
+	min, max   interface{}
+}
+
+// Check the pages of a column chunk, decoding their values
+func (v *validator) checkChunk(location chunkLocation, column string, numRows int64, md *parquet.ColumnMetaData) {
+	se := v.sh.SchemaElements[v.sh.MapIndex[column]]
+	if se.Type == nil || *se.Type != md.Type {
+		location.report(v, -1, "column chunk type %v, expected %v in the schema", md.Type, se.GetType())
+		return
+	}
+
+	// The chunk starts with its dictionary page if it has one
+	start := md.DataPageOffset
+	if offset := md.DictionaryPageOffset; offset != nil && *offset > 0 && *offset < start {
+		start = *offset
+	}
+	if start < 4 || md.TotalCompressedSize <= 0 || start+md.TotalCompressedSize > v.dataEnd {
+		location.report(v, -1, "column chunk of %v bytes at offset %v outside of the data of the file (offsets 4 to %v)", md.TotalCompressedSize, start, v.dataEnd)
+		return
+	}
+	data, err := v.readAt(start, md.TotalCompressedSize)
+	if err != nil {
+		location.report(v, -1, "can't read the column chunk: %v", err)
+		return
+	}
+
+	state := &chunkState{location: location, se: se, path: common.StrToPath(column), counted: true, decoded: true}
+	// parquet-go panics on the logical types it can't order
+	catch(func() error {
+		state.order = common.FindFuncTable(se.Type, se.ConvertedType)
+		return nil
+	})
+
+	var offset, uncompressed int64
+	for page := 0; offset < int64(len(data)); page++ {
+		v.pages++
+		header := parquet.NewPageHeader()
+		n, err := decodeThrift(data[offset:], header)
+		if err != nil {
+			location.report(v, page, "can't decode the page header at offset %v: %v", start+offset, err)
+			return
+		}
+		size := int64(header.CompressedPageSize)
+		if size < 0 || header.UncompressedPageSize < 0 || offset+int64(n)+size > int64(len(data)) {
+			location.report(v, page, "page of %v bytes at offset %v beyond the end of the column chunk at offset %v", size, start+offset, start+int64(len(data)))
+			return
+		}
+		raw := data[offset+int64(n) : offset+int64(n)+size]
+		offset += int64(n) + size
+		uncompressed += int64(n) + int64(header.UncompressedPageSize)
+
+		if header.IsSetCrc() && crc32.ChecksumIEEE(raw) != uint32(header.GetCrc()) {
+			location.report(v, page, "CRC checksum %08x of the page, expected %08x", crc32.ChecksumIEEE(raw), uint32(header.GetCrc()))
+			state.counted, state.decoded = false, false
+			continue
+		}
+		v.checkPage(state, page, header, raw, md.Codec)
+	}
+
+	if uncompressed != md.TotalUncompressedSize {
+		location.report(v, -1, "uncompressed size of the pages %v bytes, expected %v", uncompressed, md.TotalUncompressedSize)
+	}
+	if state.counted && state.values != md.NumValues {
+		location.report(v, -1, "%v values in the pages, expected %v", state.values, md.NumValues)
+	}
+	if state.counted && state.rows != numRows {
+		location.report(v, -1, "%v rows in the pages, expected %v in the row group", state.rows, numRows)
+	}
+	if state.decoded {
+		v.checkStatistics(state, -1, md.Statistics, state.min, state.max, state.nulls)
+	}
+}
+
+// Check a page: decompress it and decode its values
+func (v *validator) checkPage(state *chunkState, index int, header *parquet.PageHeader, raw []byte, codec parquet.CompressionCodec) {
+	location := state.location
+	var (
+		data  []byte // Uncompressed page
+		stats *parquet.Statistics
+		err   error
+	)
+	switch header.GetType() {
+	case parquet.PageType_DATA_PAGE:
+		if header.DataPageHeader == nil {
+			location.report(v, index, "data page without data page header")
+			state.counted, state.decoded = false, false
+			return
+		}
+		state.values += int64(header.DataPageHeader.NumValues)
+		stats = header.DataPageHeader.Statistics
+		if data, err = compress.Uncompress(raw, codec); err != nil {
+			location.report(v, index, "can't decompress the %v page: %v", codec, err)
+			state.counted, state.decoded = false, false
+			return
+		}
+	case parquet.PageType_DATA_PAGE_V2:
+		if header.DataPageHeaderV2 == nil {
+			location.report(v, index, "data page without data page header")
+			state.counted, state.decoded = false, false
+			return
+		}
+		state.values += int64(header.DataPageHeaderV2.NumValues)
+		stats = header.DataPageHeaderV2.Statistics
+		// The levels of a data page v2 aren't compressed
+		h := header.DataPageHeaderV2
+		levels := int64(h.RepetitionLevelsByteLength) + int64(h.DefinitionLevelsByteLength)
+		if h.RepetitionLevelsByteLength < 0 || h.DefinitionLevelsByteLength < 0 || levels > int64(len(raw)) {
+			location.report(v, index, "levels of %v bytes in a page of %v bytes", levels, len(raw))
+			state.counted, state.decoded = false, false
+			return
+		}
+		values := raw[levels:]
+		if h.IsCompressed {
+			if values, err = compress.Uncompress(values, codec); err != nil {
+				location.report(v, index, "can't decompress the %v page: %v", codec, err)
+				state.counted, state.decoded = false, false
+				return
+			}
+		}
+		data = append(append([]byte{}, raw[:levels]...), values...)
+	case parquet.PageType_DICTIONARY_PAGE:
+		if header.DictionaryPageHeader == nil {
+			location.report(v, index, "dictionary page without dictionary page header")
+			state.decoded = false
+			return
+		}
+		if index != 0 {
+			location.report(v, index, "dictionary page after the first page")
+		}
+		if data, err = compress.Uncompress(raw, codec); err != nil {
+			location.report(v, index, "can't decompress the %v page: %v", codec, err)
+			state.decoded = false
+			return
+		}
+	case parquet.PageType_INDEX_PAGE:
+		return
+	default:
+		location.report(v, index, "unknown page type %v", header.GetType())
+		return
+	}
+	if len(data) != int(header.UncompressedPageSize) {
+		location.report(v, index, "uncompressed page of %v bytes, expected %v", len(data), header.UncompressedPageSize)
+	}
+
+	page := layout.NewDataPage()
+	if header.GetType() == parquet.PageType_DICTIONARY_PAGE {
+		page = layout.NewDictPage()
+	}
+	page.Header = header
+	page.CompressType = parquet.CompressionCodec_UNCOMPRESSED
+	page.RawData = data
+	page.Path = state.path
+	page.Schema = state.se
+
+	var rows int64
+	err = catch(func() error {
+		var err error
+		if _, rows, err = page.GetRLDLFromRawData(v.sh); err != nil {
+			return err
+		}
+		// parquet-go decodes the values of data pages v2 like those of data pages
+		// v1, once their levels are read
+		if h := header.DataPageHeaderV2; h != nil {
+			page.Header = &parquet.PageHeader{
+				Type:           parquet.PageType_DATA_PAGE,
+				DataPageHeader: &parquet.DataPageHeader{NumValues: h.NumValues, Encoding: h.Encoding},
+			}
+		}
+		return page.GetValueFromRawData(v.sh)
+	})
+	if err != nil {
+		location.report(v, index, "can't decode the values: %v", err)
+		state.counted, state.decoded = false, false
+		return
+	}
+	if header.GetType() == parquet.PageType_DICTIONARY_PAGE {
+		state.dictionary = page
+		return
+	}
+	state.rows += rows
+
+	if e := page.Header.DataPageHeader.Encoding; e == parquet.Encoding_PLAIN_DICTIONARY || e == parquet.Encoding_RLE_DICTIONARY {
+		if state.dictionary == nil {
+			location.report(v, index, "dictionary encoded page without dictionary page")
+			state.decoded = false
+			return
+		}
+		size := int64(len(state.dictionary.DataTable.Values))
+		for _, value := range page.DataTable.Values {
+			if i, ok := value.(int64); ok && (i < 0 || i >= size) {
+				location.report(v, index, "dictionary index %v out of the %v values of the dictionary", i, size)
+				state.decoded = false
+				return
+			}
+		}
+		page.Decode(state.dictionary)
+	}
+
+	var min, max interface{}
+	var nulls int64
+	for _, value := range page.DataTable.Values {
+		switch {
+		case value == nil:
+			nulls++
+		case state.order != nil:
+			min = common.Min(state.order, min, value)
+			max = common.Max(state.order, max, value)
+		}
+	}
+	state.nulls += nulls
+	if state.order != nil {
+		state.min = common.Min(state.order, state.min, min)
+		state.max = common.Max(state.order, state.max, max)
+	}
+	v.checkStatistics(state, index, stats, min, max, nulls)
+}
+
+// Decode a value of the statistics of a column
+func statisticsValue(data []byte, se *parquet.SchemaElement) (interface{}, error) {
+	switch se.GetType() {
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		return string(data), nil
+	}
+	var values []interface{}
+	err := catch(func() error {
+		var err error
+		values, err = encoding.ReadPlain(bytes.NewReader(data), se.GetType(), 1, 0)
+		return err
+	})
+	if err == nil && len(values) != 1 {
+		err = fmt.Errorf("no value")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// Check the statistics of a page (or a column chunk if page is -1) against the
+// minimum, maximum and number of nulls of its values
+func (v *validator) checkStatistics(state *chunkState, page int, stats *parquet.Statistics, min interface{}, max interface{}, nulls int64) {
+	if stats == nil {
+		return
+	}
+	location := state.location
+	if stats.IsSetNullCount() && stats.GetNullCount() != nulls && state.path != nil {
+		if maxRepetition, _ := v.sh.MaxRepetitionLevel(state.path); maxRepetition == 0 {
+			location.report(v, page, "statistics null count %v, the values have %v nulls", stats.GetNullCount(), nulls)
+		}
+	}
+	if state.order == nil || min == nil {
+		return
+	}
+
+	// Minimum and maximum in the order of the logical type, else in the order of
+	// the physical type for the deprecated statistics
+	minData, maxData := stats.MinValue, stats.MaxValue
+	if minData == nil && maxData == nil && state.se.ConvertedType == nil {
+		minData, maxData = stats.Min, stats.Max
+	}
+	check := func(name string, data []byte, valid func(stat interface{}) bool, value interface{}) {
+		if data == nil {
+			return
+		}
+		stat, err := statisticsValue(data, state.se)
+		if err != nil {
+			location.report(v, page, "can't decode the statistics %v: %v", name, err)
+			return
+		}
+		if valid(stat) {
+			return
+		}
+		// parquet-go writes the statistics of the byte arrays without a logical
+		// type of column chunks with their length
+		if state.se.GetType() == parquet.Type_BYTE_ARRAY && len(data) >= 4 && int(binary.LittleEndian.Uint32(data)) == len(data)-4 {
+			if valid(string(data[4:])) {
+				return
+			}
+		}
+		location.report(v, page, "statistics %v %v inconsistent with the %v %v of the values", name, formatStatistic(stat), name, formatStatistic(value))
+	}
+	check("min", minData, func(stat interface{}) bool { return !state.order.LessThan(min, stat) }, min)
+	check("max", maxData, func(stat interface{}) bool { return !state.order.LessThan(stat, max) }, max)
+}
+
+// Return a statistic value as text, quoting strings
+func formatStatistic(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(value)
+}
+
+// Check a parquet file: magic bytes, footer, page headers, CRC checksums,
+// decompression and decoding of the pages, numbers of values and rows, and
+// statistics. The problems are written to w as they're found, followed by a
+// summary, and returned. The error is only set if the file can't be read
+func Validate(w io.Writer, filename string) ([]ValidationProblem, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't open parquet file '%v': %v", filename, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("can't read parquet file '%v': %v", filename, err)
+	}
+
+	v := &validator{w: w, file: file, size: info.Size()}
+	if v.checkFooter() {
+		for i, rg := range v.footer.RowGroups {
+			Debug("Checking row group %v of %v rows", i, rg.NumRows)
+			v.checkRowGroup(i, rg)
+		}
+	}
+
+	if len(v.problems) > 0 {
+		fmt.Fprintf(w, "%v: %v problems found\n", filename, len(v.problems))
+	} else {
+		fmt.Fprintf(w, "%v: valid, %v rows in %v row groups, %v columns, %v pages\n", filename,
+			v.footer.NumRows, len(v.footer.RowGroups), len(v.sh.ValueColumns), v.pages)
+	}
+	return v.problems, nil
+}
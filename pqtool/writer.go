@@ -0,0 +1,448 @@
+package pqtool
+
+import (
+	"fmt"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/layout"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// WriterOptions are the options of the layout of written parquet files
+type WriterOptions struct {
+	Compression     string // Compression codec: UNCOMPRESSED, SNAPPY (default), GZIP or ZSTD
+	RowGroupSize    int64  // Size of row groups in bytes, 128 MB if 0
+	PageSize        int64  // Size of data pages in bytes, 8 KB if 0
+	DataPageVersion int    // Version of data pages, 1 (default) or 2
+	Parallelism     int    // Number of goroutines encoding data pages v1, 4 if 0
+}
+
+// Writer writes rows of a dynamic structure into a new parquet file
+type Writer struct {
+	DataType reflect.Type // Structure of a row
+	file     source.ParquetFile
+	pw       *writer.ParquetWriter
+	nulls    []int64                  // Null count of each column in the current row group, nil if rows aren't flat
+	deltas   []*deltaRange            // Ranges of the DELTA_BINARY_PACKED integer columns of flat rows
+	sorting  []*parquet.SortingColumn // Columns the rows are sorted by, set in each row group
+
+	pageVersion int           // Version of data pages
+	rows        []interface{} // Rows not encoded yet in data pages v2
+	rowsSize    int64         // Estimated size of the rows not encoded yet
+}
+
+// Return whether a structure only has flat columns, one per field
+func isFlat(dataType reflect.Type) bool {
+	for i := 0; i < dataType.NumField(); i++ {
+		t := dataType.Field(i).Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct, reflect.Slice, reflect.Map:
+			return false
+		}
+	}
+	return true
+}
+
+// Create a parquet file whose rows have the structure dataType
+func CreateWriter(filename string, dataType reflect.Type) (*Writer, error) {
+	return CreateWriterWithOptions(filename, dataType, WriterOptions{})
+}
+
+// Create a parquet file whose rows have the structure dataType, with layout options
+func CreateWriterWithOptions(filename string, dataType reflect.Type, opts WriterOptions) (*Writer, error) {
+
+	codec := parquet.CompressionCodec_SNAPPY
+	if opts.Compression != "" {
+		var err error
+		codec, err = parquet.CompressionCodecFromString(strings.ToUpper(opts.Compression))
+		if err != nil || !supportedCodecs[codec] {
+			return nil, fmt.Errorf("invalid compression codec %v", opts.Compression)
+		}
+	}
+	if opts.DataPageVersion != 0 && opts.DataPageVersion != 1 && opts.DataPageVersion != 2 {
+		return nil, fmt.Errorf("invalid data page version %v", opts.DataPageVersion)
+	}
+	if opts.RowGroupSize < 0 || opts.PageSize < 0 || opts.Parallelism < 0 {
+		return nil, fmt.Errorf("invalid negative size or parallelism")
+	}
+	np := int64(opts.Parallelism)
+	if np == 0 {
+		np = 4
+	}
+
+	// Define Parquet File Writer
+	Debug("Creating NewLocalFileWriter")
+	fw, err := local.NewLocalFileWriter(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't create parquet file '%v': %v", filename, err)
+	}
+
+	// Define Parquet Writer pw on File Writer fw
+	Debug("Creating NewParquetWriter:%v", dataType)
+	pw, err := writer.NewParquetWriter(fw, reflect.New(dataType).Interface(), np)
+	if err != nil {
+		fw.Close()
+		return nil, fmt.Errorf("can't create parquet writer: %v", err)
+	}
+	pw.CompressionType = codec
+	if opts.RowGroupSize > 0 {
+		pw.RowGroupSize = opts.RowGroupSize
+	}
+	if opts.PageSize > 0 {
+		pw.PageSize = opts.PageSize
+	}
+
+	w := &Writer{
+		DataType:    dataType,
+		file:        fw,
+		pw:          pw,
+		pageVersion: opts.DataPageVersion,
+	}
+	if isFlat(dataType) {
+		w.nulls = make([]int64, dataType.NumField())
+		w.deltas = deltaRanges(dataType)
+	}
+	return w, nil
+}
+
+// Range of the values written in a DELTA_BINARY_PACKED integer column. parquet-go
+// computes the differences of the values in signed integers of 32 or 64 bits,
+// and writes wrong values if they can overflow: the range must be below 2^(bits-2)
+type deltaRange struct {
+	field    int    // Index of the field
+	name     string // Name of the column
+	bits     uint   // 32 or 64
+	min, max uint64 // Smallest and largest values, with the sign bit flipped for signed integers
+	started  bool
+}
+
+// Return the ranges of the DELTA_BINARY_PACKED integer fields of a flat structure
+func deltaRanges(dataType reflect.Type) []*deltaRange {
+	var deltas []*deltaRange
+	for i := 0; i < dataType.NumField(); i++ {
+		field := dataType.Field(i)
+		delta, name := false, field.Name
+		for _, item := range strings.Split(field.Tag.Get("parquet"), ",") {
+			item = strings.TrimSpace(item)
+			delta = delta || item == "encoding=DELTA_BINARY_PACKED"
+			if strings.HasPrefix(item, "name=") {
+				name = strings.TrimPrefix(item, "name=")
+			}
+		}
+		t := field.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if !delta || (!isSigned(t.Kind()) && !isUnsigned(t.Kind())) {
+			continue
+		}
+		bits := uint(64)
+		if t.Bits() <= 32 {
+			bits = 32
+		}
+		deltas = append(deltas, &deltaRange{field: i, name: name, bits: bits})
+	}
+	return deltas
+}
+
+// Return whether a kind is a signed integer
+func isSigned(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+// Return whether a kind is an unsigned integer
+func isUnsigned(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// Add the value of a row to the range, and return an error if the range is too large
+func (d *deltaRange) add(row reflect.Value) error {
+	v := row.Field(d.field)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	// Flipping the sign bit orders signed integers like unsigned ones
+	var x uint64
+	if isSigned(v.Kind()) {
+		x = uint64(v.Int()) ^ 1<<63
+	} else {
+		x = v.Uint()
+	}
+	if !d.started || x < d.min {
+		d.min = x
+	}
+	if !d.started || x > d.max {
+		d.max = x
+	}
+	d.started = true
+	if d.max-d.min >= 1<<(d.bits-2) {
+		return fmt.Errorf("values of DELTA_BINARY_PACKED column %v need a range (max - min) below 2^%v", d.name, d.bits-2)
+	}
+	return nil
+}
+
+// Compression codecs of parquet-go
+var supportedCodecs = map[parquet.CompressionCodec]bool{
+	parquet.CompressionCodec_UNCOMPRESSED: true,
+	parquet.CompressionCodec_SNAPPY:       true,
+	parquet.CompressionCodec_GZIP:         true,
+	parquet.CompressionCodec_ZSTD:         true,
+}
+
+// Create a parquet file with flat columns
+func CreateFieldsWriter(filename string, fields []Field) (*Writer, error) {
+	dataType, err := StructType(fields)
+	if err != nil {
+		return nil, err
+	}
+	return CreateWriter(filename, dataType)
+}
+
+// File of flat rows, written to parquet or to CSV with a .csv extension
+type outputFile struct {
+	DataType reflect.Type // Structure of a row
+	fields   []Field
+	pw       *Writer
+	csv      *csvFile
+}
+
+// Create a file of flat rows, a CSV file with a header line if its extension is .csv
+func createOutputFile(filename string, fields []Field) (*outputFile, error) {
+	dataType, err := StructType(fields)
+	if err != nil {
+		return nil, err
+	}
+	o := &outputFile{DataType: dataType, fields: fields}
+	if strings.ToLower(filepath.Ext(filename)) == ".csv" {
+		if o.csv, err = createCSV(filename); err != nil {
+			return nil, err
+		}
+		if _, err = o.csv.WriteString(ExportCSVOptions{}.header(fields)); err != nil {
+			o.csv.file.Close()
+			return nil, err
+		}
+		return o, nil
+	}
+	if o.pw, err = CreateWriter(filename, dataType); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Write a row of the file's structure
+func (o *outputFile) Write(v reflect.Value) error {
+	if o.csv != nil {
+		_, err := o.csv.WriteString(ExportCSVOptions{}.line(v, o.fields))
+		return err
+	}
+	return o.pw.Write(v)
+}
+
+// Flush and close the file
+func (o *outputFile) Close() error {
+	if o.csv != nil {
+		if err := o.csv.Flush(); err != nil {
+			o.csv.file.Close()
+			return err
+		}
+		return o.csv.file.Close()
+	}
+	return o.pw.Close()
+}
+
+// Return a new empty row
+func (w *Writer) NewRow() reflect.Value {
+	return reflect.New(w.DataType).Elem()
+}
+
+// Write a row, an addressable structure of the writer's structure
+func (w *Writer) Write(v reflect.Value) error {
+	for _, d := range w.deltas {
+		if err := d.add(v); err != nil {
+			return err
+		}
+	}
+	if w.nulls != nil {
+		for i := range w.nulls {
+			if f := v.Field(i); f.Kind() == reflect.Ptr && f.IsNil() {
+				w.nulls[i]++
+			}
+		}
+	}
+
+	rowGroups := len(w.pw.Footer.RowGroups)
+	if w.pageVersion == 2 {
+		// Encode the rows in pages when they fill a page of each column for each goroutine, like parquet-go
+		w.rows = append(w.rows, v.Interface())
+		w.rowsSize += common.SizeOf(v)
+		if w.rowsSize >= w.pw.NP*w.pw.PageSize*w.pw.SchemaHandler.GetColumnNum() {
+			if err := w.flushPages(false); err != nil {
+				return err
+			}
+		}
+	} else if err := w.pw.Write(v.Addr().Interface()); err != nil {
+		return fmt.Errorf("writing to parquet: %v", err)
+	}
+	if len(w.pw.Footer.RowGroups) > rowGroups {
+		w.setNullCounts()
+	}
+	return nil
+}
+
+// Encode the buffered rows in data pages v2 added to the pages of the parquet
+// writer, which only writes data pages v1, and flush the row group if it's full
+// (or if last is set). Dictionary-encoded columns keep data pages v1, as
+// parquet-go has no dictionary data pages v2
+func (w *Writer) flushPages(last bool) error {
+	if len(w.rows) > 0 {
+		tables, err := w.pw.MarshalFunc(w.rows, w.pw.SchemaHandler)
+		if err != nil {
+			return fmt.Errorf("writing to parquet: %v", err)
+		}
+		for name, table := range *tables {
+			var pages []*layout.Page
+			if table.Info.Encoding == parquet.Encoding_PLAIN_DICTIONARY || table.Info.Encoding == parquet.Encoding_RLE_DICTIONARY {
+				if _, ok := w.pw.DictRecs[name]; !ok {
+					w.pw.DictRecs[name] = layout.NewDictRec(*table.Schema.Type)
+				}
+				pages, _ = layout.TableToDictDataPages(w.pw.DictRecs[name], table, int32(w.pw.PageSize), 32, w.pw.CompressionType)
+			} else {
+				pages = dataPagesV2(table, int32(w.pw.PageSize), w.pw.CompressionType)
+			}
+			w.pw.PagesMapBuf[name] = append(w.pw.PagesMapBuf[name], pages...)
+			for _, page := range pages {
+				w.pw.Size += int64(len(page.RawData))
+				page.DataTable = nil
+			}
+		}
+		w.pw.NumRows += int64(len(w.rows))
+		w.pw.Footer.NumRows += int64(len(w.rows))
+		w.rows, w.rowsSize = nil, 0
+	}
+
+	if err := w.pw.Flush(last); err != nil {
+		return fmt.Errorf("Flush error: %v", err)
+	}
+	return nil
+}
+
+// Split the values of a column into data pages v2 of about pageSize bytes
+func dataPagesV2(table *layout.Table, pageSize int32, codec parquet.CompressionCodec) []*layout.Page {
+	var pages []*layout.Page
+	funcTable := common.FindFuncTable(table.Schema.Type, table.Schema.ConvertedType)
+
+	for i := 0; i < len(table.Values); {
+		// Values from i to j, a page starting at a new row (repetition level 0)
+		minVal, maxVal := interface{}(nil), interface{}(nil)
+		var size int32
+		j := i
+		for j < len(table.Values) && (j == i || size < pageSize || table.RepetitionLevels[j] != 0) {
+			if table.DefinitionLevels[j] == table.MaxDefinitionLevel {
+				var elSize int32
+				if minVal == nil {
+					minVal, maxVal = table.Values[j], table.Values[j]
+				}
+				minVal, maxVal, elSize = funcTable.MinMaxSize(minVal, maxVal, table.Values[j])
+				size += elSize
+			}
+			j++
+		}
+
+		page := layout.NewDataPage()
+		page.PageSize = pageSize
+		page.DataTable = &layout.Table{
+			RepetitionType:     table.RepetitionType,
+			Path:               table.Path,
+			MaxDefinitionLevel: table.MaxDefinitionLevel,
+			MaxRepetitionLevel: table.MaxRepetitionLevel,
+			Values:             table.Values[i:j],
+			DefinitionLevels:   table.DefinitionLevels[i:j],
+			RepetitionLevels:   table.RepetitionLevels[i:j],
+		}
+		page.MinVal, page.MaxVal = minVal, maxVal
+		page.Schema = table.Schema
+		page.CompressType = codec
+		page.Path = table.Path
+		page.Info = table.Info
+		page.DataPageV2Compress(codec)
+
+		pages = append(pages, page)
+		i = j
+	}
+	return pages
+}
+
+// Set the null counts of the columns in the statistics of the last row group,
+// as parquet-go doesn't, and start counting for the next row group
+func (w *Writer) setNullCounts() {
+	if w.nulls == nil || len(w.pw.Footer.RowGroups) == 0 {
+		return
+	}
+	rowGroup := w.pw.Footer.RowGroups[len(w.pw.Footer.RowGroups)-1]
+	if len(rowGroup.Columns) != len(w.nulls) {
+		return
+	}
+	for i, column := range rowGroup.Columns {
+		if column.MetaData != nil && column.MetaData.Statistics != nil {
+			nullCount := w.nulls[i]
+			column.MetaData.Statistics.NullCount = &nullCount
+		}
+		w.nulls[i] = 0
+	}
+}
+
+// Add a key-value pair to the file metadata
+func (w *Writer) SetMetadata(key string, value string) {
+	w.pw.Footer.KeyValueMetadata = append(w.pw.Footer.KeyValueMetadata, &parquet.KeyValue{
+		Key:   key,
+		Value: &value,
+	})
+}
+
+// Set the columns the rows are sorted by, recorded in the metadata of the row groups
+func (w *Writer) SetSortingColumns(columns []*parquet.SortingColumn) {
+	w.sorting = columns
+}
+
+// Write the footer and close the file
+func (w *Writer) Close() error {
+	defer w.file.Close()
+
+	// Flush the last row group to set its null counts before the footer is written
+	rowGroups := len(w.pw.Footer.RowGroups)
+	if err := w.flushPages(true); err != nil {
+		return err
+	}
+	if len(w.pw.Footer.RowGroups) > rowGroups {
+		w.setNullCounts()
+	}
+	if w.sorting != nil {
+		for _, rowGroup := range w.pw.Footer.RowGroups {
+			rowGroup.SortingColumns = w.sorting
+		}
+	}
+
+	if err := w.pw.WriteStop(); err != nil {
+		return fmt.Errorf("WriteStop error: %v", err)
+	}
+	return nil
+}